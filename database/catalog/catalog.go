@@ -0,0 +1,128 @@
+package catalog
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/validation"
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Querier — соединение или транзакция, через которые читается и пополняется каталог
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// Clean убирает крайние пробелы и схлопывает повторные: так название хранится в каталоге
+func Clean(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Key — ключ сравнения названий марок и моделей: без учёта регистра и лишних пробелов.
+// Совпадает с SQL-функцией catalog_key.
+func Key(name string) string {
+	return strings.ToLower(Clean(name))
+}
+
+// Index — марки и модели каталога с поиском по ключу названия
+type Index struct {
+	makes map[string]*makeEntry
+}
+
+type makeEntry struct {
+	models.Make
+	byName map[string]models.CarModel
+}
+
+// Load читает каталог. Если переданы firms, читаются только эти марки (в любом написании) и их модели.
+func Load(ctx context.Context, q Querier, firms ...string) (*Index, error) {
+	query := `SELECT mk.id, mk.name, mo.id, mo.name
+	          FROM makes mk LEFT JOIN models mo ON mo.make_id = mk.id`
+	var args []interface{}
+	if len(firms) > 0 {
+		keys := make([]string, len(firms))
+		for i, firm := range firms {
+			keys[i] = Key(firm)
+		}
+		query += " WHERE catalog_key(mk.name) = ANY($1)"
+		args = append(args, keys)
+	}
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	idx := &Index{makes: make(map[string]*makeEntry)}
+	for rows.Next() {
+		var mk models.Make
+		var modelID *int
+		var modelName *string
+		if err := rows.Scan(&mk.ID, &mk.Name, &modelID, &modelName); err != nil {
+			return nil, err
+		}
+		entry := idx.addMake(mk)
+		if modelID != nil {
+			entry.byName[Key(*modelName)] = models.CarModel{ID: *modelID, MakeID: mk.ID, Name: *modelName}
+		}
+	}
+	return idx, rows.Err()
+}
+
+func (idx *Index) addMake(mk models.Make) *makeEntry {
+	key := Key(mk.Name)
+	entry, ok := idx.makes[key]
+	if !ok {
+		entry = &makeEntry{Make: mk, byName: make(map[string]models.CarModel)}
+		idx.makes[key] = entry
+	}
+	return entry
+}
+
+// Resolve ищет марку и модель машины в каталоге и заменяет их каноническим написанием.
+// Если марки или модели в каталоге нет, возвращает ошибку валидации поля firm или model.
+func (idx *Index) Resolve(car *models.Car) validation.Errors {
+	mk, ok := idx.makes[Key(car.Firm)]
+	if !ok {
+		return validation.Errors{{Field: "firm", Code: validation.CodeNotFound}}
+	}
+	mo, ok := mk.byName[Key(car.Model)]
+	if !ok {
+		return validation.Errors{{Field: "model", Code: validation.CodeNotFound}}
+	}
+	car.Firm, car.Model = mk.Name, mo.Name
+	return nil
+}
+
+// Ensure — как Resolve, но недостающие марка и модель добавляются в каталог.
+// Возвращает добавленные записи; ID у них уже назначены базой.
+func (idx *Index) Ensure(ctx context.Context, q Querier, car *models.Car) (*models.Make, *models.CarModel, error) {
+	var addedMake *models.Make
+	var addedModel *models.CarModel
+
+	entry, ok := idx.makes[Key(car.Firm)]
+	if !ok {
+		mk := models.Make{Name: Clean(car.Firm)}
+		if err := q.QueryRow(ctx, "INSERT INTO makes (name) VALUES ($1) RETURNING id", mk.Name).Scan(&mk.ID); err != nil {
+			return nil, nil, err
+		}
+		entry = idx.addMake(mk)
+		addedMake = &mk
+	}
+
+	if _, ok := entry.byName[Key(car.Model)]; !ok {
+		mo := models.CarModel{MakeID: entry.ID, Name: Clean(car.Model)}
+		if err := q.QueryRow(ctx, "INSERT INTO models (make_id, name) VALUES ($1, $2) RETURNING id",
+			mo.MakeID, mo.Name).Scan(&mo.ID); err != nil {
+			return nil, nil, err
+		}
+		entry.byName[Key(mo.Name)] = mo
+		addedModel = &mo
+	}
+
+	idx.Resolve(car)
+	return addedMake, addedModel, nil
+}
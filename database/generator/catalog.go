@@ -0,0 +1,116 @@
+package generator
+
+// carModel — модель из каталога: диапазон мощности и цена новой машины в базовой комплектации
+type carModel struct {
+	Firm      string
+	Model     string
+	FirstYear int
+	PowerMin  int
+	PowerMax  int
+	BasePrice int
+	// Weight — относительная частота модели на складе: массовые модели встречаются чаще суперкаров
+	Weight int
+	Body   string
+}
+
+// option — значение с относительной частотой
+type option struct {
+	Name   string
+	Weight int
+}
+
+// wmi — международный код изготовителя, первые три символа VIN
+var wmi = map[string]string{
+	"Lada": "XTA", "Hyundai": "KMH", "Kia": "KNA", "Toyota": "JTD", "Volkswagen": "WVW",
+	"Renault": "VF1", "Skoda": "TMB", "Geely": "L6T", "Chery": "LVV", "Haval": "LGW",
+	"Mazda": "JM1", "Nissan": "JN1", "Ford": "1FA", "BMW": "WBA", "Mercedes-Benz": "WDD",
+	"Audi": "WAU", "Lexus": "JTH", "Volvo": "YV1", "Porsche": "WP0", "Tesla": "5YJ",
+	"Land Rover": "SAL",
+}
+
+// rearDriveFirms — марки, у которых седаны и купе обычно заднеприводные
+var rearDriveFirms = map[string]bool{"BMW": true, "Mercedes-Benz": true, "Lexus": true, "Porsche": true}
+
+// catalog — марки и модели, близкие к тем, что есть в cars.json
+var catalog = []carModel{
+	{"Lada", "Vesta", 2015, 90, 122, 14000, 60, "sedan"},
+	{"Lada", "Granta", 2011, 87, 106, 9000, 60, "sedan"},
+	{"Lada", "Niva Travel", 2020, 80, 80, 13000, 30, "suv"},
+	{"Hyundai", "Solaris", 2010, 100, 123, 15000, 50, "sedan"},
+	{"Hyundai", "Sonata", 2000, 150, 245, 26000, 15, "sedan"},
+	{"Hyundai", "Tucson", 2004, 140, 230, 28000, 25, "crossover"},
+	{"Kia", "Rio", 2005, 100, 123, 15000, 50, "sedan"},
+	{"Kia", "Optima", 2010, 150, 245, 25000, 15, "sedan"},
+	{"Kia", "Sportage", 2004, 150, 230, 27000, 30, "crossover"},
+	{"Kia", "Stinger", 2017, 247, 370, 40000, 5, "liftback"},
+	{"Toyota", "Camry", 2000, 150, 249, 30000, 35, "sedan"},
+	{"Toyota", "Corolla", 2000, 110, 140, 21000, 35, "sedan"},
+	{"Toyota", "RAV4", 2000, 146, 249, 32000, 35, "crossover"},
+	{"Toyota", "Land Cruiser", 2000, 249, 415, 80000, 10, "suv"},
+	{"Toyota", "Supra", 2019, 258, 387, 55000, 3, "coupe"},
+	{"Volkswagen", "Polo", 2010, 90, 125, 16000, 45, "liftback"},
+	{"Volkswagen", "Golf", 2000, 110, 245, 24000, 25, "hatchback"},
+	{"Volkswagen", "Passat", 2000, 150, 280, 30000, 20, "sedan"},
+	{"Volkswagen", "Tiguan", 2007, 150, 220, 32000, 25, "crossover"},
+	{"Renault", "Logan", 2004, 82, 113, 11000, 40, "sedan"},
+	{"Renault", "Duster", 2010, 114, 150, 17000, 35, "crossover"},
+	{"Skoda", "Octavia", 2000, 110, 245, 23000, 35, "liftback"},
+	{"Skoda", "Kodiaq", 2016, 150, 220, 33000, 15, "crossover"},
+	{"Geely", "Coolray", 2019, 150, 177, 22000, 30, "crossover"},
+	{"Geely", "Atlas", 2016, 139, 200, 26000, 25, "crossover"},
+	{"Chery", "Tiggo 7 Pro", 2020, 147, 170, 23000, 25, "crossover"},
+	{"Haval", "Jolion", 2021, 143, 150, 21000, 25, "crossover"},
+	{"Mazda", "CX-5", 2012, 150, 194, 30000, 20, "crossover"},
+	{"Mazda", "MX-5 Miata", 2000, 131, 184, 29000, 3, "convertible"},
+	{"Nissan", "Qashqai", 2007, 115, 150, 25000, 25, "crossover"},
+	{"Nissan", "GT-R", 2007, 480, 600, 115000, 1, "coupe"},
+	{"Ford", "Focus", 2000, 105, 150, 19000, 25, "hatchback"},
+	{"Ford", "Mustang", 2000, 310, 480, 40000, 4, "coupe"},
+	{"BMW", "3 Series", 2000, 156, 374, 42000, 15, "sedan"},
+	{"BMW", "X5", 2000, 249, 530, 70000, 10, "suv"},
+	{"BMW", "M5", 2000, 400, 635, 105000, 2, "sedan"},
+	{"Mercedes-Benz", "C-Class", 2000, 156, 390, 45000, 15, "sedan"},
+	{"Mercedes-Benz", "E-Class", 2000, 190, 435, 58000, 12, "sedan"},
+	{"Mercedes-Benz", "AMG GT", 2014, 476, 585, 120000, 1, "coupe"},
+	{"Audi", "A4", 2000, 150, 354, 40000, 15, "sedan"},
+	{"Audi", "Q7", 2005, 249, 507, 70000, 8, "suv"},
+	{"Lexus", "RX", 2000, 238, 313, 55000, 8, "crossover"},
+	{"Volvo", "XC90", 2002, 235, 455, 60000, 6, "suv"},
+	{"Porsche", "911", 2000, 370, 650, 110000, 2, "coupe"},
+	{"Porsche", "Cayenne", 2002, 340, 680, 90000, 3, "suv"},
+	{"Tesla", "Model 3", 2017, 283, 510, 42000, 4, "sedan"},
+	{"Land Rover", "Range Rover Sport", 2005, 300, 575, 85000, 4, "suv"},
+}
+
+// colors — цвета из палитры с примерной долей на рынке
+var colors = []option{
+	{"White", 26}, {"Black", 20}, {"Gray", 16}, {"Silver", 14}, {"Blue", 9},
+	{"Red", 7}, {"Brown", 3}, {"Green", 2}, {"Beige", 1}, {"Orange", 1}, {"Yellow", 1},
+}
+
+// cities — города Беларуси и России; вес примерно соответствует размеру города
+var cities = []option{
+	{"Минск", 40}, {"Гомель", 10}, {"Могилёв", 7}, {"Витебск", 7}, {"Гродно", 7}, {"Брест", 7},
+	{"Бобруйск", 4}, {"Барановичи", 3}, {"Борисов", 3}, {"Пинск", 3}, {"Орша", 2}, {"Мозырь", 2},
+	{"Солигорск", 2}, {"Новополоцк", 2}, {"Лида", 2}, {"Молодечно", 2}, {"Полоцк", 2}, {"Жлобин", 1},
+	{"Москва", 40}, {"Санкт-Петербург", 25}, {"Новосибирск", 8}, {"Екатеринбург", 8}, {"Казань", 7},
+	{"Нижний Новгород", 6}, {"Смоленск", 3}, {"Брянск", 3}, {"Псков", 2}, {"Калининград", 4},
+}
+
+// Распределения характеристик машин
+var (
+	fuels         = []option{{"petrol", 70}, {"diesel", 15}, {"hybrid", 10}, {"lpg", 5}}
+	transmissions = []option{{"automatic", 55}, {"manual", 25}, {"robot", 10}, {"cvt", 10}}
+	trims         = []string{"Base", "Classic", "Comfort", "Style", "Prestige", "Luxe", "Sport", "Premium"}
+)
+
+var (
+	dealerPrefixes = []string{"Авто", "Car", "Drive", "Мото", "Техно", "Бел", "Эко", "Супер", "Топ", "Гранд"}
+	dealerSuffixes = []string{"Мир", "Маркет", "Центр", "Плюс", "Град", "Сити", "Дом", "Хаус", "Зона", "Лэнд", "Стиль", "Клуб"}
+	streets        = []string{
+		"ул. Независимости", "ул. Ленина", "пр. Победителей", "ул. Советская", "ул. Пушкина",
+		"ул. Гагарина", "пр. Мира", "ул. Кирова", "ул. Московская", "ул. Строителей",
+		"пр. Октябрьской Революции", "ул. Садовая", "ул. Заводская", "ул. Промышленная",
+	}
+	areas = []string{"Центральный", "Советский", "Ленинский", "Октябрьский", "Первомайский", "Заводской", "Фрунзенский", "Московский", "Партизанский"}
+)
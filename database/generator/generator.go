@@ -0,0 +1,252 @@
+package generator
+
+import (
+	"CarDealership/database/loader"
+	"CarDealership/database/models"
+	"CarDealership/validation"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Options — параметры генерации
+type Options struct {
+	// Seed — зерно генератора: при одинаковых Seed, Dealers, Cars и Year данные совпадают байт в байт
+	Seed uint64
+	// Dealers и Cars — сколько записей создать
+	Dealers int
+	Cars    int
+	// Year — «текущий» год, от которого отсчитывается возраст машин; по умолчанию — текущий год
+	Year int
+}
+
+// Generator создаёт правдоподобных дилеров и машины.
+// Реализует importer.Source, поэтому данные можно сразу передать в importer.Upsert;
+// каждый проход EachDealer/EachCar выдаёт одну и ту же последовательность.
+type Generator struct {
+	opts          Options
+	models        weighted
+	colors        weighted
+	cities        weighted
+	fuels         weighted
+	transmissions weighted
+	minYear       int
+}
+
+// unassignedShare — доля машин без дилера
+const unassignedShare = 0.05
+
+// New создаёт генератор
+func New(opts Options) *Generator {
+	if opts.Year == 0 {
+		opts.Year = time.Now().Year()
+	}
+
+	g := &Generator{opts: opts, minYear: opts.Year - 25}
+	for _, m := range catalog {
+		g.models.add(m.Weight)
+	}
+	g.colors.addAll(colors)
+	g.cities.addAll(cities)
+	g.fuels.addAll(fuels)
+	g.transmissions.addAll(transmissions)
+	return g
+}
+
+// DealerKey возвращает внешний ключ i-го сгенерированного дилера (с нуля)
+func DealerKey(i int) string {
+	return fmt.Sprintf("gen-d-%06d", i+1)
+}
+
+// CarKey возвращает внешний ключ i-й сгенерированной машины (с нуля)
+func CarKey(i int) string {
+	return fmt.Sprintf("gen-c-%07d", i+1)
+}
+
+// EachDealer вызывает fn для каждого дилера
+func (g *Generator) EachDealer(fn func(loader.DealerRecord) error) error {
+	rng := rand.New(rand.NewPCG(g.opts.Seed, 1))
+	for i := 0; i < g.opts.Dealers; i++ {
+		if err := fn(g.dealer(rng, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachCar вызывает fn для каждой машины.
+// Машины ссылаются на дилеров по внешнему ключу, поэтому дилеры должны попасть в тот же импорт или уже быть в базе.
+func (g *Generator) EachCar(fn func(loader.CarRecord) error) error {
+	rng := rand.New(rand.NewPCG(g.opts.Seed, 2))
+	for i := 0; i < g.opts.Cars; i++ {
+		if err := fn(g.car(rng, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) dealer(rng *rand.Rand, i int) loader.DealerRecord {
+	city := cities[g.cities.pick(rng)].Name
+	// Номер в имени делает имена уникальными: генерированные дилеры не совпадут по имени с настоящими
+	name := fmt.Sprintf("%s%s %s %d",
+		dealerPrefixes[rng.IntN(len(dealerPrefixes))],
+		dealerSuffixes[rng.IntN(len(dealerSuffixes))],
+		city, i+1)
+
+	return loader.DealerRecord{
+		Dealer: models.Dealer{
+			Name:    name,
+			City:    city,
+			Address: fmt.Sprintf("%s, %d", streets[rng.IntN(len(streets))], 1+rng.IntN(150)),
+			Area:    areas[rng.IntN(len(areas))],
+		},
+		ExternalKey: DealerKey(i),
+	}
+}
+
+func (g *Generator) car(rng *rand.Rand, i int) loader.CarRecord {
+	m := catalog[g.models.pick(rng)]
+
+	// Возраст распределён экспоненциально: свежих машин на складе больше, чем старых
+	first := max(m.FirstYear, g.minYear)
+	year := g.opts.Year - int(rng.ExpFloat64()*5)
+	if year < first {
+		year = first + rng.IntN(g.opts.Year-first+1)
+	}
+	age := g.opts.Year - year
+
+	power := m.PowerMin
+	if m.PowerMax > m.PowerMin {
+		power += rng.IntN(m.PowerMax - m.PowerMin + 1)
+	}
+
+	// Цена растёт с мощностью, теряет около 12% в год и отклоняется от расчётной на несколько процентов
+	price := float64(m.BasePrice)
+	if m.PowerMax > m.PowerMin {
+		price *= 1 + 0.8*float64(power-m.PowerMin)/float64(m.PowerMax-m.PowerMin)
+	}
+	price *= math.Pow(0.88, float64(age)) * math.Exp(rng.NormFloat64()*0.08)
+
+	rec := loader.CarRecord{
+		Car: models.Car{
+			Firm:     m.Firm,
+			Model:    m.Model,
+			Year:     year,
+			Power:    power,
+			Color:    colors[g.colors.pick(rng)].Name,
+			Price:    max(500, int(math.Round(price/100))*100),
+			BodyType: m.Body,
+			Trim:     trims[rng.IntN(len(trims))],
+		},
+		ExternalKey: CarKey(i),
+	}
+	g.fillUsage(rng, &rec.Car, age)
+	g.fillDrive(rng, &rec.Car)
+	vin := makeVIN(rng, m.Firm, year, i)
+	rec.VIN = &vin
+
+	// Случайное значение берётся всегда, чтобы последовательность не зависела от числа дилеров
+	assigned := rng.Float64() >= unassignedShare
+	if g.opts.Dealers > 0 {
+		d := rng.IntN(g.opts.Dealers)
+		if assigned {
+			rec.Dealer = DealerKey(d)
+		}
+	}
+	return rec
+}
+
+// fillUsage задаёт пробег и состояние: часть машин текущего года — новые,
+// остальные в среднем проезжают 15 тысяч километров в год
+func (g *Generator) fillUsage(rng *rand.Rand, car *models.Car, age int) {
+	if age == 0 && rng.Float64() < 0.6 {
+		car.Condition = models.ConditionNew
+		car.Mileage = rng.IntN(50)
+		return
+	}
+	car.Condition = models.ConditionUsed
+	mileage := (float64(age) + 0.5) * 15000 * math.Exp(rng.NormFloat64()*0.35)
+	car.Mileage = max(100, int(math.Round(mileage/100))*100)
+}
+
+// fillDrive задаёт топливо, коробку передач и привод с учётом типа кузова и марки
+func (g *Generator) fillDrive(rng *rand.Rand, car *models.Car) {
+	if car.Firm == "Tesla" {
+		car.FuelType, car.Transmission, car.Drivetrain = "electric", "automatic", "rwd"
+		if rng.IntN(2) == 0 {
+			car.Drivetrain = "awd"
+		}
+		return
+	}
+	car.FuelType = fuels[g.fuels.pick(rng)].Name
+	car.Transmission = transmissions[g.transmissions.pick(rng)].Name
+
+	switch {
+	case car.BodyType == "suv":
+		car.Drivetrain = []string{"awd", "4wd"}[rng.IntN(2)]
+	case car.BodyType == "crossover":
+		car.Drivetrain = []string{"fwd", "awd"}[rng.IntN(2)]
+	case car.BodyType == "coupe" || car.BodyType == "convertible" || rearDriveFirms[car.Firm]:
+		car.Drivetrain = []string{"rwd", "rwd", "awd"}[rng.IntN(3)]
+	default:
+		car.Drivetrain = "fwd"
+	}
+}
+
+// vinChars — символы, допустимые в VIN
+const vinChars = "ABCDEFGHJKLMNPRSTUVWXYZ0123456789"
+
+// vinYears — код модельного года (десятый символ VIN); цикл из 30 лет начинается с 1980
+const vinYears = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// makeVIN собирает VIN с верной контрольной цифрой. Номер машины кодируется в последних
+// семи символах, поэтому VIN разных машин одного набора не совпадают.
+func makeVIN(rng *rand.Rand, firm string, year, i int) string {
+	b := make([]byte, 0, validation.VINLength)
+	b = append(b, wmi[firm]...)
+	for len(b) < 8 {
+		b = append(b, vinChars[rng.IntN(len(vinChars))])
+	}
+	b = append(b, '0', vinYears[(year-1980)%len(vinYears)], vinChars[(i/1_000_000)%len(vinChars)])
+	b = append(b, fmt.Sprintf("%06d", i%1_000_000)...)
+
+	digit, _ := validation.VINCheckDigit(string(b))
+	b[8] = digit
+	return string(b)
+}
+
+// weighted — кумулятивные веса для выбора элемента с заданной частотой
+type weighted struct {
+	sums []int
+}
+
+func (w *weighted) addAll(options []option) {
+	for _, o := range options {
+		w.add(o.Weight)
+	}
+}
+
+func (w *weighted) add(weight int) {
+	total := 0
+	if len(w.sums) > 0 {
+		total = w.sums[len(w.sums)-1]
+	}
+	w.sums = append(w.sums, total+weight)
+}
+
+// pick возвращает индекс элемента
+func (w *weighted) pick(rng *rand.Rand) int {
+	n := rng.IntN(w.sums[len(w.sums)-1])
+	lo, hi := 0, len(w.sums)-1
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if w.sums[mid] > n {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo
+}
@@ -1,61 +1,679 @@
 package importer
 
 import (
+	"CarDealership/database/catalog"
 	"CarDealership/database/loader"
+	"CarDealership/database/models"
+	"CarDealership/validation"
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 )
 
+const (
+	// copyBatchSize — сколько новых или изменённых машин копится перед отправкой в базу через COPY
+	copyBatchSize = 5000
+	// progressEvery — как часто (в записях) вызывается Options.Progress
+	progressEvery = 10000
+	// maxReportedErrors — сколько ошибок в данных попадает в отчёт; остальные только считаются
+	maxReportedErrors = 100
+)
+
+// ErrInvalidData — в файлах есть записи, которые нельзя импортировать; подробности в Report.Errors
+var ErrInvalidData = errors.New("импорт отменён: в данных есть ошибки")
+
+// Source — источник записей для импорта. Дилеры читаются целиком раньше машин,
+// чтобы машины могли ссылаться на дилеров из тех же файлов.
+type Source interface {
+	EachDealer(fn func(loader.DealerRecord) error) error
+	EachCar(fn func(loader.CarRecord) error) error
+}
+
+// Options — параметры импорта
+type Options struct {
+	// DryRun — выполнить импорт в транзакции и откатить её, только чтобы получить отчёт
+	DryRun bool
+	// Diff — собирать построчный список изменений в Report.Changes
+	Diff bool
+	// Progress вызывается по мере обработки записей; stage — "dealers" или "cars"
+	Progress func(stage string, processed int)
+}
+
+// Counts — итог импорта по одному типу записей
+type Counts struct {
+	Inserted int
+	Updated  int
+	Skipped  int
+}
+
+// Report — результат импорта: счётчики, построчный diff и ошибки в данных
+type Report struct {
+	Dealers Counts
+	Cars    Counts
+	// MakesAdded и ModelsAdded — сколько марок и моделей машин добавлено в каталог
+	MakesAdded  int
+	ModelsAdded int
+	Changes     []string
+	// Errors — первые maxReportedErrors ошибок, ErrorCount — их общее число
+	Errors     []string
+	ErrorCount int
+}
+
+func (r *Report) fail(format string, args ...interface{}) {
+	r.ErrorCount++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
 func ImportData(ctx context.Context, conn *pgx.Conn, carsFile string, dealersFile string) error {
-	// Загружаем дилеров
-	dealers, err := loader.LoadDealersFromJSON(dealersFile)
+	report, err := Upsert(ctx, conn, loader.Files{dealersFile, carsFile}, Options{
+		Progress: func(stage string, processed int) {
+			fmt.Printf("  … %s: обработано %d\n", stage, processed)
+		},
+	})
+	if report != nil {
+		for _, msg := range report.Errors {
+			fmt.Println("  ❌", msg)
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("ошибка загрузки дилеров: %v", err)
+		return err
 	}
 
-	// Загружаем машины
-	cars, err := loader.LoadCarsFromJSON(carsFile)
+	fmt.Printf("✅ Дилеры: добавлено %d, обновлено %d, без изменений %d\n",
+		report.Dealers.Inserted, report.Dealers.Updated, report.Dealers.Skipped)
+	fmt.Printf("✅ Машины: добавлено %d, обновлено %d, без изменений %d\n",
+		report.Cars.Inserted, report.Cars.Updated, report.Cars.Skipped)
+	if report.MakesAdded > 0 || report.ModelsAdded > 0 {
+		fmt.Printf("✅ Каталог: добавлено марок %d, моделей %d\n", report.MakesAdded, report.ModelsAdded)
+	}
+	return nil
+}
+
+// Upsert загружает дилеров и машины в одной транзакции, не создавая дубликатов.
+// Дилер сопоставляется с существующим по внешнему ключу, а если его нет — по имени без учёта регистра.
+// Машина сопоставляется по внешнему ключу, по id (например, из выгрузки) или по совпадению
+// марки, модели, года, мощности, цвета и дилера. Марки и модели, которых нет в каталоге, добавляются в него.
+// Совпавшие записи обновляются, если отличаются,
+// иначе пропускаются. Новые записи вставляются через COPY, изменения — одним UPDATE на пачку.
+// При ошибках в данных ничего не сохраняется, а Report.Errors содержит найденные проблемы.
+func Upsert(ctx context.Context, conn *pgx.Conn, src Source, opts Options) (*Report, error) {
+	tx, err := conn.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("ошибка загрузки машин: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Параллельный импорт мог бы вставить одну и ту же запись дважды
+	if _, err := tx.Exec(ctx, "LOCK TABLE dealers, cars, makes, models IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return nil, err
 	}
 
-	// Вставляем дилеров
-	dealerIDs := make(map[int]int) // индекс в слайсе -> id в базе
-	for i, dealer := range dealers {
-		var id int
-		err := conn.QueryRow(ctx,
-			`INSERT INTO dealers (name, city, address, area, rating) 
-             VALUES ($1, $2, $3, $4, $5) RETURNING id`,
-			dealer.Name, dealer.City, dealer.Address, dealer.Area, dealer.Rating,
-		).Scan(&id)
+	u := &upserter{ctx: ctx, tx: tx, opts: opts, report: &Report{}}
+	if u.dealers, err = loadDealers(ctx, tx); err != nil {
+		return nil, err
+	}
+	if u.cars, err = loadCars(ctx, tx); err != nil {
+		return nil, err
+	}
+	if u.catalog, err = catalog.Load(ctx, tx); err != nil {
+		return nil, err
+	}
 
+	processed := 0
+	err = src.EachDealer(func(rec loader.DealerRecord) error {
+		processed++
+		u.upsertDealer(processed, rec)
+		u.progress("dealers", processed, false)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.progress("dealers", processed, true)
+	if err := u.flushDealers(); err != nil {
+		return nil, err
+	}
+
+	processed = 0
+	err = src.EachCar(func(rec loader.CarRecord) error {
+		processed++
+		if err := u.upsertCar(processed, rec); err != nil {
+			return err
+		}
+		u.progress("cars", processed, false)
+		if len(u.carInserts) >= copyBatchSize || len(u.carUpdates) >= copyBatchSize {
+			return u.flushCars()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.progress("cars", processed, true)
+	if err := u.flushCars(); err != nil {
+		return nil, err
+	}
+
+	if u.report.ErrorCount > 0 {
+		return u.report, ErrInvalidData
+	}
+	if opts.DryRun {
+		return u.report, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return u.report, nil
+}
+
+// upserter хранит состояние одного импорта: индексы существующих записей и пачки изменений
+type upserter struct {
+	ctx    context.Context
+	tx     pgx.Tx
+	opts   Options
+	report *Report
+
+	dealers    *dealerIndex
+	newDealers []*existingDealer
+	// changedDealers — существующие дилеры, изменённые импортом, в порядке первого изменения
+	changedDealers []*existingDealer
+
+	cars       *carIndex
+	catalog    *catalog.Index
+	carInserts [][]interface{}
+	carUpdates [][]interface{}
+}
+
+func (u *upserter) progress(stage string, processed int, done bool) {
+	if u.opts.Progress == nil || processed == 0 {
+		return
+	}
+	// По завершении сообщаем итог, если он не совпал с очередной отметкой
+	if (processed%progressEvery == 0) != done {
+		u.opts.Progress(stage, processed)
+	}
+}
+
+func (u *upserter) change(format string, args ...interface{}) {
+	if u.opts.Diff {
+		u.report.Changes = append(u.report.Changes, fmt.Sprintf(format, args...))
+	}
+}
+
+// existingDealer — дилер, уже сохранённый в базе или добавленный этим импортом (тогда ID до flushDealers равен 0)
+type existingDealer struct {
+	models.Dealer
+	externalKey string
+	isNew       bool
+	changed     bool
+}
+
+type dealerIndex struct {
+	byID   map[int]*existingDealer
+	byKey  map[string]*existingDealer
+	byName map[string]*existingDealer
+}
+
+func (idx *dealerIndex) add(d *existingDealer) {
+	if d.ID > 0 {
+		idx.byID[d.ID] = d
+	}
+	if d.externalKey != "" {
+		idx.byKey[d.externalKey] = d
+	}
+	if _, ok := idx.byName[nameKey(d.Name)]; !ok {
+		idx.byName[nameKey(d.Name)] = d
+	}
+}
+
+// match ищет дилера по внешнему ключу, а затем по имени.
+// По имени сопоставляется только дилер без собственного внешнего ключа — иначе это другой дилер.
+func (idx *dealerIndex) match(externalKey, name string) *existingDealer {
+	if externalKey != "" {
+		if d, ok := idx.byKey[externalKey]; ok {
+			return d
+		}
+	}
+	d, ok := idx.byName[nameKey(name)]
+	if !ok || (externalKey != "" && d.externalKey != "") {
+		return nil
+	}
+	return d
+}
+
+// resolve находит дилера по ссылке из записи машины: внешнему ключу или имени
+func (idx *dealerIndex) resolve(ref string) *existingDealer {
+	if d, ok := idx.byKey[ref]; ok {
+		return d
+	}
+	return idx.byName[nameKey(ref)]
+}
+
+func loadDealers(ctx context.Context, tx pgx.Tx) (*dealerIndex, error) {
+	rows, err := tx.Query(ctx,
+		"SELECT id, name, city, address, area, rating, COALESCE(external_key, '') FROM dealers ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	idx := &dealerIndex{
+		byID:   make(map[int]*existingDealer),
+		byKey:  make(map[string]*existingDealer),
+		byName: make(map[string]*existingDealer),
+	}
+	for rows.Next() {
+		d := &existingDealer{}
+		if err := rows.Scan(&d.ID, &d.Name, &d.City, &d.Address, &d.Area, &d.Rating, &d.externalKey); err != nil {
+			return nil, err
+		}
+		idx.add(d)
+	}
+	return idx, rows.Err()
+}
+
+func (u *upserter) upsertDealer(n int, rec loader.DealerRecord) {
+	label := fmt.Sprintf("дилер #%d «%s»", n, rec.Name)
+	if errs := validation.ValidateDealer(rec.Dealer); errs != nil {
+		u.report.fail("%s: %v", label, errs)
+		return
+	}
+
+	current := u.dealers.match(rec.ExternalKey, rec.Name)
+	if current == nil {
+		// id из файла не используется: его выдаст последовательность в flushDealers
+		d := &existingDealer{Dealer: rec.Dealer, externalKey: rec.ExternalKey, isNew: true}
+		d.ID = 0
+		u.dealers.add(d)
+		u.newDealers = append(u.newDealers, d)
+		u.report.Dealers.Inserted++
+		u.change("+ %s", label)
+		return
+	}
+
+	diff := &fieldDiff{}
+	diff.add("name", current.Name, rec.Name)
+	diff.add("city", current.City, rec.City)
+	diff.add("address", current.Address, rec.Address)
+	diff.add("area", current.Area, rec.Area)
+	if rec.ExternalKey != "" {
+		diff.add("external_key", current.externalKey, rec.ExternalKey)
+	}
+	if diff.empty() {
+		u.report.Dealers.Skipped++
+		return
+	}
+
+	if u.dealers.byName[nameKey(current.Name)] == current {
+		delete(u.dealers.byName, nameKey(current.Name))
+	}
+	current.Dealer = models.Dealer{ID: current.ID, Name: rec.Name, City: rec.City, Address: rec.Address, Area: rec.Area, Rating: current.Rating}
+	if rec.ExternalKey != "" {
+		current.externalKey = rec.ExternalKey
+	}
+	u.dealers.add(current)
+
+	// Новый дилер, встретившийся повторно, просто будет вставлен в последней редакции
+	if !current.isNew && !current.changed {
+		current.changed = true
+		u.changedDealers = append(u.changedDealers, current)
+	}
+
+	u.report.Dealers.Updated++
+	u.change("~ %s (id %d): %s", label, current.ID, diff)
+}
+
+// flushDealers сохраняет новых и изменённых дилеров.
+// Идентификаторы новых дилеров берутся из последовательности заранее, чтобы машины могли на них ссылаться.
+func (u *upserter) flushDealers() error {
+	if len(u.newDealers) > 0 {
+		rows, err := u.tx.Query(u.ctx,
+			"SELECT nextval(pg_get_serial_sequence('dealers', 'id')) FROM generate_series(1, $1)", len(u.newDealers))
+		if err != nil {
+			return err
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			return err
+		}
+
+		copyRows := make([][]interface{}, len(u.newDealers))
+		for i, d := range u.newDealers {
+			d.ID = ids[i]
+			u.dealers.byID[d.ID] = d
+			// Рейтинг из файла не используется: новый дилер начинает с априорного, дальше его меняют отзывы
+			copyRows[i] = []interface{}{d.ID, d.Name, d.City, d.Address, d.Area, models.ReviewPriorMean, nullable(d.externalKey)}
+		}
+		_, err = u.tx.CopyFrom(u.ctx, pgx.Identifier{"dealers"},
+			[]string{"id", "name", "city", "address", "area", "rating", "external_key"},
+			pgx.CopyFromRows(copyRows))
 		if err != nil {
-			return fmt.Errorf("ошибка вставки дилера %s: %v", dealer.Name, err)
+			return fmt.Errorf("ошибка вставки дилеров: %w", err)
 		}
-		dealerIDs[i] = id
+		u.newDealers = nil
 	}
 
-	fmt.Printf("✅ Добавлено %d дилеров\n", len(dealers))
+	if len(u.changedDealers) > 0 {
+		updates := make([][]interface{}, len(u.changedDealers))
+		for i, d := range u.changedDealers {
+			updates[i] = []interface{}{d.ID, d.Name, d.City, d.Address, d.Area, nullable(d.externalKey)}
+		}
+		err := updateFrom(u.ctx, u.tx, "dealers",
+			[]string{"name", "city", "address", "area", "external_key"}, updates)
+		if err != nil {
+			return fmt.Errorf("ошибка обновления дилеров: %w", err)
+		}
+		u.changedDealers = nil
+	}
+	return nil
+}
+
+// existingCar — машина, уже сохранённая в базе (или добавленная этим импортом)
+type existingCar struct {
+	models.Car
+	externalKey string
+	// matched — машина уже сопоставлена с записью из файла и не может совпасть повторно
+	matched bool
+}
+
+type carIndex struct {
+	byID      map[int]*existingCar
+	byKey     map[string]*existingCar
+	byVIN     map[string]*existingCar
+	byNatural map[string][]*existingCar
+}
+
+func (idx *carIndex) add(c *existingCar) {
+	idx.byID[c.ID] = c
+	if c.externalKey != "" {
+		idx.byKey[c.externalKey] = c
+	}
+	if c.VIN != nil {
+		idx.byVIN[*c.VIN] = c
+	}
+	key := naturalKey(c.Car)
+	idx.byNatural[key] = append(idx.byNatural[key], c)
+}
+
+// match ищет машину по внешнему ключу, по id, по VIN или по совпадению основных характеристик.
+// Одинаковые машины из файла сопоставляются с разными строками базы; по характеристикам
+// не сопоставляются машины с разными VIN.
+func (idx *carIndex) match(rec loader.CarRecord, car models.Car) *existingCar {
+	if rec.ExternalKey != "" {
+		return idx.byKey[rec.ExternalKey]
+	}
+	if c, ok := idx.byID[rec.ID]; ok && rec.ID > 0 && !c.matched {
+		return c
+	}
+	if car.VIN != nil {
+		if c, ok := idx.byVIN[*car.VIN]; ok {
+			return c
+		}
+	}
+	for _, c := range idx.byNatural[naturalKey(car)] {
+		if !c.matched && c.externalKey == "" && (c.VIN == nil || car.VIN == nil) {
+			return c
+		}
+	}
+	return nil
+}
+
+// carColumns — колонки машин, которые записывает импорт; значения в том же порядке возвращает carValues
+var carColumns = []string{"firm", "model", "year", "power", "color", "price", "dealer_id",
+	"vin", "mileage", "condition", "body_type", "fuel_type", "transmission", "drivetrain", "trim"}
+
+func carValues(car models.Car) []interface{} {
+	return []interface{}{car.Firm, car.Model, car.Year, car.Power, car.Color, car.Price, car.DealerID,
+		car.VIN, car.Mileage, car.Condition, car.BodyType, car.FuelType, car.Transmission, car.Drivetrain, car.Trim}
+}
+
+func loadCars(ctx context.Context, tx pgx.Tx) (*carIndex, error) {
+	rows, err := tx.Query(ctx,
+		"SELECT id, "+strings.Join(carColumns, ", ")+", COALESCE(external_key, '') FROM cars ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	idx := &carIndex{
+		byID:      make(map[int]*existingCar),
+		byKey:     make(map[string]*existingCar),
+		byVIN:     make(map[string]*existingCar),
+		byNatural: make(map[string][]*existingCar),
+	}
+	for rows.Next() {
+		c := &existingCar{}
+		if err := rows.Scan(&c.ID, &c.Firm, &c.Model, &c.Year, &c.Power, &c.Color, &c.Price, &c.DealerID,
+			&c.VIN, &c.Mileage, &c.Condition, &c.BodyType, &c.FuelType, &c.Transmission, &c.Drivetrain, &c.Trim,
+			&c.externalKey); err != nil {
+			return nil, err
+		}
+		idx.add(c)
+	}
+	return idx, rows.Err()
+}
+
+// upsertCar сопоставляет машину с существующими; ошибки в данных попадают в отчёт,
+// а возвращается только ошибка базы данных
+func (u *upserter) upsertCar(n int, rec loader.CarRecord) error {
+	label := fmt.Sprintf("машина #%d «%s %s»", n, rec.Firm, rec.Model)
+
+	car := rec.Car
+	car.ID = 0
+	switch {
+	case rec.Dealer != "":
+		d := u.dealers.resolve(rec.Dealer)
+		if d == nil {
+			u.report.fail("%s: дилер «%s» не найден", label, rec.Dealer)
+			return nil
+		}
+		if rec.DealerID != nil && *rec.DealerID != d.ID {
+			u.report.fail("%s: dealer «%s» (id %d) не совпадает с dealer_id %d", label, rec.Dealer, d.ID, *rec.DealerID)
+			return nil
+		}
+		id := d.ID
+		car.DealerID = &id
+	case rec.DealerID != nil:
+		if _, ok := u.dealers.byID[*rec.DealerID]; !ok {
+			u.report.fail("%s: дилер с id %d не найден", label, *rec.DealerID)
+			return nil
+		}
+	}
+
+	validation.NormalizeCar(&car)
+	if errs := validation.ValidateCar(car); errs != nil {
+		u.report.fail("%s: %v", label, errs)
+		return nil
+	}
+
+	addedMake, addedModel, err := u.catalog.Ensure(u.ctx, u.tx, &car)
+	if err != nil {
+		return fmt.Errorf("ошибка пополнения каталога: %w", err)
+	}
+	if addedMake != nil {
+		u.report.MakesAdded++
+		u.change("+ марка «%s»", addedMake.Name)
+	}
+	if addedModel != nil {
+		u.report.ModelsAdded++
+		u.change("+ модель «%s %s»", car.Firm, addedModel.Name)
+	}
+
+	current := u.cars.match(rec, car)
+	if current != nil && current.matched {
+		if rec.ExternalKey != "" {
+			u.report.fail("%s: external_key «%s» повторяется в файлах", label, rec.ExternalKey)
+		} else {
+			u.report.fail("%s: VIN %s повторяется в файлах", label, *car.VIN)
+		}
+		return nil
+	}
+	if car.VIN != nil {
+		if owner := u.cars.byVIN[*car.VIN]; owner != nil && owner != current {
+			u.report.fail("%s: VIN %s уже принадлежит другой машине", label, *car.VIN)
+			return nil
+		}
+	}
+	if current == nil {
+		// Новые машины индексируем только по ключу и VIN — чтобы поймать повторы
+		added := &existingCar{externalKey: rec.ExternalKey, matched: true}
+		if rec.ExternalKey != "" {
+			u.cars.byKey[rec.ExternalKey] = added
+		}
+		if car.VIN != nil {
+			u.cars.byVIN[*car.VIN] = added
+		}
+		u.carInserts = append(u.carInserts, append(carValues(car), nullable(rec.ExternalKey)))
+		u.report.Cars.Inserted++
+		u.change("+ %s", label)
+		return nil
+	}
+	current.matched = true
 
-	// Вставляем машины, распределяя их по дилерам
-	for i, car := range cars {
-		// Распределяем машины по дилерам циклически
-		dealerIndex := i % len(dealers)
-		dealerID := dealerIDs[dealerIndex]
+	// Дилера машины меняет только передача (триггер cars_dealer_move); импорт её не заменяет
+	if current.DealerID != nil && (car.DealerID == nil || *car.DealerID != *current.DealerID) {
+		u.report.fail("%s (id %d): смена дилера %s → %s требует передачи через /api/transfers",
+			label, current.ID, formatDealerID(current.DealerID), formatDealerID(car.DealerID))
+		return nil
+	}
+
+	diff := &fieldDiff{}
+	diff.add("firm", current.Firm, car.Firm)
+	diff.add("model", current.Model, car.Model)
+	diff.add("year", strconv.Itoa(current.Year), strconv.Itoa(car.Year))
+	diff.add("power", strconv.Itoa(current.Power), strconv.Itoa(car.Power))
+	diff.add("color", current.Color, car.Color)
+	diff.add("price", strconv.Itoa(current.Price), strconv.Itoa(car.Price))
+	diff.add("dealer_id", formatDealerID(current.DealerID), formatDealerID(car.DealerID))
+	diff.add("vin", formatVIN(current.VIN), formatVIN(car.VIN))
+	diff.add("mileage", strconv.Itoa(current.Mileage), strconv.Itoa(car.Mileage))
+	diff.add("condition", current.Condition, car.Condition)
+	diff.add("body_type", current.BodyType, car.BodyType)
+	diff.add("fuel_type", current.FuelType, car.FuelType)
+	diff.add("transmission", current.Transmission, car.Transmission)
+	diff.add("drivetrain", current.Drivetrain, car.Drivetrain)
+	diff.add("trim", current.Trim, car.Trim)
+	if diff.empty() {
+		u.report.Cars.Skipped++
+		return nil
+	}
 
-		_, err := conn.Exec(ctx,
-			`INSERT INTO cars (firm, model, year, power, color, price, dealer_id) 
-             VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-			car.Firm, car.Model, car.Year, car.Power, car.Color, car.Price, dealerID,
-		)
+	if current.VIN != nil {
+		delete(u.cars.byVIN, *current.VIN)
+	}
+	if car.VIN != nil {
+		u.cars.byVIN[*car.VIN] = current
+	}
+	u.carUpdates = append(u.carUpdates, append([]interface{}{current.ID}, carValues(car)...))
+	u.report.Cars.Updated++
+	u.change("~ %s (id %d): %s", label, current.ID, diff)
+	return nil
+}
 
+// flushCars отправляет накопленные вставки через COPY и обновления одним UPDATE
+func (u *upserter) flushCars() error {
+	if len(u.carInserts) > 0 {
+		_, err := u.tx.CopyFrom(u.ctx, pgx.Identifier{"cars"},
+			append(carColumns[:len(carColumns):len(carColumns)], "external_key"),
+			pgx.CopyFromRows(u.carInserts))
 		if err != nil {
-			return fmt.Errorf("ошибка вставки машины %s %s: %v", car.Firm, car.Model, err)
+			return fmt.Errorf("ошибка вставки машин: %w", err)
 		}
+		u.carInserts = u.carInserts[:0]
 	}
 
-	fmt.Printf("✅ Добавлено %d машин\n", len(cars))
+	if len(u.carUpdates) > 0 {
+		err := updateFrom(u.ctx, u.tx, "cars", carColumns, u.carUpdates)
+		if err != nil {
+			return fmt.Errorf("ошибка обновления машин: %w", err)
+		}
+		u.carUpdates = u.carUpdates[:0]
+	}
 	return nil
 }
+
+// updateFrom обновляет строки table по id: значения копируются во временную таблицу
+// и применяются одним UPDATE ... FROM. Первый элемент каждой строки rows — id.
+func updateFrom(ctx context.Context, tx pgx.Tx, table string, columns []string, rows [][]interface{}) error {
+	staging := "import_" + table
+	_, err := tx.Exec(ctx, fmt.Sprintf(
+		"CREATE TEMP TABLE IF NOT EXISTS %s ON COMMIT DROP AS SELECT id, %s FROM %s WITH NO DATA",
+		staging, strings.Join(columns, ", "), table))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "TRUNCATE "+staging); err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{staging}, append([]string{"id"}, columns...), pgx.CopyFromRows(rows))
+	if err != nil {
+		return err
+	}
+
+	set := make([]string, len(columns))
+	for i, c := range columns {
+		set[i] = fmt.Sprintf("%s = s.%s", c, c)
+	}
+	_, err = tx.Exec(ctx, fmt.Sprintf("UPDATE %s AS t SET %s FROM %s AS s WHERE t.id = s.id",
+		table, strings.Join(set, ", "), staging))
+	return err
+}
+
+// fieldDiff накапливает изменившиеся поля в виде «поле: было → стало»
+type fieldDiff struct {
+	parts []string
+}
+
+func (d *fieldDiff) add(field, before, after string) {
+	if before != after {
+		d.parts = append(d.parts, fmt.Sprintf("%s: %q → %q", field, before, after))
+	}
+}
+
+func (d *fieldDiff) empty() bool {
+	return len(d.parts) == 0
+}
+
+func (d *fieldDiff) String() string {
+	return strings.Join(d.parts, ", ")
+}
+
+func nameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func naturalKey(car models.Car) string {
+	return strings.Join([]string{
+		nameKey(car.Firm), nameKey(car.Model), strconv.Itoa(car.Year),
+		strconv.Itoa(car.Power), nameKey(car.Color), formatDealerID(car.DealerID),
+	}, "|")
+}
+
+func formatVIN(vin *string) string {
+	if vin == nil {
+		return ""
+	}
+	return *vin
+}
+
+// nullable превращает пустую строку в NULL
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func formatDealerID(id *int) string {
+	if id == nil {
+		return "—"
+	}
+	return strconv.Itoa(*id)
+}
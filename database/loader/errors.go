@@ -0,0 +1,50 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxFileErrors — после стольких ошибок разбор файла прекращается
+const maxFileErrors = 50
+
+// errStop прерывает разбор файла: ошибок набралось maxFileErrors или дальше читать невозможно
+var errStop = errors.New("разбор файла прекращён")
+
+// FileError — ошибка в файле начальных данных с позицией.
+// Line и Column считаются с единицы (колонка — в байтах); Pointer — путь к значению (JSON Pointer).
+type FileError struct {
+	File    string
+	Line    int
+	Column  int
+	Pointer string
+	Message string
+}
+
+func (e *FileError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.File)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ":%d:%d", e.Line, e.Column)
+	}
+	if e.Pointer != "" {
+		b.WriteString(": " + e.Pointer)
+	}
+	b.WriteString(": " + e.Message)
+	return b.String()
+}
+
+// Errors — все ошибки, найденные в файле
+type Errors []*FileError
+
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, fe := range e {
+		lines[i] = fe.Error()
+	}
+	if len(e) >= maxFileErrors {
+		lines = append(lines, fmt.Sprintf("разбор остановлен после %d ошибок", maxFileErrors))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,67 @@
+package loader
+
+import (
+	"CarDealership/jsonschema"
+	_ "embed"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Форматы файлов начальных данных
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatYAML   = "yaml"
+)
+
+// CurrentVersion — актуальная версия формата начальных данных
+const CurrentVersion = 1
+
+// ErrUnknownFormat — расширение файла не соответствует ни одному поддерживаемому формату
+var ErrUnknownFormat = errors.New("неподдерживаемый формат файла: ожидается .json, .ndjson, .jsonl, .yaml или .yml")
+
+var (
+	//go:embed schema/seed.v1.schema.json
+	schemaV1Source []byte
+	//go:embed schema/seed.v0.schema.json
+	schemaV0Source []byte
+
+	// schemaV1 — схема версии 1 (JSON, NDJSON, YAML)
+	schemaV1 = jsonschema.MustCompile(schemaV1Source)
+	// schemaV0 — исходный формат без версии, только для JSON
+	schemaV0 = jsonschema.MustCompile(schemaV0Source)
+)
+
+// Виды записей и ключи, под которыми они лежат в документе
+const (
+	kindDealer = "dealer"
+	kindCar    = "car"
+)
+
+var kindByKey = map[string]string{
+	"dealers": kindDealer,
+	"cars":    kindCar,
+}
+
+// DetectFormat определяет формат файла по расширению
+func DetectFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("%s: %w", path, ErrUnknownFormat)
+}
+
+// schemaForVersion возвращает схему указанной версии формата
+func schemaForVersion(version interface{}) (*jsonschema.Schema, error) {
+	if n, ok := toInt(version); ok && n == CurrentVersion {
+		return schemaV1, nil
+	}
+	return nil, fmt.Errorf("неподдерживаемая версия формата %v (поддерживается %d)", version, CurrentVersion)
+}
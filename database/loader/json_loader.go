@@ -2,41 +2,508 @@ package loader
 
 import (
 	"CarDealership/database/models"
+	"CarDealership/jsonschema"
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strconv"
 )
 
+// DealerRecord — дилер из файла начальных данных.
+// ExternalKey — необязательный ключ внешней системы; без него дилер сопоставляется по имени.
+type DealerRecord struct {
+	models.Dealer
+	ExternalKey string `json:"external_key"`
+}
+
+// CarRecord — машина из файла начальных данных.
+// Дилер задаётся либо dealer_id, либо полем dealer — именем или внешним ключом дилера.
+type CarRecord struct {
+	models.Car
+	ExternalKey string `json:"external_key"`
+	Dealer      string `json:"dealer"`
+}
+
+// Files — набор файлов начальных данных; формат каждого определяется по расширению.
+// Записи JSON и NDJSON читаются потоково, по одной, поэтому размер файла не ограничен объёмом памяти.
+// Каждая запись проверяется по схеме; ошибки возвращаются как Errors с номерами строк и колонок.
+type Files []string
+
+// EachDealer вызывает fn для каждого дилера из всех файлов по порядку
+func (f Files) EachDealer(fn func(DealerRecord) error) error {
+	for _, path := range f {
+		if err := streamFile(path, fn, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachCar вызывает fn для каждой машины из всех файлов по порядку
+func (f Files) EachCar(fn func(CarRecord) error) error {
+	for _, path := range f {
+		if err := streamFile(path, nil, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func LoadCarsFromJSON(filename string) ([]models.Car, error) {
-	file, err := os.Open(filename)
+	records, err := LoadCarRecords(filename)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	var data struct {
-		Cars []models.Car `json:"cars"`
+	cars := make([]models.Car, len(records))
+	for i, rec := range records {
+		cars[i] = rec.Car
 	}
+	return cars, nil
+}
 
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&data); err != nil {
+func LoadDealersFromJSON(filename string) ([]models.Dealer, error) {
+	records, err := LoadDealerRecords(filename)
+	if err != nil {
 		return nil, err
 	}
 
-	return data.Cars, nil
+	dealers := make([]models.Dealer, len(records))
+	for i, rec := range records {
+		dealers[i] = rec.Dealer
+	}
+	return dealers, nil
 }
 
-func LoadDealersFromJSON(filename string) ([]models.Dealer, error) {
-	file, err := os.Open(filename)
+// LoadCarRecords читает машины из файла начальных данных
+func LoadCarRecords(filename string) ([]CarRecord, error) {
+	_, cars, err := LoadSeedFile(filename)
+	return cars, err
+}
+
+// LoadDealerRecords читает дилеров из файла начальных данных
+func LoadDealerRecords(filename string) ([]DealerRecord, error) {
+	dealers, _, err := LoadSeedFile(filename)
+	return dealers, err
+}
+
+// LoadSeedFile читает файл начальных данных целиком в память.
+// Для больших файлов используйте Files — он не держит записи в памяти.
+func LoadSeedFile(filename string) ([]DealerRecord, []CarRecord, error) {
+	var dealers []DealerRecord
+	var cars []CarRecord
+	err := streamFile(filename,
+		func(d DealerRecord) error {
+			dealers = append(dealers, d)
+			return nil
+		},
+		func(c CarRecord) error {
+			cars = append(cars, c)
+			return nil
+		},
+	)
+	return dealers, cars, err
+}
+
+// streamFile разбирает файл в формате, определённом по расширению.
+// Если обработчик равен nil, записи этого вида пропускаются без проверки.
+func streamFile(path string, onDealer func(DealerRecord) error, onCar func(CarRecord) error) error {
+	format, err := DetectFormat(path)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
 	}
 	defer file.Close()
 
-	var dealers []models.Dealer
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&dealers); err != nil {
+	s := &sink{file: path, onDealer: onDealer, onCar: onCar}
+	switch format {
+	case FormatJSON:
+		err = streamJSON(file, s)
+	case FormatNDJSON:
+		err = streamNDJSON(file, s)
+	case FormatYAML:
+		err = loadYAML(file, s)
+	}
+
+	if err != nil && !errors.Is(err, errStop) {
+		var fe *FileError
+		var list Errors
+		if !errors.As(err, &fe) && !errors.As(err, &list) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return err
+	}
+	if len(s.errs) > 0 {
+		return s.errs
+	}
+	return nil
+}
+
+// sink проверяет записи одного файла по схеме, передаёт их обработчикам и копит ошибки
+type sink struct {
+	file     string
+	onDealer func(DealerRecord) error
+	onCar    func(CarRecord) error
+	errs     Errors
+}
+
+// wants сообщает, нужны ли вызывающему записи этого вида
+func (s *sink) wants(kind string) bool {
+	return (kind == kindDealer && s.onDealer != nil) || (kind == kindCar && s.onCar != nil)
+}
+
+// fail добавляет ошибку; когда их набирается maxFileErrors, возвращает errStop
+func (s *sink) fail(line, col int, pointer, message string) error {
+	s.errs = append(s.errs, &FileError{File: s.file, Line: line, Column: col, Pointer: pointer, Message: message})
+	if len(s.errs) >= maxFileErrors {
+		return errStop
+	}
+	return nil
+}
+
+// record проверяет запись по схеме и передаёт обработчику.
+// value — разобранное значение для проверки, raw — его JSON, pointer — путь записи в документе,
+// pos переводит путь внутри записи в строку и колонку файла.
+func (s *sink) record(kind string, schema *jsonschema.Schema, value interface{}, raw []byte, pointer string, pos func(path []string) (int, int)) error {
+	if errs := schema.Validate(value); len(errs) > 0 {
+		found := make(Errors, len(errs))
+		for i, e := range errs {
+			line, col := pos(e.Path)
+			found[i] = &FileError{File: s.file, Line: line, Column: col, Pointer: pointer + e.Pointer(), Message: e.Message}
+		}
+		// Сообщаем в порядке появления в файле
+		sort.SliceStable(found, func(i, j int) bool {
+			return found[i].Line < found[j].Line || (found[i].Line == found[j].Line && found[i].Column < found[j].Column)
+		})
+		for _, fe := range found {
+			if err := s.fail(fe.Line, fe.Column, fe.Pointer, fe.Message); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// После проверки схемой остаётся только разложить значения по полям;
+	// ошибки здесь возможны лишь для чисел, не помещающихся в int
+	var err error
+	switch kind {
+	case kindDealer:
+		var rec DealerRecord
+		if err = json.Unmarshal(raw, &rec); err == nil {
+			return s.onDealer(rec)
+		}
+	case kindCar:
+		var rec CarRecord
+		if err = json.Unmarshal(raw, &rec); err == nil {
+			return s.onCar(rec)
+		}
+	}
+	line, col := pos(nil)
+	return s.fail(line, col, pointer, err.Error())
+}
+
+// streamJSON разбирает JSON-документ потоково. Версия 1 — объект, в котором первым идёт ключ version;
+// без него документ читается по схеме исходного формата: массив дилеров или объект {"cars": [...]}.
+func streamJSON(r io.Reader, s *sink) error {
+	tracker := &lineTracker{r: bufio.NewReaderSize(r, 1<<16), lastDropped: -1}
+	dec := json.NewDecoder(tracker)
+	dec.UseNumber()
+
+	syntaxError := func(err error) error {
+		offset := dec.InputOffset()
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			offset = se.Offset
+		}
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = errors.New("неожиданный конец файла")
+		}
+		line, col := tracker.position(offset)
+		s.fail(line, col, "", err.Error())
+		return errStop
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return syntaxError(err)
+	}
+
+	switch tok {
+	case json.Delim('['):
+		return streamJSONArray(dec, tracker, s, kindDealer, schemaV0.Def("dealers_file").ItemSchema(), "", syntaxError)
+	case json.Delim('{'):
+	default:
+		line, col := tracker.position(0)
+		return s.fail(line, col, "", "ожидается объект с ключом version или массив дилеров")
+	}
+
+	schema := schemaV0.Def("cars_file")
+	for first := true; dec.More(); first = false {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return syntaxError(err)
+		}
+		key := keyTok.(string)
+		// Позиция сразу за ключом: строка точная, колонка указывает на конец ключа
+		keyLine, keyCol := tracker.position(dec.InputOffset())
+
+		if key == "version" {
+			var version json.Number
+			if err := dec.Decode(&version); err != nil {
+				return s.fail(keyLine, keyCol, "/version", "версия должна быть числом")
+			}
+			if !first {
+				return s.fail(keyLine, keyCol, "/version", "ключ version должен идти первым в документе")
+			}
+			if schema, err = schemaForVersion(version); err != nil {
+				return s.fail(keyLine, keyCol, "/version", err.Error())
+			}
+			continue
+		}
+
+		sub, allowed := schema.Property(key)
+		if sub == nil {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return syntaxError(err)
+			}
+			if !allowed {
+				if err := s.fail(keyLine, keyCol, "/"+key, fmt.Sprintf("неизвестное поле «%s»", key)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		kind, isList := kindByKey[key]
+		if !isList {
+			// Служебные ключи вроде $schema проверяем целиком
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return syntaxError(err)
+			}
+			value, _ := decodeGeneric(raw)
+			for _, e := range sub.Validate(value) {
+				if err := s.fail(keyLine, keyCol, "/"+key+e.Pointer(), e.Message); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		open, err := dec.Token()
+		if err != nil {
+			return syntaxError(err)
+		}
+		if open != json.Delim('[') {
+			if err := s.fail(keyLine, keyCol, "/"+key, "ожидается массив"); err != nil {
+				return err
+			}
+			if err := skipRest(dec, open); err != nil {
+				return syntaxError(err)
+			}
+			continue
+		}
+		if err := streamJSONArray(dec, tracker, s, kind, sub.ItemSchema(), "/"+key, syntaxError); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return syntaxError(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		line, col := tracker.position(dec.InputOffset())
+		return s.fail(line, col, "", "лишние данные после конца документа")
+	}
+	return nil
+}
+
+// streamJSONArray разбирает элементы массива по одному; открывающая скобка уже прочитана
+func streamJSONArray(dec *json.Decoder, tracker *lineTracker, s *sink, kind string, schema *jsonschema.Schema,
+	pointer string, syntaxError func(error) error) error {
+	for i := 0; dec.More(); i++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return syntaxError(err)
+		}
+		start := dec.InputOffset() - int64(len(raw))
+		tracker.forget(start)
+		if !s.wants(kind) {
+			continue
+		}
+
+		value, err := decodeGeneric(raw)
+		if err != nil {
+			return syntaxError(err)
+		}
+		pos := func(path []string) (int, int) {
+			return tracker.position(start + int64(locate(raw, path)))
+		}
+		if err := s.record(kind, schema, value, raw, pointer+"/"+strconv.Itoa(i), pos); err != nil {
+			return err
+		}
+	}
+
+	_, err := dec.Token()
+	if err != nil {
+		return syntaxError(err)
+	}
+	return nil
+}
+
+// skipRest дочитывает значение, первый токен которого уже прочитан
+func skipRest(dec *json.Decoder, first json.Token) error {
+	if first != json.Delim('{') && first != json.Delim('[') {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// decodeGeneric разбирает JSON в map/slice с числами json.Number — так их видит валидатор схемы
+func decodeGeneric(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
 		return nil, err
 	}
+	return v, nil
+}
 
-	return dealers, nil
+// locate возвращает смещение значения по пути внутри JSON-записи (0, если путь не найден)
+func locate(raw []byte, path []string) int {
+	if len(path) == 0 {
+		return 0
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return 0
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return 0
+			}
+			start := skipValueSeparators(raw, int(dec.InputOffset()))
+			var sub json.RawMessage
+			if err := dec.Decode(&sub); err != nil {
+				return 0
+			}
+			if key == path[0] {
+				return start + locate(sub, path[1:])
+			}
+		}
+	case json.Delim('['):
+		index, err := strconv.Atoi(path[0])
+		if err != nil {
+			return 0
+		}
+		for i := 0; dec.More(); i++ {
+			start := skipValueSeparators(raw, int(dec.InputOffset()))
+			var sub json.RawMessage
+			if err := dec.Decode(&sub); err != nil {
+				return 0
+			}
+			if i == index {
+				return start + locate(sub, path[1:])
+			}
+		}
+	}
+	return 0
+}
+
+// skipValueSeparators пропускает пробелы, двоеточие и запятую перед значением
+func skipValueSeparators(raw []byte, off int) int {
+	for off < len(raw) {
+		switch raw[off] {
+		case ' ', '\t', '\r', '\n', ':', ',':
+			off++
+		default:
+			return off
+		}
+	}
+	return off
+}
+
+// lineTracker считает переводы строк в прочитанных данных, чтобы переводить смещения в строку и колонку.
+// Хранит только переводы строк после начала текущей записи — память не растёт с размером файла.
+type lineTracker struct {
+	r           io.Reader
+	read        int64
+	newlines    []int64
+	dropped     int
+	lastDropped int64
+}
+
+func (t *lineTracker) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			t.newlines = append(t.newlines, t.read+int64(i))
+		}
+	}
+	t.read += int64(n)
+	return n, err
+}
+
+// forget отбрасывает переводы строк до смещения off — к ним больше не будет запросов
+func (t *lineTracker) forget(off int64) {
+	i := sort.Search(len(t.newlines), func(i int) bool { return t.newlines[i] >= off })
+	if i > 0 {
+		t.lastDropped = t.newlines[i-1]
+		t.dropped += i
+		t.newlines = t.newlines[i:]
+	}
+}
+
+// position переводит смещение в номер строки и колонки (с единицы)
+func (t *lineTracker) position(off int64) (int, int) {
+	i := sort.Search(len(t.newlines), func(i int) bool { return t.newlines[i] >= off })
+	prev := t.lastDropped
+	if i > 0 {
+		prev = t.newlines[i-1]
+	}
+	return t.dropped + i + 1, int(off - prev)
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := strconv.Atoi(string(n))
+		return i, err == nil
+	case int:
+		return n, true
+	case float64:
+		if n == float64(int(n)) {
+			return int(n), true
+		}
+	}
+	return 0, false
 }
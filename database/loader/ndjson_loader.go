@@ -0,0 +1,96 @@
+package loader
+
+import (
+	"CarDealership/jsonschema"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// streamNDJSON разбирает NDJSON: по одному JSON-объекту в строке.
+// Первая непустая строка — заголовок {"version": 1}, каждая следующая — запись
+// с полем "type": "dealer" или "car" и полями записи по схеме.
+func streamNDJSON(r io.Reader, s *sink) error {
+	br := bufio.NewReaderSize(r, 1<<16)
+	var schema = schemaV1
+	header := false
+	counts := map[string]int{}
+
+	for lineNo := 1; ; lineNo++ {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			if ferr := ndjsonLine(s, lineNo, line, &header, &schema, counts); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if !header {
+		return s.fail(1, 1, "", "пустой файл: ожидается заголовок {\"version\": 1}")
+	}
+	return nil
+}
+
+func ndjsonLine(s *sink, lineNo int, line []byte, header *bool, schema **jsonschema.Schema, counts map[string]int) error {
+	value, err := decodeGeneric(line)
+	if err != nil {
+		col := 1
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			col = int(se.Offset)
+		}
+		return s.fail(lineNo, col, "", err.Error())
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return s.fail(lineNo, 1, "", "каждая строка должна быть JSON-объектом")
+	}
+
+	if !*header {
+		*header = true
+		version, ok := obj["version"]
+		if !ok || len(obj) != 1 {
+			return s.fail(lineNo, 1, "", "первая строка должна быть заголовком {\"version\": 1}")
+		}
+		sc, err := schemaForVersion(version)
+		if err != nil {
+			return s.fail(lineNo, 1+locate(line, []string{"version"}), "/version", err.Error())
+		}
+		*schema = sc
+		return nil
+	}
+
+	kind, _ := obj["type"].(string)
+	key := ""
+	for k, v := range kindByKey {
+		if v == kind {
+			key = k
+		}
+	}
+	if key == "" {
+		return s.fail(lineNo, 1+locate(line, []string{"type"}), "/type",
+			fmt.Sprintf("поле type должно быть %q или %q", kindDealer, kindCar))
+	}
+	index := counts[kind]
+	counts[kind]++
+	if !s.wants(kind) {
+		return nil
+	}
+
+	delete(obj, "type")
+	itemSchema, _ := (*schema).Property(key)
+	pos := func(path []string) (int, int) {
+		return lineNo, 1 + locate(line, path)
+	}
+	return s.record(kind, itemSchema.ItemSchema(), obj, line, "/"+key+"/"+strconv.Itoa(index), pos)
+}
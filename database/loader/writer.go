@@ -0,0 +1,156 @@
+package loader
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Writer потоково пишет файл начальных данных текущей версии в формате JSON или NDJSON.
+// В JSON все дилеры должны быть записаны раньше машин.
+type Writer struct {
+	w       *bufio.Writer
+	format  string
+	section string
+}
+
+// dealerV1 и carV1 — записи в том виде, в каком их описывает схема версии 1
+type dealerV1 struct {
+	Type        string `json:"type,omitempty"`
+	ExternalKey string `json:"external_key,omitempty"`
+	Name        string `json:"name"`
+	City        string `json:"city"`
+	Address     string `json:"address"`
+	Area        string `json:"area"`
+}
+
+type carV1 struct {
+	Type         string  `json:"type,omitempty"`
+	ID           int     `json:"id,omitempty"`
+	ExternalKey  string  `json:"external_key,omitempty"`
+	Firm         string  `json:"firm"`
+	Model        string  `json:"model"`
+	Year         int     `json:"year"`
+	Power        int     `json:"power"`
+	Color        string  `json:"color"`
+	Price        int     `json:"price"`
+	DealerID     *int    `json:"dealer_id,omitempty"`
+	Dealer       string  `json:"dealer,omitempty"`
+	VIN          *string `json:"vin,omitempty"`
+	Mileage      int     `json:"mileage,omitempty"`
+	Condition    string  `json:"condition,omitempty"`
+	BodyType     string  `json:"body_type,omitempty"`
+	FuelType     string  `json:"fuel_type,omitempty"`
+	Transmission string  `json:"transmission,omitempty"`
+	Drivetrain   string  `json:"drivetrain,omitempty"`
+	Trim         string  `json:"trim,omitempty"`
+}
+
+// NewWriter начинает файл начальных данных; format — FormatJSON или FormatNDJSON
+func NewWriter(w io.Writer, format string) (*Writer, error) {
+	if format != FormatJSON && format != FormatNDJSON {
+		return nil, fmt.Errorf("запись в формате %q не поддерживается", format)
+	}
+
+	wr := &Writer{w: bufio.NewWriterSize(w, 1<<16), format: format}
+	var err error
+	if format == FormatJSON {
+		_, err = fmt.Fprintf(wr.w, "{\n  \"version\": %d", CurrentVersion)
+	} else {
+		_, err = fmt.Fprintf(wr.w, "{\"version\": %d}\n", CurrentVersion)
+	}
+	return wr, err
+}
+
+// WriteDealer добавляет дилера
+func (w *Writer) WriteDealer(rec DealerRecord) error {
+	d := dealerV1{
+		ExternalKey: rec.ExternalKey,
+		Name:        rec.Name,
+		City:        rec.City,
+		Address:     rec.Address,
+		Area:        rec.Area,
+	}
+	if w.format == FormatNDJSON {
+		d.Type = kindDealer
+	}
+	return w.write("dealers", d)
+}
+
+// WriteCar добавляет машину
+func (w *Writer) WriteCar(rec CarRecord) error {
+	c := carV1{
+		ID:           rec.ID,
+		ExternalKey:  rec.ExternalKey,
+		Firm:         rec.Firm,
+		Model:        rec.Model,
+		Year:         rec.Year,
+		Power:        rec.Power,
+		Color:        rec.Color,
+		Price:        rec.Price,
+		DealerID:     rec.DealerID,
+		Dealer:       rec.Dealer,
+		VIN:          rec.VIN,
+		Mileage:      rec.Mileage,
+		Condition:    rec.Condition,
+		BodyType:     rec.BodyType,
+		FuelType:     rec.FuelType,
+		Transmission: rec.Transmission,
+		Drivetrain:   rec.Drivetrain,
+		Trim:         rec.Trim,
+	}
+	if w.format == FormatNDJSON {
+		c.Type = kindCar
+	}
+	return w.write("cars", c)
+}
+
+// Close завершает документ и сбрасывает буфер
+func (w *Writer) Close() error {
+	if w.format == FormatJSON {
+		if err := w.closeSection(); err != nil {
+			return err
+		}
+		if _, err := w.w.WriteString("\n}\n"); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) write(section string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if w.format == FormatNDJSON {
+		w.w.Write(data)
+		return w.w.WriteByte('\n')
+	}
+
+	if section != w.section {
+		if w.section == "cars" {
+			return errors.New("в JSON дилеры должны быть записаны раньше машин")
+		}
+		if err := w.closeSection(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w.w, ",\n  %q: [\n    ", section)
+		w.section = section
+	} else {
+		w.w.WriteString(",\n    ")
+	}
+	_, err = w.w.Write(data)
+	return err
+}
+
+func (w *Writer) closeSection() error {
+	if w.section == "" {
+		return nil
+	}
+	_, err := w.w.WriteString("\n  ]")
+	return err
+}
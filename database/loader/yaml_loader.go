@@ -0,0 +1,129 @@
+package loader
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadYAML разбирает YAML-документ той же структуры, что и JSON версии 1.
+// YAML читается в память целиком: для очень больших наборов данных используйте JSON или NDJSON.
+func loadYAML(r io.Reader, s *sink) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return s.fail(0, 0, "", err.Error())
+	}
+	if len(root.Content) == 0 {
+		return s.fail(1, 1, "", "пустой файл")
+	}
+	doc := root.Content[0]
+
+	var value interface{}
+	if err := doc.Decode(&value); err != nil {
+		return s.fail(doc.Line, doc.Column, "", err.Error())
+	}
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return s.fail(doc.Line, doc.Column, "", "ожидается объект с ключом version")
+	}
+
+	version, ok := obj["version"]
+	if !ok {
+		return s.fail(doc.Line, doc.Column, "", "не указана версия формата (version)")
+	}
+	schema, err := schemaForVersion(version)
+	if err != nil {
+		n := yamlNodeAt(doc, []string{"version"})
+		return s.fail(n.Line, n.Column, "/version", err.Error())
+	}
+
+	// Верхний уровень проверяем без списков: их элементы проверяются по одному ниже
+	top := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		if _, isList := kindByKey[k]; isList {
+			v = []interface{}{}
+		}
+		top[k] = v
+	}
+	for _, e := range schema.Validate(top) {
+		n := yamlNodeAt(doc, e.Path)
+		if err := s.fail(n.Line, n.Column, e.Pointer(), e.Message); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range []string{"dealers", "cars"} {
+		list, present := obj[key]
+		kind := kindByKey[key]
+		if !present || !s.wants(kind) {
+			continue
+		}
+		items, ok := list.([]interface{})
+		if !ok {
+			n := yamlNodeAt(doc, []string{key})
+			if err := s.fail(n.Line, n.Column, "/"+key, "ожидается массив"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		sub, _ := schema.Property(key)
+		for i, item := range items {
+			path := []string{key, strconv.Itoa(i)}
+			raw, err := json.Marshal(item)
+			if err != nil {
+				n := yamlNodeAt(doc, path)
+				if err := s.fail(n.Line, n.Column, "/"+key+"/"+strconv.Itoa(i), err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+			pos := func(rel []string) (int, int) {
+				n := yamlNodeAt(doc, append(append([]string(nil), path...), rel...))
+				return n.Line, n.Column
+			}
+			if err := s.record(kind, sub.ItemSchema(), item, raw, "/"+key+"/"+strconv.Itoa(i), pos); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// yamlNodeAt находит узел по пути; если путь обрывается, возвращает ближайший найденный узел
+func yamlNodeAt(n *yaml.Node, path []string) *yaml.Node {
+	for _, seg := range path {
+		next := childNode(n, seg)
+		if next == nil {
+			return n
+		}
+		n = next
+	}
+	return n
+}
+
+func childNode(n *yaml.Node, seg string) *yaml.Node {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == seg {
+				return n.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(seg)
+		if err == nil && i >= 0 && i < len(n.Content) {
+			return n.Content[i]
+		}
+	case yaml.AliasNode:
+		return childNode(n.Alias, seg)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// Migrate применяет к базе все ещё не применённые миграции из каталога sql.
+// Каждая миграция выполняется в отдельной транзакции и фиксируется в schema_migrations.
+func Migrate(ctx context.Context, conn *pgx.Conn) error {
+	_, err := conn.Exec(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(200) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+	`)
+	if err != nil {
+		return fmt.Errorf("ошибка создания таблицы миграций: %v", err)
+	}
+
+	list, err := load()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range list {
+		var applied bool
+		err := conn.QueryRow(ctx,
+			"SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1)", m.version).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("ошибка проверки миграции %d: %v", m.version, err)
+		}
+		if applied {
+			continue
+		}
+
+		if err := apply(ctx, conn, m); err != nil {
+			return fmt.Errorf("ошибка применения миграции %d_%s: %v", m.version, m.name, err)
+		}
+		fmt.Printf("✅ Применена миграция %d_%s\n", m.version, m.name)
+	}
+
+	return nil
+}
+
+func apply(ctx context.Context, conn *pgx.Conn, m migration) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, m.sql); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// load читает файлы вида 0001_name.sql и сортирует их по номеру версии
+func load() ([]migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var list []migration
+	for _, entry := range entries {
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("неверное имя файла миграции: %s", entry.Name())
+		}
+
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("неверный номер миграции %s: %v", entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(files, "sql/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, migration{version: version, name: name, sql: string(body)})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+	return list, nil
+}
@@ -0,0 +1,7 @@
+-- Удаление дилера больше не каскадирует на его автомобили:
+-- решение о переносе или удалении машин принимает обработчик DELETE /api/dealers/{id}.
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS cars_dealer_id_fkey;
+
+ALTER TABLE cars
+	ADD CONSTRAINT cars_dealer_id_fkey
+	FOREIGN KEY (dealer_id) REFERENCES dealers(id) ON DELETE RESTRICT;
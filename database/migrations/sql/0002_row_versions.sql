@@ -0,0 +1,21 @@
+-- Версия строки для оптимистичной блокировки (ETag / If-Match).
+-- Триггер увеличивает версию при любом UPDATE, поэтому её не нужно помнить в каждом запросе.
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION bump_row_version() RETURNS trigger AS $$
+BEGIN
+	NEW.version := OLD.version + 1;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS dealers_bump_version ON dealers;
+CREATE TRIGGER dealers_bump_version
+	BEFORE UPDATE ON dealers
+	FOR EACH ROW EXECUTE FUNCTION bump_row_version();
+
+DROP TRIGGER IF EXISTS cars_bump_version ON cars;
+CREATE TRIGGER cars_bump_version
+	BEFORE UPDATE ON cars
+	FOR EACH ROW EXECUTE FUNCTION bump_row_version();
@@ -0,0 +1,16 @@
+-- Сохранённые ответы на POST-запросы с заголовком Idempotency-Key.
+-- scope — метод и путь запроса, чтобы один ключ не пересекался между ресурсами.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	key VARCHAR(255) NOT NULL,
+	scope VARCHAR(255) NOT NULL,
+	request_hash CHAR(64) NOT NULL,
+	status_code INTEGER,
+	content_type VARCHAR(255),
+	etag VARCHAR(255),
+	response_body BYTEA,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (key, scope)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,7 @@
+-- Ключи внешних систем для повторного импорта: по ним команда import находит уже загруженные записи.
+-- Ключ необязателен, но если задан — уникален.
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS external_key VARCHAR(100);
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS external_key VARCHAR(100);
+
+CREATE UNIQUE INDEX IF NOT EXISTS dealers_external_key_idx ON dealers (external_key) WHERE external_key IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS cars_external_key_idx ON cars (external_key) WHERE external_key IS NOT NULL;
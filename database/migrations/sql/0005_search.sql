@@ -0,0 +1,27 @@
+-- Полнотекстовый и нечёткий поиск (GET /api/search).
+-- search_text — нормализованный текст записи для триграмм, search_vector — для полнотекстового поиска.
+-- Конфигурация simple не делает стемминг, поэтому одинаково работает с русскими и латинскими названиями;
+-- ё заменяется на е, как и в запросе.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS search_text TEXT
+	GENERATED ALWAYS AS (replace(lower(firm || ' ' || model || ' ' || color), 'ё', 'е')) STORED;
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', replace(lower(firm || ' ' || model), 'ё', 'е')), 'A') ||
+		setweight(to_tsvector('simple', replace(lower(color), 'ё', 'е')), 'C')
+	) STORED;
+
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS search_text TEXT
+	GENERATED ALWAYS AS (replace(lower(name || ' ' || city || ' ' || address), 'ё', 'е')) STORED;
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', replace(lower(name), 'ё', 'е')), 'A') ||
+		setweight(to_tsvector('simple', replace(lower(city), 'ё', 'е')), 'B') ||
+		setweight(to_tsvector('simple', replace(lower(address), 'ё', 'е')), 'C')
+	) STORED;
+
+CREATE INDEX IF NOT EXISTS cars_search_vector_idx ON cars USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS cars_search_text_trgm_idx ON cars USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS dealers_search_vector_idx ON dealers USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS dealers_search_text_trgm_idx ON dealers USING GIN (search_text gin_trgm_ops);
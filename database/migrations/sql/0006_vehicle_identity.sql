@@ -0,0 +1,15 @@
+-- Идентификация автомобиля: VIN, пробег, состояние и комплектация.
+-- VIN необязателен, но если задан — уникален. Перечислимые значения проверяет приложение,
+-- в базе пустая строка означает «не указано».
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS vin VARCHAR(17);
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS mileage INTEGER NOT NULL DEFAULT 0 CHECK (mileage >= 0);
+-- У машин, заведённых до миграции, пробег 0, поэтому они считаются новыми
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS condition VARCHAR(10) NOT NULL DEFAULT 'new' CHECK (condition IN ('new', 'used'));
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS body_type VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS fuel_type VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS transmission VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS drivetrain VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS trim VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS cars_vin_idx ON cars (vin) WHERE vin IS NOT NULL;
+CREATE INDEX IF NOT EXISTS cars_mileage_idx ON cars (mileage);
@@ -0,0 +1,126 @@
+-- Справочник марок и моделей. Раньше cars.firm и cars.model были свободным текстом, и
+-- «Toyota», «toyota» и «TOYOTA » считались разными марками. Теперь каждая машина ссылается
+-- на модель каталога (model_id), а firm и model хранят каноническое написание.
+
+-- catalog_key — ключ сравнения названий: без учёта регистра, крайних и повторных пробелов.
+-- Совпадает с catalog.Key в коде приложения.
+CREATE OR REPLACE FUNCTION catalog_key(name TEXT) RETURNS TEXT AS $$
+	SELECT lower(btrim(regexp_replace(name, '\s+', ' ', 'g')))
+$$ LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE;
+
+CREATE TABLE IF NOT EXISTS makes (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL CHECK (btrim(name) <> '')
+);
+CREATE UNIQUE INDEX IF NOT EXISTS makes_name_idx ON makes (catalog_key(name));
+
+CREATE TABLE IF NOT EXISTS models (
+	id SERIAL PRIMARY KEY,
+	make_id INTEGER NOT NULL REFERENCES makes(id) ON DELETE RESTRICT,
+	name VARCHAR(100) NOT NULL CHECK (btrim(name) <> '')
+);
+CREATE UNIQUE INDEX IF NOT EXISTS models_make_name_idx ON models (make_id, catalog_key(name));
+
+-- Отчёт о переносе свободного текста в каталог:
+--   merged  — написание value объединено с каноническим resolved_to;
+--   similar — похожие, но разные записи каталога, которые стоит проверить вручную;
+--   unmapped — машина (value — её id), для которой не нашлось записи каталога.
+CREATE TABLE IF NOT EXISTS catalog_conflicts (
+	id SERIAL PRIMARY KEY,
+	entity VARCHAR(10) NOT NULL,
+	kind VARCHAR(10) NOT NULL,
+	value VARCHAR(201) NOT NULL,
+	resolved_to VARCHAR(201),
+	cars_count INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Каноническое написание — самое частое среди вариантов, при равенстве — первое по алфавиту
+INSERT INTO makes (name)
+SELECT DISTINCT ON (catalog_key(firm)) btrim(regexp_replace(firm, '\s+', ' ', 'g'))
+FROM cars
+WHERE catalog_key(firm) <> ''
+GROUP BY catalog_key(firm), btrim(regexp_replace(firm, '\s+', ' ', 'g'))
+ORDER BY catalog_key(firm), count(*) DESC, 1
+ON CONFLICT DO NOTHING;
+
+INSERT INTO models (make_id, name)
+SELECT DISTINCT ON (mk.id, catalog_key(c.model)) mk.id, btrim(regexp_replace(c.model, '\s+', ' ', 'g'))
+FROM cars c
+JOIN makes mk ON catalog_key(mk.name) = catalog_key(c.firm)
+WHERE catalog_key(c.model) <> ''
+GROUP BY mk.id, catalog_key(c.model), btrim(regexp_replace(c.model, '\s+', ' ', 'g'))
+ORDER BY mk.id, catalog_key(c.model), count(*) DESC, 2
+ON CONFLICT DO NOTHING;
+
+INSERT INTO catalog_conflicts (entity, kind, value, resolved_to, cars_count)
+SELECT 'make', 'merged', c.firm, mk.name, count(*)
+FROM cars c
+JOIN makes mk ON catalog_key(mk.name) = catalog_key(c.firm)
+WHERE c.firm <> mk.name
+GROUP BY c.firm, mk.name;
+
+INSERT INTO catalog_conflicts (entity, kind, value, resolved_to, cars_count)
+SELECT 'model', 'merged', mk.name || ' ' || c.model, mk.name || ' ' || mo.name, count(*)
+FROM cars c
+JOIN makes mk ON catalog_key(mk.name) = catalog_key(c.firm)
+JOIN models mo ON mo.make_id = mk.id AND catalog_key(mo.name) = catalog_key(c.model)
+WHERE c.model <> mo.name
+GROUP BY mk.name, c.model, mo.name;
+
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS model_id INTEGER REFERENCES models(id) ON DELETE RESTRICT;
+CREATE INDEX IF NOT EXISTS cars_model_id_idx ON cars (model_id);
+
+UPDATE cars c SET model_id = mo.id, firm = mk.name, model = mo.name
+FROM makes mk
+JOIN models mo ON mo.make_id = mk.id
+WHERE catalog_key(mk.name) = catalog_key(c.firm) AND catalog_key(mo.name) = catalog_key(c.model);
+
+INSERT INTO catalog_conflicts (entity, kind, value, cars_count)
+SELECT 'car', 'unmapped', id::text, 1
+FROM cars
+WHERE model_id IS NULL;
+
+-- Похожие названия (например, «Mercedes» и «Mercedes-Benz») автоматически не объединяются
+INSERT INTO catalog_conflicts (entity, kind, value, resolved_to, cars_count)
+SELECT 'make', 'similar', a.name, b.name,
+	(SELECT count(*) FROM cars c JOIN models mo ON mo.id = c.model_id WHERE mo.make_id = a.id)
+FROM makes a
+JOIN makes b ON a.id <> b.id AND similarity(a.name, b.name) >= 0.5
+WHERE a.name < b.name;
+
+INSERT INTO catalog_conflicts (entity, kind, value, resolved_to, cars_count)
+SELECT 'model', 'similar', mk.name || ' ' || a.name, mk.name || ' ' || b.name,
+	(SELECT count(*) FROM cars WHERE model_id = a.id)
+FROM models a
+JOIN models b ON a.make_id = b.make_id AND a.id <> b.id AND similarity(a.name, b.name) >= 0.5
+JOIN makes mk ON mk.id = a.make_id
+WHERE a.name < b.name;
+
+-- Марка и модель новой или изменённой машины ищутся в каталоге; найденные названия
+-- заменяют присланные, поэтому в cars.firm и cars.model всегда каноническое написание
+CREATE OR REPLACE FUNCTION cars_resolve_model() RETURNS trigger AS $$
+DECLARE
+	resolved RECORD;
+BEGIN
+	SELECT mo.id, mk.name AS make_name, mo.name AS model_name INTO resolved
+	FROM models mo
+	JOIN makes mk ON mk.id = mo.make_id
+	WHERE catalog_key(mk.name) = catalog_key(NEW.firm) AND catalog_key(mo.name) = catalog_key(NEW.model);
+
+	IF NOT FOUND THEN
+		RAISE EXCEPTION 'модели «% %» нет в каталоге', NEW.firm, NEW.model
+			USING ERRCODE = 'foreign_key_violation';
+	END IF;
+
+	NEW.model_id := resolved.id;
+	NEW.firm := resolved.make_name;
+	NEW.model := resolved.model_name;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS cars_resolve_model ON cars;
+CREATE TRIGGER cars_resolve_model
+	BEFORE INSERT OR UPDATE OF firm, model ON cars
+	FOR EACH ROW EXECUTE FUNCTION cars_resolve_model();
@@ -0,0 +1,39 @@
+-- Фотографии и документы автомобилей. Сами файлы лежат во внешнем хранилище (диск или S3),
+-- в базе — только описание и ключи объектов.
+CREATE TABLE IF NOT EXISTS car_media (
+	id SERIAL PRIMARY KEY,
+	car_id INTEGER NOT NULL REFERENCES cars(id) ON DELETE CASCADE,
+	kind VARCHAR(10) NOT NULL CHECK (kind IN ('photo', 'document')),
+	file_name VARCHAR(255) NOT NULL,
+	content_type VARCHAR(100) NOT NULL,
+	size BIGINT NOT NULL CHECK (size >= 0),
+	width INTEGER,
+	height INTEGER,
+	storage_key VARCHAR(300) NOT NULL UNIQUE,
+	thumbnail_key VARCHAR(300),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS car_media_car_id_idx ON car_media (car_id, id);
+
+-- Очередь файлов на удаление из хранилища. Записи car_media удаляются вместе с машиной
+-- любым способом (DELETE, массовые операции, удаление дилера), а триггер ставит их файлы
+-- в очередь, которую разбирает приложение.
+CREATE TABLE IF NOT EXISTS media_deletions (
+	storage_key VARCHAR(300) PRIMARY KEY,
+	queued_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION queue_media_deletion() RETURNS trigger AS $$
+BEGIN
+	INSERT INTO media_deletions (storage_key) VALUES (OLD.storage_key) ON CONFLICT DO NOTHING;
+	IF OLD.thumbnail_key IS NOT NULL THEN
+		INSERT INTO media_deletions (storage_key) VALUES (OLD.thumbnail_key) ON CONFLICT DO NOTHING;
+	END IF;
+	RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS car_media_queue_deletion ON car_media;
+CREATE TRIGGER car_media_queue_deletion
+	AFTER DELETE ON car_media
+	FOR EACH ROW EXECUTE FUNCTION queue_media_deletion();
@@ -0,0 +1,35 @@
+-- Статус автомобиля на складе: available → reserved → sold. Бронь снимается вручную
+-- или автоматически по истечении reserved_until; проданная машина больше не меняет статус.
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'available';
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS reserved_until TIMESTAMPTZ;
+
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS cars_status_check;
+ALTER TABLE cars ADD CONSTRAINT cars_status_check
+	CHECK (status IN ('available', 'reserved', 'sold'));
+
+-- Срок брони есть только у забронированной машины
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS cars_reserved_until_check;
+ALTER TABLE cars ADD CONSTRAINT cars_reserved_until_check
+	CHECK ((status = 'reserved') = (reserved_until IS NOT NULL));
+
+CREATE INDEX IF NOT EXISTS cars_status_idx ON cars (status);
+-- Фоновое снятие просроченных броней читает только забронированные машины
+CREATE INDEX IF NOT EXISTS cars_reserved_until_idx ON cars (reserved_until) WHERE status = 'reserved';
+
+-- Переходы проверяет приложение; триггер страхует от прямых UPDATE в обход API
+CREATE OR REPLACE FUNCTION cars_check_status_transition() RETURNS trigger AS $$
+BEGIN
+	IF NEW.status = OLD.status
+		OR (OLD.status, NEW.status) IN (('available', 'reserved'), ('reserved', 'available'), ('reserved', 'sold')) THEN
+		RETURN NEW;
+	END IF;
+
+	RAISE EXCEPTION 'нельзя перевести машину % из статуса % в %', OLD.id, OLD.status, NEW.status
+		USING ERRCODE = 'check_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS cars_status_transition ON cars;
+CREATE TRIGGER cars_status_transition
+	BEFORE UPDATE OF status ON cars
+	FOR EACH ROW EXECUTE FUNCTION cars_check_status_transition();
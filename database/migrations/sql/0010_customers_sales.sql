@@ -0,0 +1,63 @@
+-- Покупатели и продажи. Продажа связывает машину, дилера и покупателя; у машины может быть
+-- не больше одной продажи, и записи, на которые ссылаются продажи, удалить нельзя.
+CREATE TABLE IF NOT EXISTS customers (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	email VARCHAR(254) NOT NULL DEFAULT '',
+	phone VARCHAR(30) NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	version INTEGER NOT NULL DEFAULT 1
+);
+-- Один адрес — один покупатель; адрес без учёта регистра
+CREATE UNIQUE INDEX IF NOT EXISTS customers_email_key ON customers (lower(email)) WHERE email <> '';
+
+CREATE TABLE IF NOT EXISTS sales (
+	id SERIAL PRIMARY KEY,
+	car_id INTEGER NOT NULL UNIQUE REFERENCES cars(id) ON DELETE RESTRICT,
+	dealer_id INTEGER NOT NULL REFERENCES dealers(id) ON DELETE RESTRICT,
+	customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE RESTRICT,
+	-- price — итоговая цена с учётом скидки, discount — размер скидки
+	price INTEGER NOT NULL CHECK (price > 0),
+	discount INTEGER NOT NULL DEFAULT 0 CHECK (discount >= 0),
+	payment_method VARCHAR(20) NOT NULL
+		CHECK (payment_method IN ('cash', 'card', 'bank_transfer', 'credit', 'leasing')),
+	sold_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	version INTEGER NOT NULL DEFAULT 1
+);
+CREATE INDEX IF NOT EXISTS sales_dealer_sold_at_idx ON sales (dealer_id, sold_at);
+CREATE INDEX IF NOT EXISTS sales_customer_id_idx ON sales (customer_id);
+
+DROP TRIGGER IF EXISTS customers_bump_version ON customers;
+CREATE TRIGGER customers_bump_version
+	BEFORE UPDATE ON customers
+	FOR EACH ROW EXECUTE FUNCTION bump_row_version();
+
+DROP TRIGGER IF EXISTS sales_bump_version ON sales;
+CREATE TRIGGER sales_bump_version
+	BEFORE UPDATE ON sales
+	FOR EACH ROW EXECUTE FUNCTION bump_row_version();
+
+-- Отмена продажи возвращает машину в наличие: переход sold → available разрешён,
+-- только если у машины не осталось записи о продаже. Продать машину из наличия (available → sold)
+-- можно, только записав продажу: строка в sales должна появиться раньше смены статуса
+CREATE OR REPLACE FUNCTION cars_check_status_transition() RETURNS trigger AS $$
+BEGIN
+	IF NEW.status = OLD.status
+		OR (OLD.status, NEW.status) IN (('available', 'reserved'), ('reserved', 'available'), ('reserved', 'sold')) THEN
+		RETURN NEW;
+	END IF;
+
+	IF OLD.status = 'sold' AND NEW.status = 'available'
+		AND NOT EXISTS (SELECT 1 FROM sales WHERE car_id = OLD.id) THEN
+		RETURN NEW;
+	END IF;
+
+	IF OLD.status = 'available' AND NEW.status = 'sold'
+		AND EXISTS (SELECT 1 FROM sales WHERE car_id = OLD.id) THEN
+		RETURN NEW;
+	END IF;
+
+	RAISE EXCEPTION 'нельзя перевести машину % из статуса % в %', OLD.id, OLD.status, NEW.status
+		USING ERRCODE = 'check_violation';
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,79 @@
+-- Часы работы дилеров: одна строка на день недели (1 — понедельник, 7 — воскресенье),
+-- дня без строки у дилера нет — в этот день он закрыт. Время — местное время дилерского центра.
+CREATE TABLE IF NOT EXISTS dealer_hours (
+	dealer_id INTEGER NOT NULL REFERENCES dealers(id) ON DELETE CASCADE,
+	weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 1 AND 7),
+	opens TIME NOT NULL,
+	closes TIME NOT NULL,
+	PRIMARY KEY (dealer_id, weekday),
+	CHECK (opens < closes)
+);
+
+-- Расписание по умолчанию: будни 09:00–19:00, суббота 10:00–16:00
+CREATE OR REPLACE FUNCTION dealer_default_hours(dealer INTEGER) RETURNS void AS $$
+	INSERT INTO dealer_hours (dealer_id, weekday, opens, closes)
+	SELECT dealer, d, '09:00', '19:00' FROM generate_series(1, 5) AS d
+	UNION ALL
+	SELECT dealer, 6, '10:00', '16:00'
+	ON CONFLICT DO NOTHING;
+$$ LANGUAGE sql;
+
+SELECT dealer_default_hours(id) FROM dealers;
+
+-- Новый дилер сразу получает расписание по умолчанию, в том числе при импорте через COPY
+CREATE OR REPLACE FUNCTION dealers_default_hours() RETURNS trigger AS $$
+BEGIN
+	PERFORM dealer_default_hours(NEW.id);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS dealers_default_hours ON dealers;
+CREATE TRIGGER dealers_default_hours
+	AFTER INSERT ON dealers
+	FOR EACH ROW EXECUTE FUNCTION dealers_default_hours();
+
+-- Записи на тест-драйв. Одна машина не может быть записана на пересекающееся время:
+-- это гарантирует ограничение-исключение по диапазону slot (отменённые записи не учитываются).
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE IF NOT EXISTS bookings (
+	id SERIAL PRIMARY KEY,
+	car_id INTEGER NOT NULL REFERENCES cars(id) ON DELETE CASCADE,
+	dealer_id INTEGER NOT NULL REFERENCES dealers(id) ON DELETE CASCADE,
+	customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE RESTRICT,
+	slot TSTZRANGE NOT NULL CHECK (NOT isempty(slot) AND NOT lower_inf(slot) AND NOT upper_inf(slot)),
+	status VARCHAR(10) NOT NULL DEFAULT 'booked' CHECK (status IN ('booked', 'cancelled')),
+	note VARCHAR(500) NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	version INTEGER NOT NULL DEFAULT 1,
+	CONSTRAINT bookings_no_overlap EXCLUDE USING gist (car_id WITH =, slot WITH &&) WHERE (status = 'booked')
+);
+CREATE INDEX IF NOT EXISTS bookings_dealer_slot_idx ON bookings USING gist (dealer_id, slot) WHERE status = 'booked';
+CREATE INDEX IF NOT EXISTS bookings_customer_id_idx ON bookings (customer_id);
+
+DROP TRIGGER IF EXISTS bookings_bump_version ON bookings;
+CREATE TRIGGER bookings_bump_version
+	BEFORE UPDATE ON bookings
+	FOR EACH ROW EXECUTE FUNCTION bump_row_version();
+
+-- Записаться можно только на машину в наличии; бронь с истёкшим сроком считается снятой.
+-- Проверку делает приложение, триггер страхует от записей в обход API.
+CREATE OR REPLACE FUNCTION bookings_check_car_status() RETURNS trigger AS $$
+BEGIN
+	IF NEW.status = 'booked' AND NOT EXISTS (
+		SELECT 1 FROM cars
+		WHERE id = NEW.car_id
+			AND (status = 'available' OR (status = 'reserved' AND reserved_until <= now()))
+	) THEN
+		RAISE EXCEPTION 'машина % недоступна для тест-драйва', NEW.car_id
+			USING ERRCODE = 'check_violation';
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS bookings_check_car_status ON bookings;
+CREATE TRIGGER bookings_check_car_status
+	BEFORE INSERT OR UPDATE OF slot, car_id ON bookings
+	FOR EACH ROW EXECUTE FUNCTION bookings_check_car_status();
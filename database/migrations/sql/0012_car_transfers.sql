@@ -0,0 +1,91 @@
+-- Передача автомобиля между дилерами: requested → approved → completed,
+-- заявку можно отклонить (rejected) или отозвать (cancelled) до завершения.
+-- Строки не удаляются и образуют историю передач машины. Машина (марка, модель, год и VIN)
+-- и названия дилеров сохраняются в заявке: если машину или дилера удалят, ссылка на них
+-- обнулится, а история останется.
+CREATE TABLE IF NOT EXISTS car_transfers (
+	id SERIAL PRIMARY KEY,
+	car_id INTEGER REFERENCES cars(id) ON DELETE SET NULL,
+	car_title VARCHAR(250) NOT NULL,
+	car_vin VARCHAR(17),
+	from_dealer_id INTEGER REFERENCES dealers(id) ON DELETE SET NULL,
+	from_dealer_name VARCHAR(100) NOT NULL,
+	to_dealer_id INTEGER REFERENCES dealers(id) ON DELETE SET NULL,
+	to_dealer_name VARCHAR(100) NOT NULL,
+	status VARCHAR(10) NOT NULL DEFAULT 'requested'
+		CHECK (status IN ('requested', 'approved', 'rejected', 'cancelled', 'completed')),
+	note VARCHAR(500) NOT NULL DEFAULT '',
+	requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	decided_at TIMESTAMPTZ,
+	completed_at TIMESTAMPTZ,
+	version INTEGER NOT NULL DEFAULT 1,
+	CHECK (from_dealer_id <> to_dealer_id)
+);
+
+-- У машины не больше одной незавершённой заявки
+CREATE UNIQUE INDEX IF NOT EXISTS car_transfers_open_idx ON car_transfers (car_id)
+	WHERE status IN ('requested', 'approved');
+CREATE INDEX IF NOT EXISTS car_transfers_from_dealer_id_idx ON car_transfers (from_dealer_id);
+CREATE INDEX IF NOT EXISTS car_transfers_to_dealer_id_idx ON car_transfers (to_dealer_id);
+
+DROP TRIGGER IF EXISTS car_transfers_bump_version ON car_transfers;
+CREATE TRIGGER car_transfers_bump_version
+	BEFORE UPDATE ON car_transfers
+	FOR EACH ROW EXECUTE FUNCTION bump_row_version();
+
+-- Переходы проверяет приложение; триггер страхует от прямых UPDATE в обход API
+CREATE OR REPLACE FUNCTION car_transfers_check_status_transition() RETURNS trigger AS $$
+BEGIN
+	IF NEW.status = OLD.status
+		OR (OLD.status, NEW.status) IN (('requested', 'approved'), ('requested', 'rejected'), ('requested', 'cancelled'),
+			('approved', 'completed'), ('approved', 'cancelled')) THEN
+		RETURN NEW;
+	END IF;
+
+	RAISE EXCEPTION 'нельзя перевести передачу % из статуса % в %', OLD.id, OLD.status, NEW.status
+		USING ERRCODE = 'check_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS car_transfers_status_transition ON car_transfers;
+CREATE TRIGGER car_transfers_status_transition
+	BEFORE UPDATE OF status ON car_transfers
+	FOR EACH ROW EXECUTE FUNCTION car_transfers_check_status_transition();
+
+-- Машину или дилера удалили: внешний ключ обнуляет ссылку, а незавершённая заявка отменяется —
+-- передавать уже нечего или некому
+CREATE OR REPLACE FUNCTION car_transfers_cancel_orphaned() RETURNS trigger AS $$
+BEGIN
+	IF NEW.status IN ('requested', 'approved')
+		AND (NEW.car_id IS NULL OR NEW.from_dealer_id IS NULL OR NEW.to_dealer_id IS NULL) THEN
+		NEW.status := 'cancelled';
+		NEW.decided_at := now();
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS car_transfers_cancel_orphaned ON car_transfers;
+CREATE TRIGGER car_transfers_cancel_orphaned
+	BEFORE UPDATE OF car_id, from_dealer_id, to_dealer_id ON car_transfers
+	FOR EACH ROW EXECUTE FUNCTION car_transfers_cancel_orphaned();
+
+-- Машина переходит от одного дилера к другому только завершением передачи или переносом машин
+-- удаляемого дилера; такие транзакции включают app.dealer_move. Снять дилера тоже нельзя:
+-- иначе машину можно увести без заявки, сняв дилера и назначив другого. Назначить дилера
+-- можно только машине, у которой его ещё не было.
+CREATE OR REPLACE FUNCTION cars_check_dealer_move() RETURNS trigger AS $$
+BEGIN
+	IF OLD.dealer_id IS NOT NULL AND NEW.dealer_id IS DISTINCT FROM OLD.dealer_id
+		AND current_setting('app.dealer_move', true) IS DISTINCT FROM 'on' THEN
+		RAISE EXCEPTION 'машину % можно передать другому дилеру только через заявку на передачу', OLD.id
+			USING ERRCODE = 'check_violation';
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS cars_dealer_move ON cars;
+CREATE TRIGGER cars_dealer_move
+	BEFORE UPDATE OF dealer_id ON cars
+	FOR EACH ROW EXECUTE FUNCTION cars_check_dealer_move();
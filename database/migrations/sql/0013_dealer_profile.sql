@@ -0,0 +1,41 @@
+-- Контакты и координаты дилера. Пустая строка в контактах означает «не указано»;
+-- координаты указываются обе или ни одной. Телефон хранится в формате E.164.
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS phone VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS email VARCHAR(254) NOT NULL DEFAULT '';
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS website VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+ALTER TABLE dealers DROP CONSTRAINT IF EXISTS dealers_phone_check;
+ALTER TABLE dealers ADD CONSTRAINT dealers_phone_check
+	CHECK (phone = '' OR phone ~ '^\+[1-9][0-9]{1,14}$');
+
+ALTER TABLE dealers DROP CONSTRAINT IF EXISTS dealers_location_check;
+ALTER TABLE dealers ADD CONSTRAINT dealers_location_check
+	CHECK ((latitude IS NULL) = (longitude IS NULL)
+		AND latitude BETWEEN -90 AND 90 AND longitude BETWEEN -180 AND 180);
+
+-- Поиск ближайших дилеров сначала отбирает строки по ограничивающему прямоугольнику
+CREATE INDEX IF NOT EXISTS dealers_location_idx ON dealers (latitude, longitude) WHERE latitude IS NOT NULL;
+
+-- Расстояние по дуге большого круга в километрах (формула гаверсинусов, радиус Земли 6371 км).
+-- LEAST защищает asin от значений чуть больше 1 из-за погрешности округления.
+CREATE OR REPLACE FUNCTION haversine_km(lat1 DOUBLE PRECISION, lon1 DOUBLE PRECISION,
+	lat2 DOUBLE PRECISION, lon2 DOUBLE PRECISION) RETURNS DOUBLE PRECISION AS $$
+	SELECT 2 * 6371 * asin(LEAST(1, sqrt(
+		power(sin(radians(lat2 - lat1) / 2), 2)
+		+ cos(radians(lat1)) * cos(radians(lat2)) * power(sin(radians(lon2 - lon1) / 2), 2)
+	)));
+$$ LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE;
+
+-- Исключения из расписания: в дату day дилер работает в другие часы или закрыт (opens и closes — NULL)
+CREATE TABLE IF NOT EXISTS dealer_hours_exceptions (
+	dealer_id INTEGER NOT NULL REFERENCES dealers(id) ON DELETE CASCADE,
+	day DATE NOT NULL,
+	opens TIME,
+	closes TIME,
+	note VARCHAR(200) NOT NULL DEFAULT '',
+	PRIMARY KEY (dealer_id, day),
+	CHECK ((opens IS NULL) = (closes IS NULL)),
+	CHECK (opens < closes)
+);
@@ -0,0 +1,49 @@
+-- Отзывы покупателей о дилерах: оценка 1–5 и текст. Новый отзыв ждёт модерации (pending);
+-- в рейтинг дилера входят только одобренные (approved). Модератор может передумать:
+-- одобренный отзыв можно отклонить и наоборот.
+CREATE TABLE IF NOT EXISTS reviews (
+	id SERIAL PRIMARY KEY,
+	dealer_id INTEGER NOT NULL REFERENCES dealers(id) ON DELETE CASCADE,
+	customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE RESTRICT,
+	score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+	text VARCHAR(2000) NOT NULL,
+	status VARCHAR(10) NOT NULL DEFAULT 'pending'
+		CHECK (status IN ('pending', 'approved', 'rejected')),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	moderated_at TIMESTAMPTZ,
+	version INTEGER NOT NULL DEFAULT 1
+);
+
+-- Покупатель оставляет дилеру один отзыв
+CREATE UNIQUE INDEX IF NOT EXISTS reviews_dealer_customer_key ON reviews (dealer_id, customer_id);
+CREATE INDEX IF NOT EXISTS reviews_dealer_status_created_at_idx ON reviews (dealer_id, status, created_at);
+CREATE INDEX IF NOT EXISTS reviews_customer_id_idx ON reviews (customer_id);
+
+DROP TRIGGER IF EXISTS reviews_bump_version ON reviews;
+CREATE TRIGGER reviews_bump_version
+	BEFORE UPDATE ON reviews
+	FOR EACH ROW EXECUTE FUNCTION bump_row_version();
+
+-- Переходы проверяет приложение; триггер страхует от прямых UPDATE в обход API
+CREATE OR REPLACE FUNCTION reviews_check_status_transition() RETURNS trigger AS $$
+BEGIN
+	IF NEW.status = OLD.status
+		OR (OLD.status, NEW.status) IN (('pending', 'approved'), ('pending', 'rejected'),
+			('approved', 'rejected'), ('rejected', 'approved')) THEN
+		RETURN NEW;
+	END IF;
+
+	RAISE EXCEPTION 'нельзя перевести отзыв % из статуса % в %', OLD.id, OLD.status, NEW.status
+		USING ERRCODE = 'check_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS reviews_status_transition ON reviews;
+CREATE TRIGGER reviews_status_transition
+	BEFORE UPDATE OF status ON reviews
+	FOR EACH ROW EXECUTE FUNCTION reviews_check_status_transition();
+
+
+-- Рейтинг дилеров раньше задавали вручную. Он не сбрасывается: при первой модерации отзыва
+-- текущий рейтинг сохраняется в rating_prior и дальше служит априорным средним (см. updateDealerRating)
+ALTER TABLE dealers ADD COLUMN IF NOT EXISTS rating_prior DECIMAL(3,1);
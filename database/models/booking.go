@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Booking — запись покупателя на тест-драйв. Время — полуинтервал [Start, End).
+// Дилер берётся у машины в момент записи.
+type Booking struct {
+	ID         int       `json:"id"`
+	CarID      int       `json:"car_id"`
+	DealerID   int       `json:"dealer_id"`
+	CustomerID int       `json:"customer_id"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Status     string    `json:"status"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Статус записи на тест-драйв
+const (
+	BookingBooked    = "booked"
+	BookingCancelled = "cancelled"
+)
+
+// DealerHours — часы работы дилера в один день недели (1 — понедельник, 7 — воскресенье).
+// Opens и Closes — местное время в формате ЧЧ:ММ.
+type DealerHours struct {
+	Weekday int    `json:"weekday"`
+	Opens   string `json:"opens"`
+	Closes  string `json:"closes"`
+}
+
+// HoursException — часы работы в конкретный день вместо обычных: праздник, санитарный или сокращённый день.
+// Date — в формате ГГГГ-ММ-ДД; пустые Opens и Closes означают, что дилер в этот день закрыт.
+type HoursException struct {
+	Date   string `json:"date"`
+	Opens  string `json:"opens,omitempty"`
+	Closes string `json:"closes,omitempty"`
+	Note   string `json:"note"`
+}
+
+// OpeningHours — часы работы дилера: расписание по дням недели и исключения из него
+type OpeningHours struct {
+	Weekly     []DealerHours    `json:"weekly"`
+	Exceptions []HoursException `json:"exceptions"`
+}
@@ -1,12 +1,58 @@
 package models
 
+import "time"
+
+// Car — автомобиль на складе. DealerID равен nil, если машина ещё не закреплена за дилером.
+// VIN равен nil, если номер неизвестен; перечислимые поля (Condition, BodyType, FuelType,
+// Transmission, Drivetrain) хранятся в нижнем регистре, пустая строка означает «не указано».
+// Status и ReservedUntil меняются только через переходы статуса (reserve, release, sell).
 type Car struct {
-	ID       int    `json:"id"`
-	Firm     string `json:"firm"`
-	Model    string `json:"model"`
-	Year     int    `json:"year"`
-	Power    int    `json:"power"`
-	Color    string `json:"color"`
-	Price    int    `json:"price"`
-	DealerID int    `json:"dealer_id"`
+	ID           int     `json:"id"`
+	Firm         string  `json:"firm"`
+	Model        string  `json:"model"`
+	Year         int     `json:"year"`
+	Power        int     `json:"power"`
+	Color        string  `json:"color"`
+	Price        int     `json:"price"`
+	DealerID     *int    `json:"dealer_id"`
+	VIN          *string `json:"vin"`
+	Mileage      int     `json:"mileage"`
+	Condition    string  `json:"condition"`
+	BodyType     string  `json:"body_type"`
+	FuelType     string  `json:"fuel_type"`
+	Transmission string  `json:"transmission"`
+	Drivetrain   string  `json:"drivetrain"`
+	Trim         string  `json:"trim"`
+
+	Status        string     `json:"status,omitempty"`
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+}
+
+// Состояние автомобиля
+const (
+	ConditionNew  = "new"
+	ConditionUsed = "used"
+)
+
+// Статус автомобиля на складе
+const (
+	StatusAvailable = "available"
+	StatusReserved  = "reserved"
+	StatusSold      = "sold"
+)
+
+// StatusTransitions — допустимые переходы статуса: из какого статуса в какие
+var StatusTransitions = map[string][]string{
+	StatusAvailable: {StatusReserved},
+	StatusReserved:  {StatusAvailable, StatusSold},
+}
+
+// CanTransition сообщает, можно ли перевести автомобиль из статуса from в статус to
+func CanTransition(from, to string) bool {
+	for _, allowed := range StatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,14 @@
+package models
+
+// Make — марка автомобиля из каталога
+type Make struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// CarModel — модель автомобиля из каталога; принадлежит одной марке
+type CarModel struct {
+	ID     int    `json:"id"`
+	MakeID int    `json:"make_id"`
+	Name   string `json:"name"`
+}
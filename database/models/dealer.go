@@ -1,10 +1,20 @@
 package models
 
+// Dealer — дилерский центр. Phone (E.164), Email и Website необязательны: пустая строка — «не указано».
+// Latitude и Longitude указываются вместе. Hours заполняется только в ответе на запрос одного дилера,
+// а меняется через /api/dealers/{id}/hours. Rating считается только по одобренным отзывам
+// (/api/dealers/{id}/reviews): клиент и импорт его не задают.
 type Dealer struct {
-	ID      int     `json:"id"`
-	Name    string  `json:"name"`
-	City    string  `json:"city"`
-	Address string  `json:"address"`
-	Area    string  `json:"area"`
-	Rating  float64 `json:"rating"`
+	ID        int           `json:"id"`
+	Name      string        `json:"name"`
+	City      string        `json:"city"`
+	Address   string        `json:"address"`
+	Area      string        `json:"area"`
+	Rating    float64       `json:"rating"`
+	Phone     string        `json:"phone"`
+	Email     string        `json:"email"`
+	Website   string        `json:"website"`
+	Latitude  *float64      `json:"latitude"`
+	Longitude *float64      `json:"longitude"`
+	Hours     *OpeningHours `json:"hours,omitempty"`
 }
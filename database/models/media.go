@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// CarMedia — фотография или документ автомобиля. Сам файл хранится во внешнем хранилище
+// по ключу StorageKey; Width и Height заданы только у изображений.
+type CarMedia struct {
+	ID           int       `json:"id"`
+	CarID        int       `json:"car_id"`
+	Kind         string    `json:"kind"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        *int      `json:"width"`
+	Height       *int      `json:"height"`
+	CreatedAt    time.Time `json:"created_at"`
+	URL          string    `json:"url"`
+	ThumbnailURL *string   `json:"thumbnail_url"`
+
+	StorageKey   string  `json:"-"`
+	ThumbnailKey *string `json:"-"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Review — отзыв покупателя о дилере: оценка от 1 до 5 и текст.
+// В рейтинг дилера входят только одобренные модератором отзывы.
+type Review struct {
+	ID          int        `json:"id"`
+	DealerID    int        `json:"dealer_id"`
+	CustomerID  int        `json:"customer_id"`
+	Score       int        `json:"score"`
+	Text        string     `json:"text"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModeratedAt *time.Time `json:"moderated_at"`
+}
+
+// Рейтинг дилера — байесовское среднее одобренных оценок: к ним добавляется ReviewPriorWeight
+// воображаемых оценок, равных рейтингу дилера до первой модерации отзыва. Новый дилер получает
+// рейтинг ReviewPriorMean; пока отзывов мало, рейтинг остаётся близок к исходному
+// и одна оценка не делает дилера лучшим или худшим.
+const (
+	ReviewPriorMean   = 3.0
+	ReviewPriorWeight = 5
+)
+
+// Статус модерации отзыва
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// ReviewTransitions — допустимые переходы статуса отзыва: из какого статуса в какие
+var ReviewTransitions = map[string][]string{
+	ReviewPending:  {ReviewApproved, ReviewRejected},
+	ReviewApproved: {ReviewRejected},
+	ReviewRejected: {ReviewApproved},
+}
+
+// CanReviewTransition сообщает, можно ли перевести отзыв из статуса from в статус to
+func CanReviewTransition(from, to string) bool {
+	for _, allowed := range ReviewTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
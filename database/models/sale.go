@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Customer — покупатель. Email и Phone необязательны, пустая строка означает «не указано».
+type Customer struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Phone     string    `json:"phone"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Sale — продажа автомобиля покупателю. Price — итоговая цена с учётом скидки Discount.
+// Если DealerID не указан при создании, берётся дилер автомобиля.
+type Sale struct {
+	ID            int       `json:"id"`
+	CarID         int       `json:"car_id"`
+	DealerID      int       `json:"dealer_id"`
+	CustomerID    int       `json:"customer_id"`
+	Price         int       `json:"price"`
+	Discount      int       `json:"discount"`
+	PaymentMethod string    `json:"payment_method"`
+	SoldAt        time.Time `json:"sold_at"`
+}
+
+// Способы оплаты
+const (
+	PaymentCash         = "cash"
+	PaymentCard         = "card"
+	PaymentBankTransfer = "bank_transfer"
+	PaymentCredit       = "credit"
+	PaymentLeasing      = "leasing"
+)
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Transfer — заявка на передачу автомобиля от одного дилера другому.
+// FromDealerID — дилер машины в момент заявки; сама машина переходит к ToDealerID только при завершении.
+// Машина (CarTitle — марка, модель и год; CarVIN) и названия дилеров запоминаются при создании заявки:
+// если машину или дилера удалят, их ID станет nil, а незавершённая заявка — отменённой.
+type Transfer struct {
+	ID             int        `json:"id"`
+	CarID          *int       `json:"car_id"`
+	CarTitle       string     `json:"car_title"`
+	CarVIN         *string    `json:"car_vin"`
+	FromDealerID   *int       `json:"from_dealer_id"`
+	FromDealerName string     `json:"from_dealer_name"`
+	ToDealerID     *int       `json:"to_dealer_id"`
+	ToDealerName   string     `json:"to_dealer_name"`
+	Status         string     `json:"status"`
+	Note           string     `json:"note"`
+	RequestedAt    time.Time  `json:"requested_at"`
+	DecidedAt      *time.Time `json:"decided_at"`
+	CompletedAt    *time.Time `json:"completed_at"`
+}
+
+// Статус передачи
+const (
+	TransferRequested = "requested"
+	TransferApproved  = "approved"
+	TransferRejected  = "rejected"
+	TransferCancelled = "cancelled"
+	TransferCompleted = "completed"
+)
+
+// TransferTransitions — допустимые переходы статуса передачи: из какого статуса в какие
+var TransferTransitions = map[string][]string{
+	TransferRequested: {TransferApproved, TransferRejected, TransferCancelled},
+	TransferApproved:  {TransferCompleted, TransferCancelled},
+}
+
+// CanTransferTransition сообщает, можно ли перевести передачу из статуса from в статус to
+func CanTransferTransition(from, to string) bool {
+	for _, allowed := range TransferTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
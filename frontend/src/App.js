@@ -5,6 +5,15 @@ import CarList from './components/CarList';
 import CarForm from './components/CarForm';
 import DealerList from './components/DealerList';
 
+// Собирает текст ошибки из ответа API (problem+json): ошибки по полям или detail
+const describeError = (err, fallback) => {
+  const data = err.response?.data;
+  if (data?.errors?.length) {
+    return data.errors.map((e) => `${e.field}: ${e.message}`).join('; ');
+  }
+  return data?.detail || fallback;
+};
+
 function App() {
   const [activeTab, setActiveTab] = useState('cars');
   const [cars, setCars] = useState([]);
@@ -105,7 +114,7 @@ function App() {
       setEditingCar(null);
       fetchCars();
     } catch (err) {
-      setError(err.response?.data?.message || 'Не удалось сохранить автомобиль');
+      setError(describeError(err, 'Не удалось сохранить автомобиль'));
     } finally {
       setLoading(false);
     }
@@ -130,7 +139,7 @@ function App() {
       setEditingDealer(null);
       fetchDealers();
     } catch (err) {
-      setError(err.response?.data?.message || 'Не удалось сохранить дилера');
+      setError(describeError(err, 'Не удалось сохранить дилера'));
     } finally {
       setLoading(false);
     }
@@ -154,6 +163,22 @@ function App() {
     }
   };
 
+  // Забронировать, снять бронь или продать автомобиль
+  const changeCarStatus = async (id, action) => {
+    setLoading(true);
+    setError('');
+
+    try {
+      await carApi[action](id);
+      setSuccess('Статус автомобиля изменён!');
+      fetchCars();
+    } catch (err) {
+      setError(describeError(err, 'Не удалось изменить статус автомобиля'));
+    } finally {
+      setLoading(false);
+    }
+  };
+
   // Удалить дилера
   const deleteDealer = async (id) => {
     if (!window.confirm('Вы уверены, что хотите удалить этого дилера?')) return;
@@ -166,7 +191,22 @@ function App() {
       setSuccess('Дилер успешно удален!');
       fetchDealers();
     } catch (err) {
-      setError('Не удалось удалить дилера');
+      // У дилера остались автомобили — удаляем их только с явного согласия
+      if (err.response?.status === 409) {
+        const count = err.response.data?.cars_count;
+        if (window.confirm(`У дилера ${count} автомобилей. Удалить дилера вместе с ними?`)) {
+          try {
+            await dealerApi.delete(id, { cascade: true });
+            setSuccess('Дилер и его автомобили удалены!');
+            fetchDealers();
+            fetchCars();
+          } catch (cascadeErr) {
+            setError('Не удалось удалить дилера');
+          }
+        }
+      } else {
+        setError('Не удалось удалить дилера');
+      }
     } finally {
       setLoading(false);
     }
@@ -424,6 +464,7 @@ function App() {
               cars={cars}
               onEdit={editCar}
               onDelete={deleteCar}
+              onStatusChange={changeCarStatus}
             />
           )}
         </>
@@ -1,6 +1,39 @@
 import React, { useState, useEffect } from 'react';
+import { catalogApi } from '../services/api';
 import '../styles/App.css';
 
+// Цвета, которые принимает сервер (см. validation.Palette)
+const COLORS = [
+  'White', 'Black', 'Silver', 'Gray', 'Red', 'Blue', 'Green',
+  'Yellow', 'Orange', 'Brown', 'Beige', 'Purple', 'Gold',
+];
+
+// Значения перечислимых полей (см. validation.BodyTypes и соседние списки)
+const CONDITIONS = [['new', 'Новый'], ['used', 'С пробегом']];
+const BODY_TYPES = [
+  ['sedan', 'Седан'], ['hatchback', 'Хэтчбек'], ['liftback', 'Лифтбек'], ['wagon', 'Универсал'],
+  ['coupe', 'Купе'], ['convertible', 'Кабриолет'], ['suv', 'Внедорожник'], ['crossover', 'Кроссовер'],
+  ['minivan', 'Минивэн'], ['pickup', 'Пикап'], ['van', 'Фургон'],
+];
+const FUEL_TYPES = [
+  ['petrol', 'Бензин'], ['diesel', 'Дизель'], ['hybrid', 'Гибрид'], ['plug_in_hybrid', 'Подключаемый гибрид'],
+  ['electric', 'Электро'], ['lpg', 'Газ (пропан)'], ['cng', 'Газ (метан)'],
+];
+const TRANSMISSIONS = [['manual', 'Механика'], ['automatic', 'Автомат'], ['robot', 'Робот'], ['cvt', 'Вариатор']];
+const DRIVETRAINS = [['fwd', 'Передний'], ['rwd', 'Задний'], ['awd', 'Полный (AWD)'], ['4wd', 'Полный (4WD)']];
+
+const SelectField = ({ label, name, value, options, onChange, emptyLabel = 'Не указано' }) => (
+  <div className="form-group">
+    <label className="form-label">{label}</label>
+    <select name={name} value={value} onChange={onChange} className="form-input">
+      <option value="">{emptyLabel}</option>
+      {options.map(([optionValue, title]) => (
+        <option key={optionValue} value={optionValue}>{title}</option>
+      ))}
+    </select>
+  </div>
+);
+
 const CarForm = ({ car, onSubmit, onCancel }) => {
   const [formData, setFormData] = useState({
     firm: '',
@@ -10,8 +43,45 @@ const CarForm = ({ car, onSubmit, onCancel }) => {
     color: '',
     price: '',
     dealer_id: '',
+    vin: '',
+    mileage: '',
+    condition: '',
+    body_type: '',
+    fuel_type: '',
+    transmission: '',
+    drivetrain: '',
+    trim: '',
   });
 
+  const [makes, setMakes] = useState([]);
+  const [models, setModels] = useState([]);
+
+  // Марки и модели выбираются из каталога: сервер принимает только их
+  useEffect(() => {
+    catalogApi.getMakes()
+      .then(response => setMakes(response.data))
+      .catch(() => setMakes([]));
+  }, []);
+
+  const selectedMake = makes.find(make => make.name.toLowerCase() === formData.firm.trim().toLowerCase());
+  const selectedMakeId = selectedMake ? selectedMake.id : null;
+
+  useEffect(() => {
+    if (!selectedMakeId) {
+      setModels([]);
+      return;
+    }
+    catalogApi.getModels(selectedMakeId)
+      .then(response => setModels(response.data))
+      .catch(() => setModels([]));
+  }, [selectedMakeId]);
+
+  // Значение, которого нет в каталоге (например, у старой машины), остаётся в списке, чтобы не потеряться
+  const withCurrent = (names, current) =>
+    current && !names.some(name => name.toLowerCase() === current.toLowerCase()) ? [current, ...names] : names;
+  const makeOptions = withCurrent(makes.map(make => make.name), formData.firm).map(name => [name, name]);
+  const modelOptions = withCurrent(models.map(model => model.name), formData.model).map(name => [name, name]);
+
   useEffect(() => {
     if (car) {
       setFormData({
@@ -22,6 +92,14 @@ const CarForm = ({ car, onSubmit, onCancel }) => {
         color: car.color || '',
         price: car.price || '',
         dealer_id: car.dealer_id || '',
+        vin: car.vin || '',
+        mileage: car.mileage || '',
+        condition: car.condition || '',
+        body_type: car.body_type || '',
+        fuel_type: car.fuel_type || '',
+        transmission: car.transmission || '',
+        drivetrain: car.drivetrain || '',
+        trim: car.trim || '',
       });
     }
   }, [car]);
@@ -34,6 +112,12 @@ const CarForm = ({ car, onSubmit, onCancel }) => {
     }));
   };
 
+  // При смене марки выбранная модель сбрасывается
+  const handleMakeChange = (e) => {
+    const { value } = e.target;
+    setFormData(prev => ({ ...prev, firm: value, model: '' }));
+  };
+
   const handleSubmit = (e) => {
     e.preventDefault();
     
@@ -42,7 +126,9 @@ const CarForm = ({ car, onSubmit, onCancel }) => {
       year: parseInt(formData.year),
       power: parseInt(formData.power),
       price: parseInt(formData.price),
-      dealer_id: parseInt(formData.dealer_id),
+      dealer_id: formData.dealer_id ? parseInt(formData.dealer_id) : null,
+      vin: formData.vin.trim() || null,
+      mileage: formData.mileage ? parseInt(formData.mileage) : 0,
     };
     
     onSubmit(submitData);
@@ -60,28 +146,29 @@ const CarForm = ({ car, onSubmit, onCancel }) => {
         <form onSubmit={handleSubmit}>
           <div className="form-group">
             <label className="form-label">Марка *</label>
-            <input
-              type="text"
-              name="firm"
-              value={formData.firm}
-              onChange={handleChange}
-              className="form-input"
-              placeholder="Например: Toyota"
-              required
-            />
+            <select name="firm" value={formData.firm} onChange={handleMakeChange} className="form-input" required>
+              <option value="">Выберите марку</option>
+              {makeOptions.map(([value, title]) => (
+                <option key={value} value={value}>{title}</option>
+              ))}
+            </select>
           </div>
 
           <div className="form-group">
             <label className="form-label">Модель *</label>
-            <input
-              type="text"
+            <select
               name="model"
               value={formData.model}
               onChange={handleChange}
               className="form-input"
-              placeholder="Например: Camry"
+              disabled={!formData.firm}
               required
-            />
+            >
+              <option value="">{formData.firm ? 'Выберите модель' : 'Сначала выберите марку'}</option>
+              {modelOptions.map(([value, title]) => (
+                <option key={value} value={value}>{title}</option>
+              ))}
+            </select>
           </div>
 
           <div className="form-group">
@@ -92,8 +179,8 @@ const CarForm = ({ car, onSubmit, onCancel }) => {
               value={formData.year}
               onChange={handleChange}
               className="form-input"
-              min="1900"
-              max="2024"
+              min="1886"
+              max={new Date().getFullYear() + 1}
               placeholder="Например: 2023"
               required
             />
@@ -122,8 +209,14 @@ const CarForm = ({ car, onSubmit, onCancel }) => {
               onChange={handleChange}
               className="form-input"
               placeholder="Например: Красный"
+              list="car-colors"
               required
             />
+            <datalist id="car-colors">
+              {COLORS.map((color) => (
+                <option key={color} value={color} />
+              ))}
+            </datalist>
           </div>
 
           <div className="form-group">
@@ -141,7 +234,7 @@ const CarForm = ({ car, onSubmit, onCancel }) => {
           </div>
 
           <div className="form-group">
-            <label className="form-label">ID дилера *</label>
+            <label className="form-label">ID дилера</label>
             <input
               type="number"
               name="dealer_id"
@@ -149,8 +242,61 @@ const CarForm = ({ car, onSubmit, onCancel }) => {
               onChange={handleChange}
               className="form-input"
               min="1"
-              placeholder="Например: 1"
-              required
+              placeholder="Пусто — без дилера"
+              // Дилер машины меняется только через передачу (/api/transfers)
+              readOnly={isEditMode && !!car.dealer_id}
+            />
+          </div>
+
+          <div className="form-group">
+            <label className="form-label">VIN</label>
+            <input
+              type="text"
+              name="vin"
+              value={formData.vin}
+              onChange={handleChange}
+              className="form-input"
+              maxLength="17"
+              placeholder="17 символов, без I, O и Q"
+            />
+          </div>
+
+          <div className="form-group">
+            <label className="form-label">Пробег (км)</label>
+            <input
+              type="number"
+              name="mileage"
+              value={formData.mileage}
+              onChange={handleChange}
+              className="form-input"
+              min="0"
+              placeholder="0 — новый автомобиль"
+            />
+          </div>
+
+          <SelectField
+            label="Состояние"
+            name="condition"
+            value={formData.condition}
+            options={CONDITIONS}
+            onChange={handleChange}
+            emptyLabel="По пробегу"
+          />
+          <SelectField label="Кузов" name="body_type" value={formData.body_type} options={BODY_TYPES} onChange={handleChange} />
+          <SelectField label="Топливо" name="fuel_type" value={formData.fuel_type} options={FUEL_TYPES} onChange={handleChange} />
+          <SelectField label="Коробка передач" name="transmission" value={formData.transmission} options={TRANSMISSIONS} onChange={handleChange} />
+          <SelectField label="Привод" name="drivetrain" value={formData.drivetrain} options={DRIVETRAINS} onChange={handleChange} />
+
+          <div className="form-group">
+            <label className="form-label">Комплектация</label>
+            <input
+              type="text"
+              name="trim"
+              value={formData.trim}
+              onChange={handleChange}
+              className="form-input"
+              maxLength="100"
+              placeholder="Например: Prestige"
             />
           </div>
 
@@ -1,7 +1,23 @@
 import React from 'react';
 import '../styles/App.css';
 
-const CarList = ({ cars, onEdit, onDelete }) => {
+// Подписи статусов и переходы, доступные из каждого статуса
+const statusLabels = {
+  available: 'В наличии',
+  reserved: 'Забронирован',
+  sold: 'Продан',
+};
+
+const statusActions = {
+  available: [{ action: 'reserve', label: 'Забронировать' }],
+  reserved: [
+    { action: 'release', label: 'Снять бронь' },
+    { action: 'sell', label: 'Продать' },
+  ],
+  sold: [],
+};
+
+const CarList = ({ cars, onEdit, onDelete, onStatusChange }) => {
   if (!cars || cars.length === 0) {
     return (
       <div className="empty-state">
@@ -42,11 +58,27 @@ const CarList = ({ cars, onEdit, onDelete }) => {
             </div>
             <div className="detail-row">
               <span className="detail-label">ID дилера:</span>
-              <span className="detail-value">{car.dealer_id}</span>
+              <span className="detail-value">{car.dealer_id ?? 'Без дилера'}</span>
+            </div>
+            <div className="detail-row">
+              <span className="detail-label">Статус:</span>
+              <span className="detail-value">
+                {statusLabels[car.status] ?? car.status}
+                {car.reserved_until && ` до ${new Date(car.reserved_until).toLocaleString('ru-RU')}`}
+              </span>
             </div>
           </div>
           
           <div className="card-actions">
+            {onStatusChange && (statusActions[car.status] ?? []).map(({ action, label }) => (
+              <button
+                key={action}
+                onClick={() => onStatusChange(car.id, action)}
+                className="btn btn-secondary"
+              >
+                {label}
+              </button>
+            ))}
             <button 
               onClick={() => onEdit(car.id)} 
               className="btn btn-warning"
@@ -7,7 +7,11 @@ const DealerForm = ({ dealer, onSubmit, onCancel }) => {
     city: '',
     address: '',
     area: '',
-    rating: '',
+    phone: '',
+    email: '',
+    website: '',
+    latitude: '',
+    longitude: '',
   });
 
   useEffect(() => {
@@ -17,7 +21,11 @@ const DealerForm = ({ dealer, onSubmit, onCancel }) => {
         city: dealer.city || '',
         address: dealer.address || '',
         area: dealer.area || '',
-        rating: dealer.rating || '',
+        phone: dealer.phone || '',
+        email: dealer.email || '',
+        website: dealer.website || '',
+        latitude: dealer.latitude ?? '',
+        longitude: dealer.longitude ?? '',
       });
     }
   }, [dealer]);
@@ -35,7 +43,9 @@ const DealerForm = ({ dealer, onSubmit, onCancel }) => {
     
     const submitData = {
       ...formData,
-      rating: parseFloat(formData.rating),
+      // Координаты необязательны: пустое поле — null
+      latitude: formData.latitude === '' ? null : parseFloat(formData.latitude),
+      longitude: formData.longitude === '' ? null : parseFloat(formData.longitude),
     };
     
     onSubmit(submitData);
@@ -104,18 +114,64 @@ const DealerForm = ({ dealer, onSubmit, onCancel }) => {
           </div>
 
           <div className="form-group">
-            <label className="form-label required">Рейтинг (0-5)</label>
+            <label className="form-label">Телефон</label>
+            <input
+              type="tel"
+              name="phone"
+              value={formData.phone}
+              onChange={handleChange}
+              className="form-input"
+              placeholder="В формате E.164, например: +74951234567"
+            />
+          </div>
+
+          <div className="form-group">
+            <label className="form-label">Email</label>
+            <input
+              type="email"
+              name="email"
+              value={formData.email}
+              onChange={handleChange}
+              className="form-input"
+              placeholder="Например: info@premium-auto.ru"
+            />
+          </div>
+
+          <div className="form-group">
+            <label className="form-label">Сайт</label>
+            <input
+              type="url"
+              name="website"
+              value={formData.website}
+              onChange={handleChange}
+              className="form-input"
+              placeholder="Например: https://premium-auto.ru"
+            />
+          </div>
+
+          <div className="form-group">
+            <label className="form-label">Широта и долгота (если не указаны — определяются по адресу)</label>
             <input
               type="number"
-              name="rating"
-              value={formData.rating}
+              name="latitude"
+              value={formData.latitude}
               onChange={handleChange}
               className="form-input"
-              min="0"
-              max="5"
-              step="0.1"
-              placeholder="Например: 4.5"
-              required
+              min="-90"
+              max="90"
+              step="any"
+              placeholder="Широта, например: 55.7558"
+            />
+            <input
+              type="number"
+              name="longitude"
+              value={formData.longitude}
+              onChange={handleChange}
+              className="form-input"
+              min="-180"
+              max="180"
+              step="any"
+              placeholder="Долгота, например: 37.6173"
             />
           </div>
 
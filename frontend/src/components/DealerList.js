@@ -40,6 +40,24 @@ const DealerList = ({ dealers, onEdit, onDelete }) => {
                 {dealer.rating}/5
               </span>
             </div>
+            {dealer.phone && (
+              <div className="detail-row">
+                <span className="detail-label">Телефон:</span>
+                <span className="detail-value">{dealer.phone}</span>
+              </div>
+            )}
+            {dealer.email && (
+              <div className="detail-row">
+                <span className="detail-label">Email:</span>
+                <span className="detail-value">{dealer.email}</span>
+              </div>
+            )}
+            {dealer.website && (
+              <div className="detail-row">
+                <span className="detail-label">Сайт:</span>
+                <span className="detail-value">{dealer.website}</span>
+              </div>
+            )}
           </div>
           
           <div className="card-actions">
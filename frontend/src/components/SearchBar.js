@@ -1,8 +1,49 @@
 import React from 'react';
+import { searchApi } from '../services/api';
 import '../styles/App.css';
 
-const SearchBar = ({ onSearch, onClear, placeholder = "Введите ID..." }) => {
+const escapeHtml = (text) => String(text)
+  .replace(/&/g, '&amp;')
+  .replace(/</g, '&lt;')
+  .replace(/>/g, '&gt;')
+  .replace(/"/g, '&quot;');
+
+// Поле с подсветкой из ответа сервера или экранированное значение, если совпадений в нём нет
+const marked = (result, record, field) => result.highlights[field] || escapeHtml(record[field]);
+
+// Если передан onSelect, под полем показываются подсказки из /api/search:
+// поиск идёт на сервере и находит записи с опечатками и в транслитерации.
+const SearchBar = ({ onSearch, onClear, onSelect, searchType, placeholder = "Введите ID..." }) => {
   const [searchId, setSearchId] = React.useState('');
+  const [results, setResults] = React.useState([]);
+
+  React.useEffect(() => {
+    const q = searchId.trim();
+    if (!onSelect || q.length < 2) {
+      setResults([]);
+      return undefined;
+    }
+
+    let cancelled = false;
+    const timer = setTimeout(() => {
+      searchApi.search(q, { type: searchType, limit: 10 })
+        .then((response) => {
+          if (!cancelled) {
+            setResults(response.data.results);
+          }
+        })
+        .catch(() => {
+          if (!cancelled) {
+            setResults([]);
+          }
+        });
+    }, 250);
+
+    return () => {
+      cancelled = true;
+      clearTimeout(timer);
+    };
+  }, [searchId, onSelect, searchType]);
 
   const handleSearch = () => {
     if (searchId.trim()) {
@@ -18,9 +59,15 @@ const SearchBar = ({ onSearch, onClear, placeholder = "Введите ID..." })
 
   const handleClear = () => {
     setSearchId('');
+    setResults([]);
     onClear();
   };
 
+  const handleSelect = (result) => {
+    setResults([]);
+    onSelect(result);
+  };
+
   return (
     <div className="search-section">
       <input
@@ -39,8 +86,25 @@ const SearchBar = ({ onSearch, onClear, placeholder = "Введите ID..." })
           Очистить
         </button>
       </div>
+      {results.length > 0 && (
+        <ul className="search-results">
+          {results.map((result) => (
+            <li key={`${result.type}-${result.id}`} onClick={() => handleSelect(result)}>
+              {/* Сервер экранирует значения и добавляет только теги <mark> */}
+              <strong
+                dangerouslySetInnerHTML={{
+                  __html: result.type === 'car'
+                    ? `${marked(result, result.car, 'firm')} ${marked(result, result.car, 'model')}`
+                    : marked(result, result.dealer, 'name'),
+                }}
+              />
+              <span> — {result.subtitle}</span>
+            </li>
+          ))}
+        </ul>
+      )}
     </div>
   );
 };
 
-export default SearchBar;
\ No newline at end of file
+export default SearchBar;
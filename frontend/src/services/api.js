@@ -9,22 +9,150 @@ const api = axios.create({
   },
 });
 
+// Ключ идемпотентности: повтор того же POST не создаст дубликат
+const idempotent = () => ({
+  headers: { 'Idempotency-Key': crypto.randomUUID() },
+});
+
 // Cars API
 export const carApi = {
-  getAll: () => api.get('/cars'),
+  getAll: (filters) => api.get('/cars', { params: filters }),
+  getUnassigned: () => api.get('/cars/unassigned'),
   getById: (id) => api.get(`/cars/${id}`),
-  create: (carData) => api.post('/cars', carData),
+  getByVin: (vin) => api.get(`/cars/by-vin/${encodeURIComponent(vin)}`),
+  create: (carData) => api.post('/cars', carData, idempotent()),
   update: (id, carData) => api.put(`/cars/${id}`, carData),
+  patch: (id, changes) => api.patch(`/cars/${id}`, changes, {
+    headers: { 'Content-Type': 'application/merge-patch+json' },
+  }),
   delete: (id) => api.delete(`/cars/${id}`),
+  // Смена статуса: бронь на minutes минут (по умолчанию сутки), снятие брони, продажа
+  reserve: (id, minutes) => api.post(`/cars/${id}/reserve`, minutes ? { minutes } : {}, idempotent()),
+  release: (id) => api.post(`/cars/${id}/release`, {}, idempotent()),
+  sell: (id) => api.post(`/cars/${id}/sell`, {}, idempotent()),
+  // Ссылка на выгрузку: format = csv | xlsx | json, filters — как у getAll
+  exportUrl: (format, filters) =>
+    `${API_BASE_URL}/cars/export?${new URLSearchParams({ ...filters, format })}`,
+  import: (file, { dryRun = false, strict = false, mapping } = {}) => {
+    const form = new FormData();
+    form.append('file', file);
+    form.append('dry_run', dryRun);
+    form.append('strict', strict);
+    if (mapping) {
+      form.append('mapping', JSON.stringify(mapping));
+    }
+    return api.post('/import/cars', form, {
+      headers: { 'Content-Type': 'multipart/form-data', ...idempotent().headers },
+    });
+  },
 };
 
 // Dealers API
 export const dealerApi = {
   getAll: () => api.get('/dealers'),
   getById: (id) => api.get(`/dealers/${id}`),
-  create: (dealerData) => api.post('/dealers', dealerData),
+  create: (dealerData) => api.post('/dealers', dealerData, idempotent()),
   update: (id, dealerData) => api.put(`/dealers/${id}`, dealerData),
-  delete: (id) => api.delete(`/dealers/${id}`),
+  patch: (id, changes) => api.patch(`/dealers/${id}`, changes, {
+    headers: { 'Content-Type': 'application/merge-patch+json' },
+  }),
+  delete: (id, params) => api.delete(`/dealers/${id}`, { params }),
+  // Дилеры в радиусе radiusKm от точки, от ближних к дальним
+  getNearby: (lat, lon, radiusKm) => api.get('/dealers/nearby', { params: { lat, lon, radius_km: radiusKm } }),
+};
+
+// Search API: q — строка запроса, params — { type: 'car' | 'dealer', limit }
+export const searchApi = {
+  search: (q, params) => api.get('/search', { params: { q, ...params } }),
+};
+
+// Catalog API: справочник марок и моделей
+export const catalogApi = {
+  getMakes: () => api.get('/makes'),
+  createMake: (makeData) => api.post('/makes', makeData),
+  updateMake: (id, makeData) => api.put(`/makes/${id}`, makeData),
+  deleteMake: (id) => api.delete(`/makes/${id}`),
+  getModels: (makeId) => api.get('/models', { params: makeId ? { make_id: makeId } : {} }),
+  createModel: (modelData) => api.post('/models', modelData),
+  updateModel: (id, modelData) => api.put(`/models/${id}`, modelData),
+  deleteModel: (id) => api.delete(`/models/${id}`),
+  getConflicts: () => api.get('/catalog/conflicts'),
+};
+
+// Customers API: q — поиск по имени, email или телефону
+export const customerApi = {
+  getAll: (q) => api.get('/customers', { params: q ? { q } : {} }),
+  getById: (id) => api.get(`/customers/${id}`),
+  create: (customerData) => api.post('/customers', customerData, idempotent()),
+  update: (id, customerData) => api.put(`/customers/${id}`, customerData),
+  delete: (id) => api.delete(`/customers/${id}`),
+};
+
+// Sales API: filters — { dealer_id, customer_id, from, to }, даты в формате ГГГГ-ММ-ДД
+export const saleApi = {
+  getAll: (filters) => api.get('/sales', { params: filters }),
+  getById: (id) => api.get(`/sales/${id}`),
+  create: (saleData) => api.post('/sales', saleData, idempotent()),
+  update: (id, saleData) => api.put(`/sales/${id}`, saleData),
+  delete: (id) => api.delete(`/sales/${id}`),
+  getDealerLedger: (dealerId, period) => api.get(`/dealers/${dealerId}/sales`, { params: period }),
+};
+
+// Bookings API: записи на тест-драйв; start и end — ISO 8601 с часовым поясом
+export const bookingApi = {
+  getAll: (filters) => api.get('/bookings', { params: filters }),
+  getById: (id) => api.get(`/bookings/${id}`),
+  create: (bookingData) => api.post('/bookings', bookingData, idempotent()),
+  cancel: (id) => api.post(`/bookings/${id}/cancel`),
+  reschedule: (id, start, end) => api.post(`/bookings/${id}/reschedule`, { start, end }),
+  // params — { date, days, duration, car_id }
+  getAvailability: (dealerId, params) => api.get(`/dealers/${dealerId}/availability`, { params }),
+  // hours — { weekly: [{ weekday, opens, closes }], exceptions: [{ date, opens, closes, note }] }
+  getHours: (dealerId) => api.get(`/dealers/${dealerId}/hours`),
+  updateHours: (dealerId, hours) => api.put(`/dealers/${dealerId}/hours`, hours),
+};
+
+// Transfers API: передача машин между дилерами; filters — { car_id, dealer_id, direction, status }
+export const transferApi = {
+  getAll: (filters) => api.get('/transfers', { params: filters }),
+  getById: (id) => api.get(`/transfers/${id}`),
+  create: (transferData) => api.post('/transfers', transferData, idempotent()),
+  // dealerId — действующий дилер: получатель для approve/reject, отправитель для cancel/complete
+  approve: (id, dealerId) => api.post(`/transfers/${id}/approve`, { dealer_id: dealerId }, idempotent()),
+  reject: (id, dealerId) => api.post(`/transfers/${id}/reject`, { dealer_id: dealerId }, idempotent()),
+  cancel: (id, dealerId) => api.post(`/transfers/${id}/cancel`, { dealer_id: dealerId }, idempotent()),
+  complete: (id, dealerId) => api.post(`/transfers/${id}/complete`, { dealer_id: dealerId }, idempotent()),
+  getForDealer: (dealerId, direction) => api.get(`/dealers/${dealerId}/transfers`, { params: direction ? { direction } : {} }),
+  getCarHistory: (carId) => api.get(`/cars/${carId}/transfers`),
+};
+
+// Review API: отзывы о дилерах; новый отзыв ждёт модерации, рейтинг дилера пересчитывается после неё
+export const reviewApi = {
+  getForDealer: (dealerId, page) => api.get(`/dealers/${dealerId}/reviews`, { params: page }),
+  create: (dealerId, reviewData) => api.post(`/dealers/${dealerId}/reviews`, reviewData, idempotent()),
+  getAll: (filters) => api.get('/reviews', { params: filters }),
+  getById: (id) => api.get(`/reviews/${id}`),
+  approve: (id) => api.post(`/reviews/${id}/approve`, {}, idempotent()),
+  reject: (id) => api.post(`/reviews/${id}/reject`, {}, idempotent()),
+  delete: (id) => api.delete(`/reviews/${id}`),
+};
+
+// Media API: фотографии и документы автомобиля; kind = photo | document (необязательно)
+export const mediaApi = {
+  getAll: (carId) => api.get(`/cars/${carId}/media`),
+  upload: (carId, files, kind) => {
+    const form = new FormData();
+    Array.from(files).forEach((file) => form.append('file', file));
+    if (kind) {
+      form.append('kind', kind);
+    }
+    return api.post(`/cars/${carId}/media`, form, {
+      headers: { 'Content-Type': 'multipart/form-data' },
+    });
+  },
+  delete: (carId, mediaId) => api.delete(`/cars/${carId}/media/${mediaId}`),
+  // url и thumbnail_url из ответа — пути от корня сервера
+  fileUrl: (path) => `${API_BASE_URL.replace(/\/api$/, '')}${path}`,
 };
 
 export default api;
\ No newline at end of file
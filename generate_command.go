@@ -0,0 +1,112 @@
+package main
+
+import (
+	"CarDealership/database/connection"
+	"CarDealership/database/generator"
+	"CarDealership/database/importer"
+	"CarDealership/database/loader"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runGenerateCommand создаёт синтетических дилеров и машины для нагрузочных проверок:
+//
+//	go run . generate --seed 42 --dealers 2000 --cars 1000000 --out big.ndjson
+//	go run . generate --seed 42 --dealers 2000 --cars 1000000 --import
+//
+// При одинаковых параметрах данные одинаковые. Файл пишется в формате загрузчика
+// (.json или .ndjson), --import загружает данные через импортёр одной транзакцией.
+// Возвращает код завершения процесса.
+func runGenerateCommand(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	seed := fs.Uint64("seed", 1, "зерно генератора")
+	dealers := fs.Int("dealers", 100, "количество дилеров")
+	cars := fs.Int("cars", 10000, "количество машин")
+	year := fs.Int("year", time.Now().Year(), "год, от которого считается возраст машин")
+	out := fs.String("out", "", "файл для записи (.json или .ndjson)")
+	doImport := fs.Bool("import", false, "загрузить данные в базу через импортёр")
+	dryRun := fs.Bool("dry-run", false, "вместе с --import: проверить импорт, не сохраняя изменения")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: generate [--seed N] [--dealers N] [--cars N] (--out файл | --import [--dry-run])")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*out == "") == !*doImport || *dealers < 0 || *cars < 0 || (*cars > 0 && *dealers == 0) {
+		fs.Usage()
+		return 2
+	}
+
+	gen := generator.New(generator.Options{Seed: *seed, Dealers: *dealers, Cars: *cars, Year: *year})
+	started := time.Now()
+
+	if *out != "" {
+		if err := writeGenerated(*out, gen); err != nil {
+			fmt.Fprintln(os.Stderr, "❌", err)
+			return 1
+		}
+		fmt.Printf("✅ %s: %d дилеров, %d машин за %s\n", *out, *dealers, *cars, time.Since(started).Round(time.Millisecond))
+		return 0
+	}
+
+	pool, err := connection.CreateConnectionPool(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Ошибка создания пула соединений:", err)
+		return 1
+	}
+	defer pool.Close()
+
+	if err := prepareSchema(ctx, pool); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Ошибка подготовки схемы:", err)
+		return 1
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Ошибка получения соединения:", err)
+		return 1
+	}
+	defer conn.Release()
+
+	report, err := importer.Upsert(ctx, conn.Conn(), gen, importer.Options{
+		DryRun: *dryRun,
+		Progress: func(stage string, processed int) {
+			fmt.Fprintf(os.Stderr, "… %s: обработано %d (%s)\n", stage, processed, time.Since(started).Round(time.Millisecond))
+		},
+	})
+	return printImportReport(report, err, *dryRun, started)
+}
+
+// writeGenerated пишет сгенерированные данные в файл; формат определяется по расширению
+func writeGenerated(path string, gen *generator.Generator) (err error) {
+	format, err := loader.DetectFormat(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	w, err := loader.NewWriter(f, format)
+	if err != nil {
+		return err
+	}
+	if err := gen.EachDealer(w.WriteDealer); err != nil {
+		return err
+	}
+	if err := gen.EachCar(w.WriteCar); err != nil {
+		return err
+	}
+	return w.Close()
+}
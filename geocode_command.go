@@ -0,0 +1,135 @@
+package main
+
+import (
+	"CarDealership/database/connection"
+	"CarDealership/geocoding"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runGeocodeCommand заполняет координаты уже сохранённых дилеров по их адресам:
+//
+//	go run . geocode [--dry-run] [--all] [-v]
+//
+// По умолчанию обрабатываются только дилеры без координат; --all пересчитывает всех.
+// Геокодер выбирается так же, как у сервера (GEOCODER, GEOCODER_GAZETTEER, GEOCODER_URL).
+// Все изменения сохраняются одной транзакцией. Возвращает код завершения процесса.
+func runGeocodeCommand(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet("geocode", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "показать найденные координаты, не сохраняя их")
+	all := fs.Bool("all", false, "пересчитать координаты всех дилеров, а не только пустые")
+	verbose := fs.Bool("v", false, "вывести результат по каждому дилеру")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: geocode [--dry-run] [--all] [-v]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	geocoder, err := geocoding.FromEnv()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Ошибка настройки геокодера:", err)
+		return 1
+	}
+	if geocoder == nil {
+		fmt.Fprintln(os.Stderr, "❌ Геокодер отключён (GEOCODER=off)")
+		return 1
+	}
+
+	pool, err := connection.CreateConnectionPool(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Ошибка создания пула соединений:", err)
+		return 1
+	}
+	defer pool.Close()
+
+	if err := prepareSchema(ctx, pool); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Ошибка подготовки схемы:", err)
+		return 1
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Ошибка начала транзакции:", err)
+		return 1
+	}
+	defer tx.Rollback(ctx)
+
+	type pending struct {
+		id                  int
+		name, city, address string
+	}
+	query := "SELECT id, name, city, address FROM dealers WHERE latitude IS NULL ORDER BY id FOR UPDATE"
+	if *all {
+		query = "SELECT id, name, city, address FROM dealers ORDER BY id FOR UPDATE"
+	}
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Ошибка чтения дилеров:", err)
+		return 1
+	}
+	var dealers []pending
+	for rows.Next() {
+		var d pending
+		if err := rows.Scan(&d.id, &d.name, &d.city, &d.address); err != nil {
+			rows.Close()
+			fmt.Fprintln(os.Stderr, "❌ Ошибка чтения дилеров:", err)
+			return 1
+		}
+		dealers = append(dealers, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Ошибка чтения дилеров:", err)
+		return 1
+	}
+
+	started := time.Now()
+	found := map[string]int{}
+	notFound := 0
+	for _, d := range dealers {
+		point, err := geocoder.Geocode(ctx, geocoding.Query{City: d.city, Address: d.address})
+		if errors.Is(err, geocoding.ErrNotFound) {
+			notFound++
+			if *verbose {
+				fmt.Printf("? #%d %s: адрес «%s, %s» не найден\n", d.id, d.name, d.city, d.address)
+			}
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Ошибка геокодирования дилера #%d: %v\n", d.id, err)
+			return 1
+		}
+
+		found[point.Precision]++
+		if *verbose {
+			fmt.Printf("✓ #%d %s: %.5f, %.5f (%s)\n", d.id, d.name, point.Latitude, point.Longitude, point.Precision)
+		}
+		if _, err := tx.Exec(ctx, "UPDATE dealers SET latitude = $1, longitude = $2 WHERE id = $3",
+			point.Latitude, point.Longitude, d.id); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Ошибка сохранения координат дилера #%d: %v\n", d.id, err)
+			return 1
+		}
+	}
+
+	if *dryRun {
+		fmt.Println("🔍 Пробный запуск: изменения не сохранены")
+	} else if err := tx.Commit(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Ошибка сохранения:", err)
+		return 1
+	}
+
+	fmt.Printf("Дилеры: обработано %d, до улицы %d, до города %d, не найдено %d\n",
+		len(dealers), found[geocoding.PrecisionStreet], found[geocoding.PrecisionCity], notFound)
+	fmt.Printf("⏱  %s\n", time.Since(started).Round(time.Millisecond))
+	return 0
+}
@@ -0,0 +1,197 @@
+package geocoding
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+//go:embed gazetteer.json
+var defaultGazetteer []byte
+
+// GazetteerFile — формат файла справочника:
+//
+//	{"version": 1, "cities": [{"name": "Минск", "aliases": ["Minsk"], "lat": 53.9, "lon": 27.56,
+//	  "streets": [{"name": "проспект Независимости", "lat": 53.9, "lon": 27.55}]}]}
+//
+// Координаты улицы — одна точка на ней; номер дома справочник не различает.
+type GazetteerFile struct {
+	Version int             `json:"version"`
+	Cities  []GazetteerCity `json:"cities"`
+}
+
+// GazetteerCity — город с координатами центра и известными улицами
+type GazetteerCity struct {
+	Name      string            `json:"name"`
+	Aliases   []string          `json:"aliases,omitempty"`
+	Latitude  float64           `json:"lat"`
+	Longitude float64           `json:"lon"`
+	Streets   []GazetteerStreet `json:"streets,omitempty"`
+}
+
+// GazetteerStreet — улица города и точка на ней
+type GazetteerStreet struct {
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases,omitempty"`
+	Latitude  float64  `json:"lat"`
+	Longitude float64  `json:"lon"`
+}
+
+// Gazetteer — офлайн-геокодер по справочнику городов и улиц.
+// Адрес сопоставляется по названиям без учёта регистра, «ё», знаков препинания
+// и типа улицы («ул.», «пр-т», «проспект» и т.п.), поэтому «ул. Невский, 10» найдёт «Невский проспект».
+// Если город известен, а улица нет, возвращается центр города с точностью PrecisionCity.
+type Gazetteer struct {
+	cities map[string]*gazetteerCity
+}
+
+type gazetteerCity struct {
+	center  Result
+	streets map[string]Result
+}
+
+// DefaultGazetteer загружает встроенный справочник (gazetteer.json)
+func DefaultGazetteer() (*Gazetteer, error) {
+	return ReadGazetteer(bytes.NewReader(defaultGazetteer))
+}
+
+// LoadGazetteer загружает справочник из файла
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	g, err := ReadGazetteer(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+// ReadGazetteer читает справочник в формате GazetteerFile и проверяет его:
+// координаты в допустимых пределах, названия не пустые и не повторяются
+func ReadGazetteer(r io.Reader) (*Gazetteer, error) {
+	var file GazetteerFile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("справочник геокодера: %w", err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("справочник геокодера: неподдерживаемая версия %d", file.Version)
+	}
+
+	g := &Gazetteer{cities: make(map[string]*gazetteerCity, len(file.Cities))}
+	for _, c := range file.Cities {
+		if err := checkPoint(c.Name, c.Latitude, c.Longitude); err != nil {
+			return nil, err
+		}
+		city := &gazetteerCity{
+			center:  Result{Latitude: c.Latitude, Longitude: c.Longitude, Precision: PrecisionCity},
+			streets: make(map[string]Result, len(c.Streets)),
+		}
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			key := normalize(name)
+			if _, dup := g.cities[key]; dup {
+				return nil, fmt.Errorf("справочник геокодера: город %q указан дважды", name)
+			}
+			g.cities[key] = city
+		}
+
+		for _, s := range c.Streets {
+			if err := checkPoint(c.Name+", "+s.Name, s.Latitude, s.Longitude); err != nil {
+				return nil, err
+			}
+			point := Result{Latitude: s.Latitude, Longitude: s.Longitude, Precision: PrecisionStreet}
+			for _, name := range append([]string{s.Name}, s.Aliases...) {
+				key := normalize(name)
+				if _, dup := city.streets[key]; dup {
+					return nil, fmt.Errorf("справочник геокодера: улица %q в городе %q указана дважды", name, c.Name)
+				}
+				city.streets[key] = point
+			}
+		}
+	}
+	return g, nil
+}
+
+// checkPoint проверяет название и координаты записи справочника
+func checkPoint(name string, lat, lon float64) error {
+	if normalize(name) == "" {
+		return fmt.Errorf("справочник геокодера: пустое название (%q)", name)
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return fmt.Errorf("справочник геокодера: недопустимые координаты у %q", name)
+	}
+	return nil
+}
+
+// Geocode ищет город (в Query.City или среди частей адреса через запятую), затем улицу в нём
+func (g *Gazetteer) Geocode(ctx context.Context, q Query) (Result, error) {
+	parts := strings.Split(q.Address, ",")
+
+	city := g.cities[normalize(q.City)]
+	if city == nil {
+		for _, part := range parts {
+			if city = g.cities[normalize(part)]; city != nil {
+				break
+			}
+		}
+	}
+	if city == nil {
+		return Result{}, ErrNotFound
+	}
+
+	// Улица — первая часть адреса, которая не город и не номер дома
+	for _, part := range parts {
+		key := normalize(part)
+		if key == "" || g.cities[key] == city || houseNumber.MatchString(key) {
+			continue
+		}
+		if point, ok := city.streets[key]; ok {
+			return point, nil
+		}
+		break
+	}
+	return city.center, nil
+}
+
+// houseNumber — часть адреса с номером дома после normalize: «1», «10а», «12/2», «д 5», «5 к1», «7 стр 2»
+var houseNumber = regexp.MustCompile(`^(д |дом )?\d+[0-9а-я/-]*( (к|корп|корпус|стр|строение) ?\d+)?$`)
+
+// addressTypes — слова, которые не входят в название: тип улицы и «город»
+var addressTypes = map[string]bool{
+	"ул": true, "улица": true,
+	"пр": true, "пр-т": true, "пр-кт": true, "просп": true, "проспект": true,
+	"пер": true, "переулок": true,
+	"пл": true, "площадь": true,
+	"б-р": true, "бул": true, "бульвар": true,
+	"ш": true, "шоссе": true, "тракт": true,
+	"наб": true, "набережная": true,
+	"г": true, "город": true,
+}
+
+// normalize приводит название к ключу справочника: нижний регистр, «ё» → «е»,
+// без знаков препинания и слов из addressTypes
+func normalize(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '/'
+	})
+	kept := words[:0]
+	for _, w := range words {
+		if !addressTypes[w] {
+			kept = append(kept, w)
+		}
+	}
+	return strings.Join(kept, " ")
+}
@@ -0,0 +1,60 @@
+package geocoding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNotFound — геокодер не знает ни улицы, ни города из адреса
+var ErrNotFound = errors.New("адрес не найден")
+
+// Точность найденных координат
+const (
+	PrecisionStreet = "street" // точка на улице (без учёта номера дома)
+	PrecisionCity   = "city"   // центр города: улица неизвестна
+)
+
+// Query — адрес дилера: город отдельно, улица с номером дома — в Address.
+// Город может быть и частью адреса («ул. Независимости, 1, Минск»), тогда City можно не заполнять.
+type Query struct {
+	City    string
+	Address string
+}
+
+// Result — найденные координаты и их точность
+type Result struct {
+	Latitude  float64
+	Longitude float64
+	Precision string
+}
+
+// Geocoder превращает адрес в координаты.
+// Если адрес не удалось найти, возвращается ErrNotFound; остальные ошибки — сбои самого геокодера.
+type Geocoder interface {
+	Geocode(ctx context.Context, q Query) (Result, error)
+}
+
+// FromEnv создаёт геокодер по переменным окружения:
+//
+//	GEOCODER           — gazetteer (по умолчанию), http или off
+//	GEOCODER_GAZETTEER — файл справочника городов и улиц; по умолчанию встроенный gazetteer.json
+//	GEOCODER_URL       — адрес внешнего сервиса для GEOCODER=http
+//
+// Для off возвращается nil: координаты тогда заполняются только вручную.
+func FromEnv() (Geocoder, error) {
+	switch kind := os.Getenv("GEOCODER"); kind {
+	case "", "gazetteer":
+		if path := os.Getenv("GEOCODER_GAZETTEER"); path != "" {
+			return LoadGazetteer(path)
+		}
+		return DefaultGazetteer()
+	case "http":
+		return NewHTTP(os.Getenv("GEOCODER_URL"))
+	case "off":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("неизвестный геокодер GEOCODER=%q (ожидается gazetteer, http или off)", kind)
+	}
+}
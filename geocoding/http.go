@@ -0,0 +1,35 @@
+package geocoding
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrNotImplemented — запросы к внешнему сервису пока не реализованы
+var ErrNotImplemented = errors.New("HTTP-геокодер ещё не реализован")
+
+// HTTP — заготовка геокодера через внешний сервис. Конфигурация уже проверяется,
+// но Geocode пока всегда возвращает ErrNotImplemented.
+type HTTP struct {
+	URL    *url.URL
+	Client *http.Client
+}
+
+// NewHTTP создаёт HTTP-геокодер для сервиса по адресу rawURL (http или https)
+func NewHTTP(rawURL string) (*HTTP, error) {
+	if rawURL == "" {
+		return nil, errors.New("для GEOCODER=http нужен GEOCODER_URL")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("GEOCODER_URL должен быть адресом http или https")
+	}
+	return &HTTP{URL: u, Client: &http.Client{Timeout: 5 * time.Second}}, nil
+}
+
+func (g *HTTP) Geocode(ctx context.Context, q Query) (Result, error) {
+	return Result{}, ErrNotImplemented
+}
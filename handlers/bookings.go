@@ -0,0 +1,642 @@
+package handlers
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/messaging"
+	"CarDealership/problem"
+	"CarDealership/schedule"
+	"CarDealership/validation"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BookingsHandler — записи на тест-драйв и свободное время дилеров
+type BookingsHandler struct {
+	DB     *pgxpool.Pool
+	Rabbit *messaging.RabbitMQ
+}
+
+func NewBookingsHandler(db *pgxpool.Pool) *BookingsHandler {
+	return &BookingsHandler{DB: db}
+}
+
+// bookingFields — колонки для чтения записи в том порядке, в котором их ожидает scanBooking.
+// Время хранится диапазоном slot, наружу отдаются его границы.
+const bookingFields = "bookings.id, bookings.car_id, bookings.dealer_id, bookings.customer_id, " +
+	"lower(bookings.slot), upper(bookings.slot), bookings.status, bookings.note, bookings.created_at"
+
+// Ограничения запроса свободного времени
+const (
+	defaultAvailabilityDays = 1
+	maxAvailabilityDays     = 14
+)
+
+// bookingActions — действия над записью: POST /api/bookings/{id}/{действие}
+var bookingActions = map[string]bool{"cancel": true, "reschedule": true}
+
+// scanBooking читает колонки bookingFields, а за ними — extra
+func scanBooking(row pgx.Row, booking *models.Booking, extra ...interface{}) error {
+	dest := []interface{}{&booking.ID, &booking.CarID, &booking.DealerID, &booking.CustomerID,
+		&booking.Start, &booking.End, &booking.Status, &booking.Note, &booking.CreatedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+type rescheduleRequest struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// availability — свободное время дилера для записи на тест-драйв
+type availability struct {
+	DealerID int                 `json:"dealer_id"`
+	Duration int                 `json:"duration"`
+	Slots    []schedule.FreeSlot `json:"slots"`
+}
+
+// BookingAction возвращает действие из пути /api/bookings/{id}/cancel или /reschedule
+// либо пустую строку, если путь — сама запись
+func BookingAction(path string) string {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) != 5 || !bookingActions[parts[4]] {
+		return ""
+	}
+	return parts[4]
+}
+
+// bookingFilter строит условия отбора записей по query-параметрам: dealer_id, car_id, customer_id,
+// status и период from/to — даты в формате ГГГГ-ММ-ДД, обе включительно
+func bookingFilter(q url.Values) ([]string, []interface{}, validation.Errors) {
+	var conds []string
+	var args []interface{}
+	v := validation.New()
+
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	for _, param := range []string{"dealer_id", "car_id", "customer_id"} {
+		value := q.Get(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			v.Add(param, validation.CodeInvalidValue, nil)
+			continue
+		}
+		add("bookings."+param+" = $%d", id)
+	}
+
+	if status := q.Get("status"); status != "" {
+		v.OneOf("status", status, validation.BookingStatuses)
+		add("bookings.status = $%d", status)
+	}
+
+	if value := q.Get("from"); value != "" {
+		from, err := time.ParseInLocation(dateLayout, value, schedule.Location)
+		if err != nil {
+			v.Add("from", validation.CodeInvalidValue, nil)
+		} else {
+			add("upper(bookings.slot) > $%d", from)
+		}
+	}
+	if value := q.Get("to"); value != "" {
+		to, err := time.ParseInLocation(dateLayout, value, schedule.Location)
+		if err != nil {
+			v.Add("to", validation.CodeInvalidValue, nil)
+		} else {
+			add("lower(bookings.slot) < $%d", to.AddDate(0, 0, 1))
+		}
+	}
+
+	if errs := v.Errors(); errs != nil {
+		return nil, nil, errs
+	}
+	return conds, args, nil
+}
+
+// GetAllBookings возвращает записи по времени начала, отфильтрованные по query-параметрам (см. bookingFilter)
+func (h *BookingsHandler) GetAllBookings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	conds, args, errs := bookingFilter(r.URL.Query())
+	if errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+	query := "SELECT " + bookingFields + " FROM bookings"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY lower(bookings.slot), bookings.id"
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, query, args...)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	bookings := []models.Booking{}
+	for rows.Next() {
+		var booking models.Booking
+		if err := scanBooking(rows, &booking); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		bookings = append(bookings, booking)
+	}
+	if err := rows.Err(); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", bookings)
+}
+
+// GetBookingByID возвращает запись по ID
+func (h *BookingsHandler) GetBookingByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var booking models.Booking
+	var version int
+	err = scanBooking(conn.QueryRow(ctx, "SELECT "+bookingFields+", version FROM bookings WHERE id = $1", id),
+		&booking, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "booking.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, versionETag(version), booking)
+}
+
+// CreateBooking записывает покупателя на тест-драйв (POST). Записаться можно на машину в наличии
+// у дилера и только в часы его работы; пересечение с другой записью на ту же машину
+// отклоняет ограничение bookings_no_overlap — ответ 409.
+func (h *BookingsHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	// Парсим JSON из тела запроса
+	var booking models.Booking
+	if err := json.NewDecoder(r.Body).Decode(&booking); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	// Валидация полей
+	booking.Note = strings.TrimSpace(booking.Note)
+	if errs := validation.ValidateBooking(booking); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	dealerID, status, err := bookableCar(ctx, tx, booking.CarID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Validation(w, r, validation.Errors{{Field: "car_id", Code: validation.CodeNotFound}})
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+	if status != models.StatusAvailable {
+		problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeConflict, "booking.car_unavailable").
+			With("status", status))
+		return
+	}
+	// Тест-драйв проводит дилер машины
+	if dealerID == nil {
+		problem.Validation(w, r, validation.Errors{{Field: "car_id", Code: validation.CodeNotAllowed}})
+		return
+	}
+	booking.DealerID = *dealerID
+
+	ok, err := customerExists(ctx, tx, booking.CustomerID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !ok {
+		problem.Validation(w, r, validation.Errors{{Field: "customer_id", Code: validation.CodeNotFound}})
+		return
+	}
+
+	if !checkDealerHours(w, r, tx, booking.DealerID, booking.Start, booking.End) {
+		return
+	}
+
+	var version int
+	err = scanBooking(tx.QueryRow(ctx,
+		`INSERT INTO bookings (car_id, dealer_id, customer_id, slot, note)
+		 VALUES ($1, $2, $3, tstzrange($4, $5, '[)'), $6) RETURNING `+bookingFields+", version",
+		booking.CarID, booking.DealerID, booking.CustomerID, booking.Start, booking.End, booking.Note),
+		&booking, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(booking)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.BookingEvent{
+			EventType: "CREATE",
+			Booking:   booking,
+		})
+	}
+}
+
+// CancelBooking отменяет запись (POST /api/bookings/{id}/cancel). Отменённая запись
+// остаётся в истории и больше не занимает время машины.
+func (h *BookingsHandler) CancelBooking(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	booking, version, ok := lockBooking(w, r, tx, id)
+	if !ok {
+		return
+	}
+
+	err = scanBooking(tx.QueryRow(ctx,
+		"UPDATE bookings SET status = $1 WHERE id = $2 RETURNING "+bookingFields+", version",
+		models.BookingCancelled, id), &booking, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	json.NewEncoder(w).Encode(booking)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.BookingEvent{
+			EventType: "CANCEL",
+			Booking:   booking,
+		})
+	}
+}
+
+// RescheduleBooking переносит запись на другое время (POST /api/bookings/{id}/reschedule,
+// тело {"start": ..., "end": ...}). Новое время проверяется так же, как при записи.
+func (h *BookingsHandler) RescheduleBooking(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var req rescheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	if errs := validation.ValidateSlot(req.Start, req.End); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	booking, version, ok := lockBooking(w, r, tx, id)
+	if !ok {
+		return
+	}
+
+	// Пока машина забронирована или продана, перенести тест-драйв нельзя
+	_, status, err := bookableCar(ctx, tx, booking.CarID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if status != models.StatusAvailable {
+		problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeConflict, "booking.car_unavailable").
+			With("status", status))
+		return
+	}
+
+	if !checkDealerHours(w, r, tx, booking.DealerID, req.Start, req.End) {
+		return
+	}
+
+	// Пересечение с другой записью отклоняет bookings_no_overlap
+	err = scanBooking(tx.QueryRow(ctx,
+		"UPDATE bookings SET slot = tstzrange($1, $2, '[)') WHERE id = $3 RETURNING "+bookingFields+", version",
+		req.Start, req.End, id), &booking, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	json.NewEncoder(w).Encode(booking)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.BookingEvent{
+			EventType: "RESCHEDULE",
+			Booking:   booking,
+		})
+	}
+}
+
+// GetAvailability возвращает свободное время для тест-драйва у дилера
+// (GET /api/dealers/{id}/availability). Параметры: date — первый день (ГГГГ-ММ-ДД, по умолчанию сегодня),
+// days — число дней (1..14), duration — длительность в минутах (по умолчанию 60), car_id — одна машина.
+// Слоты идут с шагом schedule.Step в часы работы; для каждого перечислены свободные машины.
+func (h *BookingsHandler) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	dealerID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	v := validation.New()
+	now := time.Now()
+
+	from := now
+	if value := q.Get("date"); value != "" {
+		date, err := time.ParseInLocation(dateLayout, value, schedule.Location)
+		if err != nil {
+			v.Add("date", validation.CodeInvalidValue, nil)
+		}
+		from = date
+	}
+	days := queryInt(v, q, "days", defaultAvailabilityDays)
+	v.IntRange("days", days, 1, maxAvailabilityDays)
+	minutes := queryInt(v, q, "duration", int(schedule.DefaultDuration/time.Minute))
+	v.IntRange("duration", minutes, int(schedule.MinDuration/time.Minute), int(schedule.MaxDuration/time.Minute))
+	carID := queryInt(v, q, "car_id", 0)
+	if errs := v.Errors(); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+	duration := time.Duration(minutes) * time.Minute
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM dealers WHERE id = $1)", dealerID).Scan(&exists); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !exists {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "dealer.not_found")
+		return
+	}
+
+	week, err := loadDealerWeek(ctx, conn, dealerID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	slots := week.Slots(from, days, duration, now)
+
+	// Машины дилера, на которые можно записаться: в наличии или с истёкшей бронью
+	query := `SELECT id FROM cars WHERE dealer_id = $1
+		AND (status = 'available' OR (status = 'reserved' AND reserved_until <= now()))`
+	args := []interface{}{dealerID}
+	if carID != 0 {
+		query += " AND id = $2"
+		args = append(args, carID)
+	}
+	rows, err := conn.Query(ctx, query, args...)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	cars, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	busy := schedule.Busy{}
+	if len(slots) > 0 && len(cars) > 0 {
+		rows, err := conn.Query(ctx,
+			`SELECT car_id, lower(slot), upper(slot) FROM bookings
+			 WHERE car_id = ANY($1) AND status = 'booked' AND slot && tstzrange($2, $3, '[)')`,
+			cars, slots[0].Start, slots[len(slots)-1].End)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var car int
+			var interval schedule.Interval
+			if err := rows.Scan(&car, &interval.Start, &interval.End); err != nil {
+				problem.DBError(w, r, err)
+				return
+			}
+			busy[car] = append(busy[car], interval)
+		}
+		if err := rows.Err(); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+	}
+
+	writeJSONWithETag(w, r, "", availability{
+		DealerID: dealerID,
+		Duration: minutes,
+		Slots:    schedule.Free(slots, cars, busy),
+	})
+}
+
+// bookableCar блокирует машину до конца транзакции, чтобы её не забронировали и не продали параллельно,
+// и возвращает её дилера и статус; истёкшая бронь считается снятой. Нет машины — pgx.ErrNoRows.
+func bookableCar(ctx context.Context, tx pgx.Tx, carID int) (*int, string, error) {
+	var dealerID *int
+	var status string
+	err := tx.QueryRow(ctx,
+		`SELECT dealer_id, CASE WHEN status = 'reserved' AND reserved_until <= now() THEN 'available' ELSE status END
+		 FROM cars WHERE id = $1 FOR SHARE`, carID).Scan(&dealerID, &status)
+	return dealerID, status, err
+}
+
+// lockBooking читает и блокирует действующую запись, проверяя If-Match.
+// Если записи нет, она уже отменена или версия не совпала, ответ уже отправлен и ok = false.
+func lockBooking(w http.ResponseWriter, r *http.Request, tx pgx.Tx, id int) (booking models.Booking, version int, ok bool) {
+	err := scanBooking(tx.QueryRow(r.Context(),
+		"SELECT "+bookingFields+", version FROM bookings WHERE id = $1 FOR UPDATE", id), &booking, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "booking.not_found")
+			return booking, 0, false
+		}
+		problem.DBError(w, r, err)
+		return booking, 0, false
+	}
+
+	// Проверяем, что клиент видел актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return booking, 0, false
+	}
+
+	if booking.Status == models.BookingCancelled {
+		problem.Error(w, r, http.StatusConflict, problem.CodeConflict, "booking.cancelled")
+		return booking, 0, false
+	}
+	return booking, version, true
+}
+
+// checkDealerHours проверяет, что время записи целиком приходится на часы работы дилера.
+// Если нет, отвечает ошибкой валидации поля start и возвращает false.
+func checkDealerHours(w http.ResponseWriter, r *http.Request, tx pgx.Tx, dealerID int, start, end time.Time) bool {
+	week, err := loadDealerWeek(r.Context(), tx, dealerID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return false
+	}
+	if !week.Within(schedule.Interval{Start: start, End: end}) {
+		problem.Validation(w, r, validation.Errors{{Field: "start", Code: validation.CodeOutsideHours}})
+		return false
+	}
+	return true
+}
+
+// queryInt читает целый query-параметр; нет параметра — def, не число — ошибка валидации
+func queryInt(v *validation.Validator, q url.Values, name string, def int) int {
+	value := q.Get(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		v.Add(name, validation.CodeInvalidValue, nil)
+		return def
+	}
+	return n
+}
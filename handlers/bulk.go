@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"CarDealership/database/catalog"
+	"CarDealership/database/models"
+	"CarDealership/i18n"
+	"CarDealership/messaging"
+	"CarDealership/problem"
+	"CarDealership/validation"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Режимы массовой операции
+const (
+	bulkModeAtomic     = "atomic"
+	bulkModeBestEffort = "best_effort"
+)
+
+// maxBulkOperations ограничивает число операций в одном запросе
+const maxBulkOperations = 1000
+
+type bulkOperation struct {
+	Op  string      `json:"op"`
+	ID  int         `json:"id,omitempty"`
+	Car *models.Car `json:"car,omitempty"`
+}
+
+type bulkRequest struct {
+	Mode       string          `json:"mode"`
+	Operations []bulkOperation `json:"operations"`
+}
+
+type bulkResult struct {
+	Index  int              `json:"index"`
+	Op     string           `json:"op"`
+	Status int              `json:"status"`
+	Car    *models.Car      `json:"car,omitempty"`
+	Error  *problem.Problem `json:"error,omitempty"`
+
+	eventType string
+}
+
+type bulkResponse struct {
+	Mode      string       `json:"mode"`
+	Applied   bool         `json:"applied"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []bulkResult `json:"results"`
+}
+
+// BulkCars выполняет пакет операций create/update/delete над автомобилями (POST /api/cars/bulk).
+// В режиме atomic все операции выполняются одним pgx.Batch в транзакции и откатываются при первой ошибке;
+// в режиме best_effort каждая операция выполняется в своей точке сохранения и не влияет на остальные.
+func (h *CarsHandler) BulkCars(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	var req bulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Mode == "" {
+		req.Mode = bulkModeAtomic
+	}
+	if req.Mode != bulkModeAtomic && req.Mode != bulkModeBestEffort {
+		problem.Validation(w, r, validation.Errors{{Field: "mode", Code: validation.CodeNotAllowed}})
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBulkOperations {
+		problem.Validation(w, r, validation.Errors{{
+			Field:  "operations",
+			Code:   validation.CodeOutOfRange,
+			Params: map[string]interface{}{"min": 1, "max": maxBulkOperations},
+		}})
+		return
+	}
+
+	results := make([]bulkResult, len(req.Operations))
+	for i, op := range req.Operations {
+		results[i] = bulkResult{Index: i, Op: op.Op}
+		if errs := validateBulkOperation(op); errs != nil {
+			results[i].fail(validationProblem(errs))
+		}
+	}
+
+	// Получаем соединение из пула — одно на весь пакет
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Проверяем всех упомянутых дилеров одним запросом
+	if err := checkBulkDealers(ctx, tx, req.Operations, results); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if err := checkBulkCatalog(ctx, tx, req.Operations, results); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if req.Mode == bulkModeAtomic {
+		if !hasFailures(results) {
+			h.runAtomic(ctx, r, tx, req.Operations, results)
+		}
+	} else {
+		h.runBestEffort(ctx, r, tx, req.Operations, results)
+	}
+
+	resp := bulkResponse{Mode: req.Mode, Results: results}
+	for _, res := range results {
+		if res.Error != nil {
+			resp.Failed++
+		} else if res.Status != 0 {
+			resp.Succeeded++
+		}
+	}
+
+	status := http.StatusOK
+	if req.Mode == bulkModeAtomic && resp.Failed > 0 {
+		// В атомарном режиме ничего не применено: отменяем и успешные операции
+		for i := range results {
+			if results[i].Error == nil {
+				results[i].Status = http.StatusFailedDependency
+				results[i].Car = nil
+			}
+		}
+		resp.Succeeded = 0
+		status = firstFailure(results)
+	} else {
+		if err := tx.Commit(ctx); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		resp.Applied = true
+	}
+
+	lang := i18n.FromRequest(r)
+	for _, res := range results {
+		if res.Error != nil {
+			res.Error.Localize(lang)
+		}
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+
+	if h.Media != nil && resp.Applied {
+		h.Media.Notify()
+	}
+
+	if h.Rabbit != nil && resp.Applied {
+		for _, res := range results {
+			if res.Error == nil && res.Car != nil {
+				h.Rabbit.PublishEvent(messaging.CarEvent{
+					EventType: res.eventType,
+					Car:       *res.Car,
+				})
+			}
+		}
+	}
+}
+
+// runAtomic отправляет все операции одним пакетом; первая ошибка прерывает выполнение
+func (h *CarsHandler) runAtomic(ctx context.Context, r *http.Request, tx pgx.Tx, ops []bulkOperation, results []bulkResult) {
+	batch := &pgx.Batch{}
+	for _, op := range ops {
+		query, args := bulkStatement(op)
+		batch.Queue(query, args...)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	defer br.Close()
+
+	for i, op := range ops {
+		if err := scanBulkResult(br.QueryRow(), op, &results[i]); err != nil {
+			results[i].fail(bulkProblem(r, err, op))
+			return
+		}
+	}
+}
+
+// runBestEffort выполняет операции по одной, каждую в своей точке сохранения
+func (h *CarsHandler) runBestEffort(ctx context.Context, r *http.Request, tx pgx.Tx, ops []bulkOperation, results []bulkResult) {
+	for i, op := range ops {
+		if results[i].Error != nil {
+			continue
+		}
+
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			results[i].fail(problem.ForRequest(r, err))
+			continue
+		}
+
+		query, args := bulkStatement(op)
+		err = scanBulkResult(sp.QueryRow(ctx, query, args...), op, &results[i])
+		if err == nil {
+			err = sp.Commit(ctx)
+		}
+		if err != nil {
+			sp.Rollback(ctx)
+			results[i].fail(bulkProblem(r, err, op))
+		}
+	}
+}
+
+// bulkStatement строит SQL для операции; все варианты возвращают строку автомобиля
+func bulkStatement(op bulkOperation) (string, []interface{}) {
+	returning := " RETURNING " + carFields
+
+	switch op.Op {
+	case "create":
+		return carInsertSQL + returning, carValues(*op.Car)
+	case "update":
+		return carUpdateSQL + returning, append(carValues(*op.Car), op.ID)
+	default:
+		return "DELETE FROM cars WHERE id = $1" + returning, []interface{}{op.ID}
+	}
+}
+
+func scanBulkResult(row pgx.Row, op bulkOperation, res *bulkResult) error {
+	var car models.Car
+	if err := scanCar(row, &car); err != nil {
+		return err
+	}
+
+	res.Car = &car
+	switch op.Op {
+	case "create":
+		res.Status = http.StatusCreated
+		res.eventType = "CREATE"
+	case "update":
+		res.Status = http.StatusOK
+		res.eventType = "UPDATE"
+	default:
+		res.Status = http.StatusOK
+		res.eventType = "DELETE"
+	}
+	return nil
+}
+
+// bulkProblem описывает ошибку отдельной операции; отсутствие строки означает, что машины нет
+func bulkProblem(r *http.Request, err error, op bulkOperation) *problem.Problem {
+	if err == pgx.ErrNoRows && op.Op != "create" {
+		return problem.New(http.StatusNotFound, problem.CodeNotFound, "car.not_found")
+	}
+	return problem.ForRequest(r, err)
+}
+
+func validateBulkOperation(op bulkOperation) validation.Errors {
+	switch op.Op {
+	case "create":
+		if op.Car == nil {
+			return validation.Errors{{Field: "car", Code: validation.CodeRequired}}
+		}
+		validation.NormalizeCar(op.Car)
+		return validation.ValidateCar(*op.Car)
+	case "update":
+		if op.ID <= 0 {
+			return validation.Errors{{Field: "id", Code: validation.CodeRequired}}
+		}
+		if op.Car == nil {
+			return validation.Errors{{Field: "car", Code: validation.CodeRequired}}
+		}
+		validation.NormalizeCar(op.Car)
+		return validation.ValidateCar(*op.Car)
+	case "delete":
+		if op.ID <= 0 {
+			return validation.Errors{{Field: "id", Code: validation.CodeRequired}}
+		}
+		return nil
+	}
+	return validation.Errors{{Field: "op", Code: validation.CodeNotAllowed}}
+}
+
+// checkBulkDealers проверяет существование всех дилеров пакета одним запросом
+// и блокирует их до конца транзакции. Как и в UpdateCar, update не может сменить или снять
+// дилера машины — для этого есть передача; обновляемые машины блокируются.
+func checkBulkDealers(ctx context.Context, tx pgx.Tx, ops []bulkOperation, results []bulkResult) error {
+	var carIDs []int
+	for i, op := range ops {
+		if results[i].Error == nil && op.Op == "update" {
+			carIDs = append(carIDs, op.ID)
+		}
+	}
+	if len(carIDs) > 0 {
+		rows, err := tx.Query(ctx, "SELECT id, dealer_id FROM cars WHERE id = ANY($1) FOR UPDATE", carIDs)
+		if err != nil {
+			return err
+		}
+		currentDealers := make(map[int]*int, len(carIDs))
+		var id int
+		var dealerID *int
+		_, err = pgx.ForEachRow(rows, []interface{}{&id, &dealerID}, func() error {
+			currentDealers[id] = dealerID
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Машины, которых нет, получат 404 при выполнении операции
+		for i, op := range ops {
+			current, ok := currentDealers[op.ID]
+			if results[i].Error == nil && op.Op == "update" && ok && dealerMoved(current, op.Car.DealerID) {
+				results[i].fail(validationProblem(validation.Errors{{Field: "dealer_id", Code: validation.CodeReadOnly}}))
+			}
+		}
+	}
+
+	var ids []int
+	for i, op := range ops {
+		if results[i].Error == nil && op.Car != nil && op.Car.DealerID != nil {
+			ids = append(ids, *op.Car.DealerID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(ctx, "SELECT id FROM dealers WHERE id = ANY($1) FOR SHARE", ids)
+	if err != nil {
+		return err
+	}
+	found, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[int]bool, len(found))
+	for _, id := range found {
+		existing[id] = true
+	}
+
+	for i, op := range ops {
+		if results[i].Error == nil && op.Car != nil && op.Car.DealerID != nil && !existing[*op.Car.DealerID] {
+			results[i].fail(validationProblem(validation.Errors{{Field: "dealer_id", Code: validation.CodeNotFound}}))
+		}
+	}
+	return nil
+}
+
+// checkBulkCatalog сверяет марки и модели пакета с каталогом, загружая нужные марки одним запросом
+func checkBulkCatalog(ctx context.Context, tx pgx.Tx, ops []bulkOperation, results []bulkResult) error {
+	var firms []string
+	for i, op := range ops {
+		if results[i].Error == nil && op.Car != nil {
+			firms = append(firms, op.Car.Firm)
+		}
+	}
+	if len(firms) == 0 {
+		return nil
+	}
+
+	idx, err := catalog.Load(ctx, tx, firms...)
+	if err != nil {
+		return err
+	}
+	for i, op := range ops {
+		if results[i].Error == nil && op.Car != nil {
+			if errs := idx.Resolve(op.Car); errs != nil {
+				results[i].fail(validationProblem(errs))
+			}
+		}
+	}
+	return nil
+}
+
+func (res *bulkResult) fail(p *problem.Problem) {
+	res.Status = p.Status
+	res.Error = p
+	res.Car = nil
+}
+
+func validationProblem(errs validation.Errors) *problem.Problem {
+	p := problem.New(http.StatusUnprocessableEntity, problem.CodeValidationFailed, "error.validation_failed")
+	p.Errors = errs
+	return p
+}
+
+func hasFailures(results []bulkResult) bool {
+	for _, res := range results {
+		if res.Error != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func firstFailure(results []bulkResult) int {
+	for _, res := range results {
+		if res.Error != nil {
+			return res.Status
+		}
+	}
+	return http.StatusOK
+}
@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"CarDealership/database/models"
+	"CarDealership/media"
 	"CarDealership/messaging"
+	"CarDealership/problem"
+	"CarDealership/validation"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
@@ -15,103 +19,180 @@ import (
 type CarsHandler struct {
 	DB     *pgxpool.Pool
 	Rabbit *messaging.RabbitMQ
+	// Media — фоновая очистка файлов; необязательна
+	Media *media.Cleaner
 }
 
 func NewCarsHandler(db *pgxpool.Pool) *CarsHandler {
 	return &CarsHandler{DB: db}
 }
 
-// GetAllCars возвращает все автомобили
+// carColumns — колонки cars, которые можно менять через PATCH; в том же порядке значения возвращает carValues
+var carColumns = []string{"firm", "model", "year", "power", "color", "price", "dealer_id",
+	"vin", "mileage", "condition", "body_type", "fuel_type", "transmission", "drivetrain", "trim"}
+
+// carFields — колонки для чтения автомобиля в том порядке, в котором их ожидает scanCar.
+// Статус читается вместе с остальными колонками, но меняется только переходами (см. ChangeStatus).
+var carFields = "id, " + strings.Join(carColumns, ", ") + ", status, reserved_until"
+
+// Запросы, записывающие все колонки carColumns; параметры — carValues, у UPDATE последний — id
+var (
+	carInsertSQL = fmt.Sprintf("INSERT INTO cars (%s) VALUES (%s)", strings.Join(carColumns, ", "), placeholders(len(carColumns)))
+	carUpdateSQL = fmt.Sprintf("UPDATE cars SET %s WHERE id = $%d", assignments(carColumns), len(carColumns)+1)
+)
+
+// carValues возвращает значения колонок carColumns
+func carValues(car models.Car) []interface{} {
+	return []interface{}{car.Firm, car.Model, car.Year, car.Power, car.Color, car.Price, car.DealerID,
+		car.VIN, car.Mileage, car.Condition, car.BodyType, car.FuelType, car.Transmission, car.Drivetrain, car.Trim}
+}
+
+// scanCar читает колонки carFields, а за ними — extra
+func scanCar(row pgx.Row, car *models.Car, extra ...interface{}) error {
+	dest := []interface{}{&car.ID, &car.Firm, &car.Model, &car.Year, &car.Power, &car.Color, &car.Price, &car.DealerID,
+		&car.VIN, &car.Mileage, &car.Condition, &car.BodyType, &car.FuelType, &car.Transmission, &car.Drivetrain, &car.Trim,
+		&car.Status, &car.ReservedUntil}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// placeholders возвращает список параметров $1, $2, ..., $n
+func placeholders(n int) string {
+	list := make([]string, n)
+	for i := range list {
+		list[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return strings.Join(list, ", ")
+}
+
+// assignments возвращает список присваиваний column = $i для SET
+func assignments(columns []string) string {
+	list := make([]string, len(columns))
+	for i, column := range columns {
+		list[i] = fmt.Sprintf("%s = $%d", column, i+1)
+	}
+	return strings.Join(list, ", ")
+}
+
+// GetAllCars возвращает все автомобили, отфильтрованные по query-параметрам (см. carFilter)
 func (h *CarsHandler) GetAllCars(w http.ResponseWriter, r *http.Request) {
+	where, args, errs := carFilter(r.URL.Query())
+	if errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	h.listCars(w, r, "SELECT "+carFields+" FROM cars"+where+" ORDER BY id", args...)
+}
+
+// GetUnassignedCars возвращает непроданные автомобили, не закреплённые ни за одним дилером
+func (h *CarsHandler) GetUnassignedCars(w http.ResponseWriter, r *http.Request) {
+	h.listCars(w, r, "SELECT "+carFields+" FROM cars WHERE dealer_id IS NULL AND status <> 'sold' ORDER BY id")
+}
+
+// listCars выполняет запрос списка автомобилей и отдаёт результат в JSON
+func (h *CarsHandler) listCars(w http.ResponseWriter, r *http.Request, query string, args ...interface{}) {
 	ctx := r.Context()
 
 	// Получаем соединение из пула
 	conn, err := h.DB.Acquire(ctx)
 	if err != nil {
-		http.Error(w, "Не удалось получить соединение с БД: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer conn.Release()
 
-	rows, err := conn.Query(ctx,
-		"SELECT id, firm, model, year, power, color, price, dealer_id FROM cars ORDER BY id")
+	rows, err := conn.Query(ctx, query, args...)
 	if err != nil {
-		http.Error(w, "Не удалось извлечь автомобили: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer rows.Close()
 
-	var cars []models.Car
+	cars := []models.Car{}
 	for rows.Next() {
 		var car models.Car
-		if err := rows.Scan(&car.ID, &car.Firm, &car.Model, &car.Year,
-			&car.Power, &car.Color, &car.Price, &car.DealerID); err != nil {
-			http.Error(w, "Ошибка чтения данных автомобиля: "+err.Error(), http.StatusInternalServerError)
+		if err := scanCar(rows, &car); err != nil {
+			problem.DBError(w, r, err)
 			return
 		}
 		cars = append(cars, car)
 	}
 
 	if err := rows.Err(); err != nil {
-		http.Error(w, "Ошибка обработки результатов: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(cars)
+	writeJSONWithETag(w, r, "", cars)
 }
 
 // GetCarByID возвращает автомобиль по ID
 func (h *CarsHandler) GetCarByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	path := r.URL.Path
-	pathParts := strings.Split(path, "/")
-
-	if len(pathParts) < 4 {
-		http.Error(w, "Неверный формат URL", http.StatusBadRequest)
+	id, ok := pathID(w, r)
+	if !ok {
 		return
 	}
 
-	idStr := pathParts[3]
-	if idStr == "" {
-		http.Error(w, "Необходим ID", http.StatusBadRequest)
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
 		return
 	}
+	defer conn.Release()
+
+	var car models.Car
+	var version int
+	err = scanCar(conn.QueryRow(ctx, "SELECT "+carFields+", version FROM cars WHERE id = $1", id), &car, &version)
 
-	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Неверный формат ID: "+err.Error(), http.StatusBadRequest)
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "car.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, versionETag(version), car)
+}
+
+// GetCarByVIN возвращает автомобиль по VIN: GET /api/cars/by-vin/{vin}.
+// VIN сравнивается после нормализации, поэтому регистр и дефисы не важны.
+func (h *CarsHandler) GetCarByVIN(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vin := validation.NormalizeVIN(strings.TrimPrefix(r.URL.Path, "/api/cars/by-vin/"))
+	v := validation.New()
+	v.Required("vin", vin).VIN("vin", vin)
+	if errs := v.Errors(); errs != nil {
+		problem.Validation(w, r, errs)
 		return
 	}
 
 	// Получаем соединение из пула
 	conn, err := h.DB.Acquire(ctx)
 	if err != nil {
-		http.Error(w, "Не удалось получить соединение с БД: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer conn.Release()
 
 	var car models.Car
-	err = conn.QueryRow(ctx,
-		"SELECT id, firm, model, year, power, color, price, dealer_id FROM cars WHERE id = $1", id).
-		Scan(&car.ID, &car.Firm, &car.Model, &car.Year,
-			&car.Power, &car.Color, &car.Price, &car.DealerID)
-
+	var version int
+	err = scanCar(conn.QueryRow(ctx, "SELECT "+carFields+", version FROM cars WHERE vin = $1", vin), &car, &version)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			http.Error(w, "Автомобиль не найден", http.StatusNotFound)
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "car.vin_not_found")
 			return
 		}
-		http.Error(w, "Ошибка базы данных: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(car)
+	writeJSONWithETag(w, r, versionETag(version), car)
 }
 
 // CreateCar создает новый автомобиль (POST)
@@ -119,60 +200,82 @@ func (h *CarsHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	if r.Method != http.MethodPost {
-		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
 		return
 	}
 
 	// Парсим JSON из тела запроса
 	var car models.Car
 	if err := json.NewDecoder(r.Body).Decode(&car); err != nil {
-		http.Error(w, "Неверный формат JSON: "+err.Error(), http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
 		return
 	}
 	defer r.Body.Close()
 
-	// Валидация обязательных полей
-	if car.Firm == "" || car.Model == "" || car.Year == 0 || car.Power == 0 || car.Price == 0 {
-		http.Error(w, "Отсутствуют обязательные поля (марка, модель, год, мощность, цена)", http.StatusBadRequest)
+	// Валидация полей
+	validation.NormalizeCar(&car)
+	if errs := validation.ValidateCar(car); errs != nil {
+		problem.Validation(w, r, errs)
 		return
 	}
 
 	// Получаем соединение из пула
 	conn, err := h.DB.Acquire(ctx)
 	if err != nil {
-		http.Error(w, "Не удалось получить соединение с БД: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer conn.Release()
 
-	// Вставляем новую запись в БД и получаем ID
-	var id int
-	err = conn.QueryRow(ctx,
-		`INSERT INTO cars (firm, model, year, power, color, price, dealer_id) 
-		 VALUES ($1, $2, $3, $4, $5, $6, $7) 
-		 RETURNING id`,
-		car.Firm, car.Model, car.Year, car.Power, car.Color, car.Price, car.DealerID,
-	).Scan(&id)
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
 
+	// Проверяем, что указанный дилер существует
+	ok, err := dealerExists(ctx, tx, car.DealerID)
 	if err != nil {
-		http.Error(w, "Ошибка при создании автомобиля: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
+		return
+	}
+	if !ok {
+		problem.Validation(w, r, validation.Errors{{Field: "dealer_id", Code: validation.CodeNotFound}})
 		return
 	}
 
-	// Получаем созданную запись для ответа
-	createdCar := models.Car{
-		ID:       id,
-		Firm:     car.Firm,
-		Model:    car.Model,
-		Year:     car.Year,
-		Power:    car.Power,
-		Color:    car.Color,
-		Price:    car.Price,
-		DealerID: car.DealerID,
+	// Марка и модель должны быть в каталоге
+	if errs, err := resolveCarCatalog(ctx, tx, &car); err != nil {
+		problem.DBError(w, r, err)
+		return
+	} else if errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Вставляем новую запись в БД и получаем ID; новая машина всегда в наличии
+	var id, version int
+	err = tx.QueryRow(ctx, carInsertSQL+" RETURNING id, version, status, reserved_until", carValues(car)...).
+		Scan(&id, &version, &car.Status, &car.ReservedUntil)
+
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Получаем созданную запись для ответа
+	createdCar := car
+	createdCar.ID = id
+
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(createdCar)
 
@@ -189,100 +292,110 @@ func (h *CarsHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	if r.Method != http.MethodPut {
-		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
 		return
 	}
 
-	// Извлекаем ID из пути
-	path := r.URL.Path
-	pathParts := strings.Split(path, "/")
-
-	if len(pathParts) < 4 {
-		http.Error(w, "Неверный формат URL", http.StatusBadRequest)
-		return
-	}
-
-	idStr := pathParts[3]
-	if idStr == "" {
-		http.Error(w, "ID обязателен", http.StatusBadRequest)
-		return
-	}
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Неверный формат ID: "+err.Error(), http.StatusBadRequest)
+	id, ok := pathID(w, r)
+	if !ok {
 		return
 	}
 
 	// Парсим JSON из тела запроса
 	var car models.Car
 	if err := json.NewDecoder(r.Body).Decode(&car); err != nil {
-		http.Error(w, "Неверный формат JSON: "+err.Error(), http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
 		return
 	}
 	defer r.Body.Close()
 
-	// Валидация обязательных полей
-	if car.Firm == "" || car.Model == "" || car.Year == 0 || car.Power == 0 || car.Price == 0 {
-		http.Error(w, "Отсутствуют обязательные поля (марка, модель, год, мощность, цена)", http.StatusBadRequest)
+	// Валидация полей
+	validation.NormalizeCar(&car)
+	if errs := validation.ValidateCar(car); errs != nil {
+		problem.Validation(w, r, errs)
 		return
 	}
 
 	// Получаем соединение из пула
 	conn, err := h.DB.Acquire(ctx)
 	if err != nil {
-		http.Error(w, "Не удалось получить соединение с БД: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer conn.Release()
 
-	// Проверяем существует ли автомобиль
-	var exists bool
-	err = conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM cars WHERE id = $1)", id).Scan(&exists)
+	tx, err := conn.Begin(ctx)
 	if err != nil {
-		http.Error(w, "Ошибка базы данных: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	if !exists {
-		http.Error(w, "Автомобиль не найден", http.StatusNotFound)
+	// Проверяем существует ли автомобиль и блокируем его до конца транзакции
+	var version int
+	var currentDealer *int
+	err = tx.QueryRow(ctx, "SELECT version, dealer_id FROM cars WHERE id = $1 FOR UPDATE", id).Scan(&version, &currentDealer)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "car.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
 		return
 	}
 
-	// Обновляем запись
-	result, err := conn.Exec(ctx,
-		`UPDATE cars 
-		 SET firm = $1, model = $2, year = $3, power = $4, color = $5, price = $6, dealer_id = $7
-		 WHERE id = $8`,
-		car.Firm, car.Model, car.Year, car.Power, car.Color, car.Price, car.DealerID, id,
-	)
+	// Проверяем, что клиент редактировал актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	// Дилер машины меняется только передачей; снять его тоже нельзя
+	if dealerMoved(currentDealer, car.DealerID) {
+		problem.Validation(w, r, validation.Errors{{Field: "dealer_id", Code: validation.CodeReadOnly}})
+		return
+	}
 
+	// Проверяем, что указанный дилер существует
+	ok, err = dealerExists(ctx, tx, car.DealerID)
 	if err != nil {
-		http.Error(w, "Ошибка при обновлении автомобиля: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
+		return
+	}
+	if !ok {
+		problem.Validation(w, r, validation.Errors{{Field: "dealer_id", Code: validation.CodeNotFound}})
 		return
 	}
 
-	// Проверяем что запись была обновлена
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "Автомобиль не найден", http.StatusNotFound)
+	// Марка и модель должны быть в каталоге
+	if errs, err := resolveCarCatalog(ctx, tx, &car); err != nil {
+		problem.DBError(w, r, err)
+		return
+	} else if errs != nil {
+		problem.Validation(w, r, errs)
 		return
 	}
 
-	// Получаем обновленную запись для ответа
-	updatedCar := models.Car{
-		ID:       id,
-		Firm:     car.Firm,
-		Model:    car.Model,
-		Year:     car.Year,
-		Power:    car.Power,
-		Color:    car.Color,
-		Price:    car.Price,
-		DealerID: car.DealerID,
+	// Обновляем запись; триггер увеличивает версию. Статус PUT не меняет — возвращаем текущий
+	err = tx.QueryRow(ctx, carUpdateSQL+" RETURNING version, status, reserved_until", append(carValues(car), id)...).
+		Scan(&version, &car.Status, &car.ReservedUntil)
+
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Получаем обновленную запись для ответа
+	updatedCar := car
+	updatedCar.ID = id
+
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
 	json.NewEncoder(w).Encode(updatedCar)
 
 	if h.Rabbit != nil {
@@ -293,76 +406,193 @@ func (h *CarsHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// DeleteCar удаляет автомобиль по ID (DELETE)
-func (h *CarsHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
+// PatchCar частично обновляет автомобиль (PATCH): меняются только затронутые колонки,
+// а валидация применяется к итоговому состоянию
+func (h *CarsHandler) PatchCar(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+	if r.Method != http.MethodPatch {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
 		return
 	}
 
-	// Извлекаем ID из пути
-	path := r.URL.Path
-	pathParts := strings.Split(path, "/")
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	defer r.Body.Close()
 
-	if len(pathParts) < 4 {
-		http.Error(w, "Неверный формат URL", http.StatusBadRequest)
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
 		return
 	}
+	defer conn.Release()
 
-	idStr := pathParts[3]
-	if idStr == "" {
-		http.Error(w, "ID обязателен", http.StatusBadRequest)
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	id, err := strconv.Atoi(idStr)
+	// Блокируем строку, чтобы патч применялся к актуальному состоянию
+	var current models.Car
+	var version int
+	err = scanCar(tx.QueryRow(ctx, "SELECT "+carFields+", version FROM cars WHERE id = $1 FOR UPDATE", id),
+		&current, &version)
 	if err != nil {
-		http.Error(w, "Неверный формат ID: "+err.Error(), http.StatusBadRequest)
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "car.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Проверяем, что клиент редактировал актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	var car models.Car
+	if p := applyPatch(r, current, &car); p != nil {
+		problem.Write(w, r, p)
+		return
+	}
+
+	if car.ID != id {
+		problem.Validation(w, r, validation.Errors{{Field: "id", Code: validation.CodeReadOnly}})
+		return
+	}
+
+	// Статус меняется только через /reserve, /release и /sell
+	if car.Status != current.Status || !sameTime(car.ReservedUntil, current.ReservedUntil) {
+		problem.Validation(w, r, validation.Errors{{Field: "status", Code: validation.CodeReadOnly}})
+		return
+	}
+
+	// Дилер машины меняется только передачей; снять его тоже нельзя
+	if dealerMoved(current.DealerID, car.DealerID) {
+		problem.Validation(w, r, validation.Errors{{Field: "dealer_id", Code: validation.CodeReadOnly}})
+		return
+	}
+
+	// Валидация итогового состояния
+	validation.NormalizeCar(&car)
+	if errs := validation.ValidateCar(car); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Марка и модель должны быть в каталоге; каноническое написание не считается изменением
+	if errs, err := resolveCarCatalog(ctx, tx, &car); err != nil {
+		problem.DBError(w, r, err)
+		return
+	} else if errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	changes := changedColumns(current, car, carColumns)
+
+	if _, changed := changes["dealer_id"]; changed {
+		found, err := dealerExists(ctx, tx, car.DealerID)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		if !found {
+			problem.Validation(w, r, validation.Errors{{Field: "dealer_id", Code: validation.CodeNotFound}})
+			return
+		}
+	}
+
+	if len(changes) > 0 {
+		version, err = updateColumns(ctx, tx, "cars", id, changes)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	json.NewEncoder(w).Encode(car)
+
+	if h.Rabbit != nil && len(changes) > 0 {
+		h.Rabbit.PublishEvent(messaging.ChangeEvent{
+			EventType: "PATCH",
+			Entity:    "car",
+			ID:        id,
+			Changes:   changes,
+		})
+	}
+}
+
+// DeleteCar удаляет автомобиль по ID (DELETE)
+func (h *CarsHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodDelete {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
 		return
 	}
 
 	// Получаем соединение из пула
 	conn, err := h.DB.Acquire(ctx)
 	if err != nil {
-		http.Error(w, "Не удалось получить соединение с БД: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer conn.Release()
 
-	// Сначала получаем данные автомобиля для RabbitMQ
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Сначала получаем данные автомобиля для RabbitMQ и блокируем строку
 	var car models.Car
-	err = conn.QueryRow(ctx,
-		"SELECT id, firm, model, year, power, color, price, dealer_id FROM cars WHERE id = $1",
-		id,
-	).Scan(&car.ID, &car.Firm, &car.Model, &car.Year,
-		&car.Power, &car.Color, &car.Price, &car.DealerID)
-
-	// Проверяем существует ли автомобиль
-	var exists bool
-	err = conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM cars WHERE id = $1)", id).Scan(&exists)
+	var version int
+	err = scanCar(tx.QueryRow(ctx, "SELECT "+carFields+", version FROM cars WHERE id = $1 FOR UPDATE", id),
+		&car, &version)
 	if err != nil {
-		http.Error(w, "Ошибка базы данных: "+err.Error(), http.StatusInternalServerError)
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "car.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
 		return
 	}
 
-	if !exists {
-		http.Error(w, "Автомобиль не найден", http.StatusNotFound)
+	// Проверяем, что клиент удаляет актуальную версию
+	if !checkIfMatch(w, r, version) {
 		return
 	}
 
 	// Удаляем запись
-	result, err := conn.Exec(ctx, "DELETE FROM cars WHERE id = $1", id)
-	if err != nil {
-		http.Error(w, "Ошибка при удалении автомобиля: "+err.Error(), http.StatusInternalServerError)
+	if _, err := tx.Exec(ctx, "DELETE FROM cars WHERE id = $1", id); err != nil {
+		problem.DBError(w, r, err)
 		return
 	}
 
-	// Проверяем что запись была удалена
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "Автомобиль не найден", http.StatusNotFound)
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
 		return
 	}
 
@@ -370,6 +600,11 @@ func (h *CarsHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.WriteHeader(http.StatusNoContent)
 
+	// Файлы удалённых вложений уже в очереди на удаление — будим фоновую очистку
+	if h.Media != nil {
+		h.Media.Notify()
+	}
+
 	if h.Rabbit != nil {
 		h.Rabbit.PublishEvent(messaging.CarEvent{
 			EventType: "DELETE",
@@ -377,3 +612,22 @@ func (h *CarsHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 }
+
+// dealerExists проверяет, что дилер существует, и блокирует его до конца транзакции,
+// чтобы его нельзя было удалить параллельно. Пустой dealerID допустим — машина без дилера.
+func dealerExists(ctx context.Context, tx pgx.Tx, dealerID *int) (bool, error) {
+	if dealerID == nil {
+		return true, nil
+	}
+
+	var id int
+	err := tx.QueryRow(ctx, "SELECT id FROM dealers WHERE id = $1 FOR SHARE", *dealerID).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,573 @@
+package handlers
+
+import (
+	"CarDealership/database/catalog"
+	"CarDealership/database/models"
+	"CarDealership/problem"
+	"CarDealership/validation"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CatalogHandler — справочник марок и моделей, с которым сверяются автомобили
+type CatalogHandler struct {
+	DB *pgxpool.Pool
+}
+
+func NewCatalogHandler(db *pgxpool.Pool) *CatalogHandler {
+	return &CatalogHandler{DB: db}
+}
+
+// catalogConflict — строка отчёта о переносе свободного текста в каталог (см. миграцию 0007)
+type catalogConflict struct {
+	ID         int       `json:"id"`
+	Entity     string    `json:"entity"`
+	Kind       string    `json:"kind"`
+	Value      string    `json:"value"`
+	ResolvedTo *string   `json:"resolved_to"`
+	CarsCount  int       `json:"cars_count"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// GetAllMakes возвращает марки каталога по алфавиту
+func (h *CatalogHandler) GetAllMakes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, "SELECT id, name FROM makes ORDER BY catalog_key(name)")
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	makes, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.Make])
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if makes == nil {
+		makes = []models.Make{}
+	}
+
+	writeJSONWithETag(w, r, "", makes)
+}
+
+// GetMakeByID возвращает марку по ID
+func (h *CatalogHandler) GetMakeByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var mk models.Make
+	err = conn.QueryRow(ctx, "SELECT id, name FROM makes WHERE id = $1", id).Scan(&mk.ID, &mk.Name)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "make.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", mk)
+}
+
+// CreateMake добавляет марку в каталог (POST). Марка, отличающаяся от существующей
+// только регистром или пробелами, считается дубликатом.
+func (h *CatalogHandler) CreateMake(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	var mk models.Make
+	if err := json.NewDecoder(r.Body).Decode(&mk); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	mk.Name = catalog.Clean(mk.Name)
+	if errs := validation.ValidateMake(mk); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	if err := conn.QueryRow(ctx, "INSERT INTO makes (name) VALUES ($1) RETURNING id", mk.Name).Scan(&mk.ID); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mk)
+}
+
+// UpdateMake переименовывает марку (PUT); название марки у её автомобилей меняется в той же транзакции
+func (h *CatalogHandler) UpdateMake(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPut {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var mk models.Make
+	if err := json.NewDecoder(r.Body).Decode(&mk); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	mk.ID = id
+	mk.Name = catalog.Clean(mk.Name)
+	if errs := validation.ValidateMake(mk); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "UPDATE makes SET name = $1 WHERE id = $2", mk.Name, id)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "make.not_found")
+		return
+	}
+
+	if err := syncCarNames(ctx, tx, "mk.id = $1", id); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mk)
+}
+
+// DeleteMake удаляет марку вместе с её моделями (DELETE).
+// Если хотя бы одна модель используется автомобилем, ничего не удаляется и возвращается 409.
+func (h *CatalogHandler) DeleteMake(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodDelete {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM models WHERE make_id = $1", id); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	tag, err := tx.Exec(ctx, "DELETE FROM makes WHERE id = $1", id)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "make.not_found")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAllModels возвращает модели каталога; ?make_id=N оставляет модели одной марки
+func (h *CatalogHandler) GetAllModels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := "SELECT id, make_id, name FROM models"
+	var args []interface{}
+	if value := r.URL.Query().Get("make_id"); value != "" {
+		makeID, err := strconv.Atoi(value)
+		if err != nil || makeID <= 0 {
+			problem.Validation(w, r, validation.Errors{{Field: "make_id", Code: validation.CodeInvalidValue}})
+			return
+		}
+		query += " WHERE make_id = $1"
+		args = append(args, makeID)
+	}
+	query += " ORDER BY make_id, catalog_key(name)"
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, query, args...)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	list, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.CarModel])
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if list == nil {
+		list = []models.CarModel{}
+	}
+
+	writeJSONWithETag(w, r, "", list)
+}
+
+// GetModelByID возвращает модель по ID
+func (h *CatalogHandler) GetModelByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var mo models.CarModel
+	err = conn.QueryRow(ctx, "SELECT id, make_id, name FROM models WHERE id = $1", id).
+		Scan(&mo.ID, &mo.MakeID, &mo.Name)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "model.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", mo)
+}
+
+// CreateModel добавляет модель в каталог (POST)
+func (h *CatalogHandler) CreateModel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	var mo models.CarModel
+	if err := json.NewDecoder(r.Body).Decode(&mo); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	mo.Name = catalog.Clean(mo.Name)
+	if errs := validation.ValidateCarModel(mo); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if ok, err := makeExists(ctx, tx, mo.MakeID); err != nil {
+		problem.DBError(w, r, err)
+		return
+	} else if !ok {
+		problem.Validation(w, r, validation.Errors{{Field: "make_id", Code: validation.CodeNotFound}})
+		return
+	}
+
+	err = tx.QueryRow(ctx, "INSERT INTO models (make_id, name) VALUES ($1, $2) RETURNING id", mo.MakeID, mo.Name).
+		Scan(&mo.ID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mo)
+}
+
+// UpdateModel переименовывает модель или переносит её к другой марке (PUT);
+// марка и модель её автомобилей меняются в той же транзакции
+func (h *CatalogHandler) UpdateModel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPut {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var mo models.CarModel
+	if err := json.NewDecoder(r.Body).Decode(&mo); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	mo.ID = id
+	mo.Name = catalog.Clean(mo.Name)
+	if errs := validation.ValidateCarModel(mo); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if ok, err := makeExists(ctx, tx, mo.MakeID); err != nil {
+		problem.DBError(w, r, err)
+		return
+	} else if !ok {
+		problem.Validation(w, r, validation.Errors{{Field: "make_id", Code: validation.CodeNotFound}})
+		return
+	}
+
+	tag, err := tx.Exec(ctx, "UPDATE models SET make_id = $1, name = $2 WHERE id = $3", mo.MakeID, mo.Name, id)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "model.not_found")
+		return
+	}
+
+	if err := syncCarNames(ctx, tx, "mo.id = $1", id); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mo)
+}
+
+// DeleteModel удаляет модель (DELETE); модель, которая используется автомобилями, удалить нельзя
+func (h *CatalogHandler) DeleteModel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodDelete {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, "DELETE FROM models WHERE id = $1", id)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "model.not_found")
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetConflicts возвращает отчёт о переносе марок и моделей из свободного текста в каталог:
+// объединённые написания, похожие названия для ручной проверки и машины без записи в каталоге
+func (h *CatalogHandler) GetConflicts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT id, entity, kind, value, resolved_to, cars_count, created_at
+		 FROM catalog_conflicts ORDER BY id`)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	conflicts, err := pgx.CollectRows(rows, pgx.RowToStructByPos[catalogConflict])
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if conflicts == nil {
+		conflicts = []catalogConflict{}
+	}
+
+	writeJSONWithETag(w, r, "", conflicts)
+}
+
+// makeExists проверяет, что марка существует, и блокирует её до конца транзакции
+func makeExists(ctx context.Context, tx pgx.Tx, id int) (bool, error) {
+	err := tx.QueryRow(ctx, "SELECT id FROM makes WHERE id = $1 FOR SHARE", id).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// syncCarNames переписывает марку и модель у автомобилей, ссылающихся на изменённые записи каталога.
+// cond отбирает модели (mo) или марки (mk) по параметру $1.
+func syncCarNames(ctx context.Context, tx pgx.Tx, cond string, id int) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE cars c SET firm = mk.name, model = mo.name
+		 FROM models mo JOIN makes mk ON mk.id = mo.make_id
+		 WHERE c.model_id = mo.id AND (c.firm <> mk.name OR c.model <> mo.name) AND `+cond, id)
+	return err
+}
+
+// resolveCarCatalog сверяет марку и модель автомобиля с каталогом и подставляет их каноническое написание
+func resolveCarCatalog(ctx context.Context, tx pgx.Tx, car *models.Car) (validation.Errors, error) {
+	idx, err := catalog.Load(ctx, tx, car.Firm)
+	if err != nil {
+		return nil, err
+	}
+	return idx.Resolve(car), nil
+}
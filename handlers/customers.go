@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/messaging"
+	"CarDealership/problem"
+	"CarDealership/validation"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CustomersHandler — покупатели
+type CustomersHandler struct {
+	DB     *pgxpool.Pool
+	Rabbit *messaging.RabbitMQ
+}
+
+func NewCustomersHandler(db *pgxpool.Pool) *CustomersHandler {
+	return &CustomersHandler{DB: db}
+}
+
+// customerFields — колонки для чтения покупателя в том порядке, в котором их ожидает scanCustomer
+const customerFields = "id, name, email, phone, created_at"
+
+// scanCustomer читает колонки customerFields, а за ними — extra
+func scanCustomer(row pgx.Row, customer *models.Customer, extra ...interface{}) error {
+	dest := []interface{}{&customer.ID, &customer.Name, &customer.Email, &customer.Phone, &customer.CreatedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// GetAllCustomers возвращает покупателей по алфавиту. Параметр q ищет по подстроке
+// в имени, email или телефоне без учёта регистра.
+func (h *CustomersHandler) GetAllCustomers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := "SELECT " + customerFields + " FROM customers"
+	var args []interface{}
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		query += " WHERE name ILIKE $1 OR email ILIKE $1 OR phone ILIKE $1"
+		args = append(args, "%"+likeEscape(q)+"%")
+	}
+	query += " ORDER BY name, id"
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, query, args...)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	customers := []models.Customer{}
+	for rows.Next() {
+		var customer models.Customer
+		if err := scanCustomer(rows, &customer); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		customers = append(customers, customer)
+	}
+	if err := rows.Err(); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", customers)
+}
+
+// GetCustomerByID возвращает покупателя по ID
+func (h *CustomersHandler) GetCustomerByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var customer models.Customer
+	var version int
+	err = scanCustomer(conn.QueryRow(ctx, "SELECT "+customerFields+", version FROM customers WHERE id = $1", id),
+		&customer, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "customer.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, versionETag(version), customer)
+}
+
+// CreateCustomer добавляет покупателя (POST)
+func (h *CustomersHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	var customer models.Customer
+	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	// Валидация полей
+	validation.NormalizeCustomer(&customer)
+	if errs := validation.ValidateCustomer(customer); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var version int
+	err = scanCustomer(conn.QueryRow(ctx,
+		"INSERT INTO customers (name, email, phone) VALUES ($1, $2, $3) RETURNING "+customerFields+", version",
+		customer.Name, customer.Email, customer.Phone), &customer, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(customer)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.CustomerEvent{
+			EventType: "CREATE",
+			Customer:  customer,
+		})
+	}
+}
+
+// UpdateCustomer обновляет покупателя (PUT)
+func (h *CustomersHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPut {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var customer models.Customer
+	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	// Валидация полей
+	validation.NormalizeCustomer(&customer)
+	if errs := validation.ValidateCustomer(customer); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Проверяем существует ли покупатель и блокируем его до конца транзакции
+	var version int
+	err = tx.QueryRow(ctx, "SELECT version FROM customers WHERE id = $1 FOR UPDATE", id).Scan(&version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "customer.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Проверяем, что клиент редактировал актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	// Обновляем запись; триггер увеличивает версию
+	err = scanCustomer(tx.QueryRow(ctx,
+		"UPDATE customers SET name = $1, email = $2, phone = $3 WHERE id = $4 RETURNING "+customerFields+", version",
+		customer.Name, customer.Email, customer.Phone, id), &customer, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	json.NewEncoder(w).Encode(customer)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.CustomerEvent{
+			EventType: "UPDATE",
+			Customer:  customer,
+		})
+	}
+}
+
+// DeleteCustomer удаляет покупателя (DELETE). Покупателя, у которого есть продажи, удалить нельзя.
+func (h *CustomersHandler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodDelete {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Сначала получаем данные покупателя для RabbitMQ и блокируем строку
+	var customer models.Customer
+	var version int
+	err = scanCustomer(tx.QueryRow(ctx, "SELECT "+customerFields+", version FROM customers WHERE id = $1 FOR UPDATE", id),
+		&customer, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "customer.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Проверяем, что клиент удаляет актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	// Продажи ссылаются на покупателя с ON DELETE RESTRICT — база ответит конфликтом
+	if _, err := tx.Exec(ctx, "DELETE FROM customers WHERE id = $1", id); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Возвращаем успешный ответ без тела
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.CustomerEvent{
+			EventType: "DELETE",
+			Customer:  customer,
+		})
+	}
+}
+
+// likeEscape экранирует спецсимволы шаблона LIKE, чтобы строка искалась буквально
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
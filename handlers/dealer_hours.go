@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/problem"
+	"CarDealership/schedule"
+	"CarDealership/validation"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// queryer — то, из чего можно читать строки: соединение пула или транзакция
+type queryer interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// DealerSubresource возвращает вложенный ресурс дилера из пути /api/dealers/{id}/{ресурс}
+// (sales, hours, availability) или пустую строку, если путь — сам дилер
+func DealerSubresource(path string) string {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) != 5 {
+		return ""
+	}
+	return parts[4]
+}
+
+// loadDealerHours читает часы работы дилера: расписание по дням недели и исключения по датам
+func loadDealerHours(ctx context.Context, q queryer, dealerID int) (models.OpeningHours, error) {
+	hours := models.OpeningHours{}
+
+	rows, err := q.Query(ctx,
+		`SELECT weekday, to_char(opens, 'HH24:MI'), to_char(closes, 'HH24:MI')
+		 FROM dealer_hours WHERE dealer_id = $1 ORDER BY weekday`, dealerID)
+	if err != nil {
+		return hours, err
+	}
+	hours.Weekly, err = pgx.CollectRows(rows, pgx.RowToStructByPos[models.DealerHours])
+	if err != nil {
+		return hours, err
+	}
+
+	rows, err = q.Query(ctx,
+		`SELECT to_char(day, 'YYYY-MM-DD'), COALESCE(to_char(opens, 'HH24:MI'), ''),
+		        COALESCE(to_char(closes, 'HH24:MI'), ''), note
+		 FROM dealer_hours_exceptions WHERE dealer_id = $1 ORDER BY day`, dealerID)
+	if err != nil {
+		return hours, err
+	}
+	hours.Exceptions, err = pgx.CollectRows(rows, pgx.RowToStructByPos[models.HoursException])
+	if err != nil {
+		return hours, err
+	}
+
+	if hours.Weekly == nil {
+		hours.Weekly = []models.DealerHours{}
+	}
+	if hours.Exceptions == nil {
+		hours.Exceptions = []models.HoursException{}
+	}
+	return hours, nil
+}
+
+// loadDealerWeek читает расписание дилера для проверки времени записи
+func loadDealerWeek(ctx context.Context, q queryer, dealerID int) (schedule.Week, error) {
+	hours, err := loadDealerHours(ctx, q, dealerID)
+	if err != nil {
+		return schedule.Week{}, err
+	}
+	return schedule.NewWeek(hours)
+}
+
+// GetDealerHours возвращает часы работы дилера (GET /api/dealers/{id}/hours): weekly — по дням недели
+// (дня нет в списке — дилер в этот день закрыт), exceptions — особые часы в отдельные даты.
+func (h *DealersHandler) GetDealerHours(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM dealers WHERE id = $1)", id).Scan(&exists); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !exists {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "dealer.not_found")
+		return
+	}
+
+	hours, err := loadDealerHours(ctx, conn, id)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", hours)
+}
+
+// UpdateDealerHours заменяет часы работы дилера целиком (PUT /api/dealers/{id}/hours):
+// и расписание, и исключения. Уже сделанные записи на тест-драйв не переносятся и не отменяются.
+func (h *DealersHandler) UpdateDealerHours(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var hours models.OpeningHours
+	if err := json.NewDecoder(r.Body).Decode(&hours); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	if errs := validation.ValidateDealerHours(hours); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем дилера, чтобы два запроса не перемешали расписание
+	ok, err = dealerExists(ctx, tx, &id)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !ok {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "dealer.not_found")
+		return
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM dealer_hours WHERE dealer_id = $1", id); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	for _, day := range hours.Weekly {
+		_, err := tx.Exec(ctx,
+			"INSERT INTO dealer_hours (dealer_id, weekday, opens, closes) VALUES ($1, $2, $3::time, $4::time)",
+			id, day.Weekday, day.Opens, day.Closes)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM dealer_hours_exceptions WHERE dealer_id = $1", id); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	for _, e := range hours.Exceptions {
+		// Пустое время — дилер закрыт весь день
+		_, err := tx.Exec(ctx,
+			`INSERT INTO dealer_hours_exceptions (dealer_id, day, opens, closes, note)
+			 VALUES ($1, $2::date, NULLIF($3, '')::time, NULLIF($4, '')::time, $5)`,
+			id, e.Date, e.Opens, e.Closes, e.Note)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+	}
+
+	saved, err := loadDealerHours(ctx, tx, id)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
@@ -2,23 +2,45 @@ package handlers
 
 import (
 	"CarDealership/database/models"
+	"CarDealership/geocoding"
+	"CarDealership/media"
+	"CarDealership/messaging"
+	"CarDealership/problem"
+	"CarDealership/validation"
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type DealersHandler struct {
-	DB *pgxpool.Pool
+	DB     *pgxpool.Pool
+	Rabbit *messaging.RabbitMQ
+	// Media — фоновая очистка файлов; необязательна
+	Media *media.Cleaner
+	// Geocoder заполняет координаты по адресу, если их не передали; необязателен
+	Geocoder geocoding.Geocoder
 }
 
 func NewDealersHandler(db *pgxpool.Pool) *DealersHandler {
 	return &DealersHandler{DB: db}
 }
 
+// dealerColumns — колонки dealers, которые можно менять через PATCH; рейтинг считается по отзывам
+var dealerColumns = []string{"name", "city", "address", "area", "phone", "email", "website", "latitude", "longitude"}
+
+// dealerFields — колонки для чтения дилера в том порядке, в котором их ожидает scanDealer
+const dealerFields = "id, name, city, address, area, rating, phone, email, website, latitude, longitude"
+
+// scanDealer читает колонки dealerFields, а за ними — extra
+func scanDealer(row pgx.Row, dealer *models.Dealer, extra ...interface{}) error {
+	dest := []interface{}{&dealer.ID, &dealer.Name, &dealer.City, &dealer.Address, &dealer.Area, &dealer.Rating,
+		&dealer.Phone, &dealer.Email, &dealer.Website, &dealer.Latitude, &dealer.Longitude}
+	return row.Scan(append(dest, extra...)...)
+}
+
 // GetAllDealers возвращает всех дилеров
 func (h *DealersHandler) GetAllDealers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -26,90 +48,76 @@ func (h *DealersHandler) GetAllDealers(w http.ResponseWriter, r *http.Request) {
 	// Получаем соединение из пула
 	conn, err := h.DB.Acquire(ctx)
 	if err != nil {
-		http.Error(w, "Не удалось получить соединение с БД: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer conn.Release()
 
-	rows, err := conn.Query(ctx,
-		"SELECT id, name, city, address, area, rating FROM dealers ORDER BY id")
+	rows, err := conn.Query(ctx, "SELECT "+dealerFields+" FROM dealers ORDER BY id")
 	if err != nil {
-		http.Error(w, "Ошибка при получении дилеров: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer rows.Close()
 
-	var dealers []models.Dealer
+	dealers := []models.Dealer{}
 	for rows.Next() {
 		var dealer models.Dealer
-		if err := rows.Scan(&dealer.ID, &dealer.Name, &dealer.City,
-			&dealer.Address, &dealer.Area, &dealer.Rating); err != nil {
-			http.Error(w, "Ошибка при чтении данных дилера: "+err.Error(), http.StatusInternalServerError)
+		if err := scanDealer(rows, &dealer); err != nil {
+			problem.DBError(w, r, err)
 			return
 		}
 		dealers = append(dealers, dealer)
 	}
 
 	if err := rows.Err(); err != nil {
-		http.Error(w, "Ошибка при обработке результатов: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dealers)
+	writeJSONWithETag(w, r, "", dealers)
 }
 
 // GetDealerByID возвращает дилера по ID
 func (h *DealersHandler) GetDealerByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	path := r.URL.Path
-	pathParts := strings.Split(path, "/")
-
-	if len(pathParts) < 4 {
-		http.Error(w, "Неверный формат URL", http.StatusBadRequest)
-		return
-	}
-
-	idStr := pathParts[3]
-	if idStr == "" {
-		http.Error(w, "ID обязателен", http.StatusBadRequest)
-		return
-	}
-
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Неверный формат ID: "+err.Error(), http.StatusBadRequest)
+	id, ok := pathID(w, r)
+	if !ok {
 		return
 	}
 
 	// Получаем соединение из пула
 	conn, err := h.DB.Acquire(ctx)
 	if err != nil {
-		http.Error(w, "Не удалось получить соединение с БД: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer conn.Release()
 
 	var dealer models.Dealer
-	err = conn.QueryRow(ctx,
-		"SELECT id, name, city, address, area, rating FROM dealers WHERE id = $1", id).
-		Scan(&dealer.ID, &dealer.Name, &dealer.City,
-			&dealer.Address, &dealer.Area, &dealer.Rating)
+	var version int
+	err = scanDealer(conn.QueryRow(ctx, "SELECT "+dealerFields+", version FROM dealers WHERE id = $1", id),
+		&dealer, &version)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			http.Error(w, "Дилер не найден", http.StatusNotFound)
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "dealer.not_found")
 			return
 		}
-		http.Error(w, "Ошибка базы данных: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dealer)
+	// В профиль дилера входят часы работы
+	hours, err := loadDealerHours(ctx, conn, id)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	dealer.Hours = &hours
+
+	writeJSONWithETag(w, r, versionETag(version), dealer)
 }
 
 // CreateDealer создает нового дилера (POST)
@@ -117,64 +125,54 @@ func (h *DealersHandler) CreateDealer(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	if r.Method != http.MethodPost {
-		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
 		return
 	}
 
 	// Парсим JSON из тела запроса
 	var dealer models.Dealer
 	if err := json.NewDecoder(r.Body).Decode(&dealer); err != nil {
-		http.Error(w, "Неверный формат JSON: "+err.Error(), http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
 		return
 	}
 	defer r.Body.Close()
 
-	// Валидация обязательных полей
-	if dealer.Name == "" || dealer.City == "" || dealer.Address == "" {
-		http.Error(w, "Отсутствуют обязательные поля (название, город, адрес)", http.StatusBadRequest)
-		return
-	}
-
-	// Валидация рейтинга (если передан)
-	if dealer.Rating < 0 || dealer.Rating > 5 {
-		http.Error(w, "Рейтинг должен быть от 0 до 5", http.StatusBadRequest)
+	// Валидация полей
+	validation.NormalizeDealer(&dealer)
+	if errs := validation.ValidateDealer(dealer); errs != nil {
+		problem.Validation(w, r, errs)
 		return
 	}
+	h.locateDealer(ctx, &dealer)
 
 	// Получаем соединение из пула
 	conn, err := h.DB.Acquire(ctx)
 	if err != nil {
-		http.Error(w, "Не удалось получить соединение с БД: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer conn.Release()
 
-	// Вставляем новую запись в БД и получаем ID
-	var id int
-	err = conn.QueryRow(ctx,
-		`INSERT INTO dealers (name, city, address, area, rating) 
-		 VALUES ($1, $2, $3, $4, $5) 
-		 RETURNING id`,
-		dealer.Name, dealer.City, dealer.Address, dealer.Area, dealer.Rating,
-	).Scan(&id)
+	// Вставляем новую запись в БД и получаем ID. Рейтинг клиент не задаёт: новый дилер
+	// начинает с априорного рейтинга, дальше его меняют отзывы
+	var version int
+	var createdDealer models.Dealer
+	err = scanDealer(conn.QueryRow(ctx,
+		`INSERT INTO dealers (name, city, address, area, rating, phone, email, website, latitude, longitude)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 RETURNING `+dealerFields+", version",
+		dealer.Name, dealer.City, dealer.Address, dealer.Area, models.ReviewPriorMean,
+		dealer.Phone, dealer.Email, dealer.Website, dealer.Latitude, dealer.Longitude,
+	), &createdDealer, &version)
 
 	if err != nil {
-		http.Error(w, "Ошибка при создании дилера: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 
-	// Получаем созданную запись для ответа
-	createdDealer := models.Dealer{
-		ID:      id,
-		Name:    dealer.Name,
-		City:    dealer.City,
-		Address: dealer.Address,
-		Area:    dealer.Area,
-		Rating:  dealer.Rating,
-	}
-
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(createdDealer)
 }
@@ -184,173 +182,392 @@ func (h *DealersHandler) UpdateDealer(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	if r.Method != http.MethodPut {
-		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Парсим JSON из тела запроса; rating читается отдельно, чтобы отличить его отсутствие от нуля
+	var body struct {
+		models.Dealer
+		Rating *float64 `json:"rating"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
 		return
 	}
+	defer r.Body.Close()
+	dealer := body.Dealer
 
-	// Извлекаем ID из пути
-	path := r.URL.Path
-	pathParts := strings.Split(path, "/")
+	// Валидация полей
+	validation.NormalizeDealer(&dealer)
+	if errs := validation.ValidateDealer(dealer); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
 
-	if len(pathParts) < 4 {
-		http.Error(w, "Неверный формат URL", http.StatusBadRequest)
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
 		return
 	}
+	defer conn.Release()
 
-	idStr := pathParts[3]
-	if idStr == "" {
-		http.Error(w, "ID обязателен", http.StatusBadRequest)
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	id, err := strconv.Atoi(idStr)
+	// Проверяем существует ли дилер и блокируем его до конца транзакции
+	var current models.Dealer
+	var version int
+	err = scanDealer(tx.QueryRow(ctx, "SELECT "+dealerFields+", version FROM dealers WHERE id = $1 FOR UPDATE", id),
+		&current, &version)
 	if err != nil {
-		http.Error(w, "Неверный формат ID: "+err.Error(), http.StatusBadRequest)
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "dealer.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
 		return
 	}
 
-	// Парсим JSON из тела запроса
-	var dealer models.Dealer
-	if err := json.NewDecoder(r.Body).Decode(&dealer); err != nil {
-		http.Error(w, "Неверный формат JSON: "+err.Error(), http.StatusBadRequest)
+	// Проверяем, что клиент редактировал актуальную версию
+	if !checkIfMatch(w, r, version) {
 		return
 	}
-	defer r.Body.Close()
 
-	// Валидация обязательных полей
-	if dealer.Name == "" || dealer.City == "" || dealer.Address == "" {
-		http.Error(w, "Отсутствуют обязательные поля (название, город, адрес)", http.StatusBadRequest)
+	// Рейтинг считается по отзывам: его можно не передавать, но изменить нельзя — как и в PATCH
+	if body.Rating != nil && *body.Rating != current.Rating {
+		problem.Validation(w, r, validation.Errors{{Field: "rating", Code: validation.CodeReadOnly}})
+		return
+	}
+
+	h.dropStaleCoordinates(current, &dealer)
+	h.locateDealer(ctx, &dealer)
+
+	// Обновляем запись; триггер увеличивает версию. Часы работы PUT не меняет
+	var updatedDealer models.Dealer
+	err = scanDealer(tx.QueryRow(ctx,
+		`UPDATE dealers
+		 SET name = $1, city = $2, address = $3, area = $4,
+		     phone = $5, email = $6, website = $7, latitude = $8, longitude = $9
+		 WHERE id = $10
+		 RETURNING `+dealerFields+", version",
+		dealer.Name, dealer.City, dealer.Address, dealer.Area,
+		dealer.Phone, dealer.Email, dealer.Website, dealer.Latitude, dealer.Longitude, id,
+	), &updatedDealer, &version)
+
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	json.NewEncoder(w).Encode(updatedDealer)
+}
+
+// PatchDealer частично обновляет дилера (PATCH): меняются только затронутые колонки,
+// а валидация применяется к итоговому состоянию
+func (h *DealersHandler) PatchDealer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPatch {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
 		return
 	}
 
-	// Валидация рейтинга (если передан)
-	if dealer.Rating < 0 || dealer.Rating > 5 {
-		http.Error(w, "Рейтинг должен быть от 0 до 5", http.StatusBadRequest)
+	id, ok := pathID(w, r)
+	if !ok {
 		return
 	}
+	defer r.Body.Close()
 
 	// Получаем соединение из пула
 	conn, err := h.DB.Acquire(ctx)
 	if err != nil {
-		http.Error(w, "Не удалось получить соединение с БД: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer conn.Release()
 
-	// Проверяем существует ли дилер
-	var exists bool
-	err = conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM dealers WHERE id = $1)", id).Scan(&exists)
+	tx, err := conn.Begin(ctx)
 	if err != nil {
-		http.Error(w, "Ошибка базы данных: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	if !exists {
-		http.Error(w, "Дилер не найден", http.StatusNotFound)
+	// Блокируем строку, чтобы патч применялся к актуальному состоянию
+	var current models.Dealer
+	var version int
+	err = scanDealer(tx.QueryRow(ctx, "SELECT "+dealerFields+", version FROM dealers WHERE id = $1 FOR UPDATE", id),
+		&current, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "dealer.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
 		return
 	}
 
-	// Обновляем запись
-	result, err := conn.Exec(ctx,
-		`UPDATE dealers 
-		 SET name = $1, city = $2, address = $3, area = $4, rating = $5
-		 WHERE id = $6`,
-		dealer.Name, dealer.City, dealer.Address, dealer.Area, dealer.Rating, id,
-	)
+	// Проверяем, что клиент редактировал актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
 
-	if err != nil {
-		http.Error(w, "Ошибка при обновлении дилера: "+err.Error(), http.StatusInternalServerError)
+	var dealer models.Dealer
+	if p := applyPatch(r, current, &dealer); p != nil {
+		problem.Write(w, r, p)
 		return
 	}
 
-	// Проверяем что запись была обновлена
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "Дилер не найден", http.StatusNotFound)
+	if dealer.ID != id {
+		problem.Validation(w, r, validation.Errors{{Field: "id", Code: validation.CodeReadOnly}})
+		return
+	}
+	// Рейтинг считается по отзывам
+	if dealer.Rating != current.Rating {
+		problem.Validation(w, r, validation.Errors{{Field: "rating", Code: validation.CodeReadOnly}})
 		return
 	}
 
-	// Получаем обновленную запись для ответа
-	updatedDealer := models.Dealer{
-		ID:      id,
-		Name:    dealer.Name,
-		City:    dealer.City,
-		Address: dealer.Address,
-		Area:    dealer.Area,
-		Rating:  dealer.Rating,
+	// Валидация итогового состояния
+	validation.NormalizeDealer(&dealer)
+	if errs := validation.ValidateDealer(dealer); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	h.dropStaleCoordinates(current, &dealer)
+	h.locateDealer(ctx, &dealer)
+
+	changes := changedColumns(current, dealer, dealerColumns)
+
+	if len(changes) > 0 {
+		version, err = updateColumns(ctx, tx, "dealers", id, changes)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
 	}
 
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updatedDealer)
+	w.Header().Set("ETag", versionETag(version))
+	json.NewEncoder(w).Encode(dealer)
+
+	if h.Rabbit != nil && len(changes) > 0 {
+		h.Rabbit.PublishEvent(messaging.ChangeEvent{
+			EventType: "PATCH",
+			Entity:    "dealer",
+			ID:        id,
+			Changes:   changes,
+		})
+	}
 }
 
-// DeleteDealer удаляет дилера по ID (DELETE)
+// DeleteDealer удаляет дилера по ID (DELETE).
+// Если у дилера есть автомобили, удаление отклоняется с 409, пока не передан
+// ?reassign_to={id} (перенести машины другому дилеру) или ?cascade=true (удалить их вместе с дилером).
+// История передач дилера сохраняется с его названием, а незавершённые заявки отменяются.
 func (h *DealersHandler) DeleteDealer(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	if r.Method != http.MethodDelete {
-		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
 		return
 	}
 
-	// Извлекаем ID из пути
-	path := r.URL.Path
-	pathParts := strings.Split(path, "/")
-
-	if len(pathParts) < 4 {
-		http.Error(w, "Неверный формат URL", http.StatusBadRequest)
+	id, ok := pathID(w, r)
+	if !ok {
 		return
 	}
 
-	idStr := pathParts[3]
-	if idStr == "" {
-		http.Error(w, "ID обязателен", http.StatusBadRequest)
-		return
+	// Разбираем параметры обработки автомобилей дилера
+	query := r.URL.Query()
+
+	var err error
+	reassignTo := 0
+	if v := query.Get("reassign_to"); v != "" {
+		reassignTo, err = strconv.Atoi(v)
+		if err != nil || reassignTo <= 0 {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "dealer.invalid_reassign_to")
+			return
+		}
+		if reassignTo == id {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "dealer.reassign_to_self")
+			return
+		}
 	}
 
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Неверный формат ID: "+err.Error(), http.StatusBadRequest)
+	cascade := false
+	if v := query.Get("cascade"); v != "" {
+		cascade, err = strconv.ParseBool(v)
+		if err != nil {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "dealer.invalid_cascade")
+			return
+		}
+	}
+
+	if reassignTo != 0 && cascade {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "dealer.reassign_and_cascade")
 		return
 	}
 
 	// Получаем соединение из пула
 	conn, err := h.DB.Acquire(ctx)
 	if err != nil {
-		http.Error(w, "Не удалось получить соединение с БД: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
 	defer conn.Release()
 
-	// Проверяем существует ли дилер
-	var exists bool
-	err = conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM dealers WHERE id = $1)", id).Scan(&exists)
+	// Перенос или удаление машин и удаление дилера выполняются атомарно
+	tx, err := conn.Begin(ctx)
 	if err != nil {
-		http.Error(w, "Ошибка базы данных: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	if !exists {
-		http.Error(w, "Дилер не найден", http.StatusNotFound)
+	// Блокируем дилера, чтобы параллельно к нему не добавили машины
+	var version int
+	err = tx.QueryRow(ctx, "SELECT version FROM dealers WHERE id = $1 FOR UPDATE", id).Scan(&version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "dealer.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
 		return
 	}
 
-	// Удаляем запись
-	result, err := conn.Exec(ctx, "DELETE FROM dealers WHERE id = $1", id)
+	// Проверяем, что клиент удаляет актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	var carsCount int
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM cars WHERE dealer_id = $1", id).Scan(&carsCount)
 	if err != nil {
-		http.Error(w, "Ошибка при удалении дилера: "+err.Error(), http.StatusInternalServerError)
+		problem.DBError(w, r, err)
+		return
+	}
+
+	var affected []models.Car
+	eventType := ""
+
+	if carsCount > 0 {
+		switch {
+		case reassignTo != 0:
+			var targetID int
+			err = tx.QueryRow(ctx, "SELECT id FROM dealers WHERE id = $1 FOR SHARE", reassignTo).Scan(&targetID)
+			if err != nil {
+				if err == pgx.ErrNoRows {
+					problem.Error(w, r, http.StatusUnprocessableEntity, problem.CodeNotFound, "dealer.reassign_target_not_found")
+					return
+				}
+				problem.DBError(w, r, err)
+				return
+			}
+
+			// Перенос машин удаляемого дилера — единственная, кроме передачи, смена дилера у машины
+			if err := allowDealerMove(ctx, tx); err != nil {
+				problem.DBError(w, r, err)
+				return
+			}
+			affected, err = collectCars(tx.Query(ctx,
+				"UPDATE cars SET dealer_id = $1 WHERE dealer_id = $2 RETURNING "+carFields,
+				reassignTo, id))
+			if err != nil {
+				problem.DBError(w, r, err)
+				return
+			}
+			eventType = "UPDATE"
+
+		case cascade:
+			affected, err = collectCars(tx.Query(ctx,
+				"DELETE FROM cars WHERE dealer_id = $1 RETURNING "+carFields,
+				id))
+			if err != nil {
+				problem.DBError(w, r, err)
+				return
+			}
+			eventType = "DELETE"
+
+		default:
+			problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeDealerHasCars, "dealer.has_cars").
+				With("cars_count", carsCount))
+			return
+		}
+	}
+
+	// Удаляем запись
+	if _, err := tx.Exec(ctx, "DELETE FROM dealers WHERE id = $1", id); err != nil {
+		problem.DBError(w, r, err)
 		return
 	}
 
-	// Проверяем что запись была удалена
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "Дилер не найден", http.StatusNotFound)
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
 		return
 	}
 
 	// Возвращаем успешный ответ без тела
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.WriteHeader(http.StatusNoContent)
+
+	// При cascade вместе с машинами удалены и их вложения
+	if h.Media != nil && len(affected) > 0 && eventType == "DELETE" {
+		h.Media.Notify()
+	}
+
+	if h.Rabbit != nil {
+		for _, car := range affected {
+			h.Rabbit.PublishEvent(messaging.CarEvent{
+				EventType: eventType,
+				Car:       car,
+			})
+		}
+	}
+}
+
+// collectCars читает автомобили из результата запроса с RETURNING
+func collectCars(rows pgx.Rows, err error) ([]models.Car, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cars []models.Car
+	for rows.Next() {
+		var car models.Car
+		if err := scanCar(rows, &car); err != nil {
+			return nil, err
+		}
+		cars = append(cars, car)
+	}
+
+	return cars, rows.Err()
 }
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"CarDealership/problem"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// pathID извлекает числовой ID из пути вида /api/<ресурс>/{id}.
+// При ошибке отвечает клиенту 400 и возвращает false.
+func pathID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	pathParts := strings.Split(r.URL.Path, "/")
+
+	if len(pathParts) < 4 || pathParts[3] == "" {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidID, "error.id_required")
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(pathParts[3])
+	if err != nil || id <= 0 {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidID, "error.invalid_id")
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"CarDealership/problem"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// versionETag строит ETag записи по её версии
+func versionETag(version int) string {
+	return `"v` + strconv.Itoa(version) + `"`
+}
+
+// etagMatches проверяет, есть ли etag в списке из If-Match / If-None-Match.
+// При weak = true префикс W/ игнорируется (слабое сравнение, RFC 9110).
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+			etag = strings.TrimPrefix(etag, "W/")
+		} else if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch сверяет If-Match с текущей версией записи.
+// При несовпадении отвечает 412 и возвращает false; без заголовка проверка пропускается.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, version int) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatches(header, versionETag(version), false) {
+		return true
+	}
+
+	problem.Write(w, r, problem.New(http.StatusPreconditionFailed, problem.CodePreconditionFailed, "error.precondition_failed").
+		With("etag", versionETag(version)))
+	return false
+}
+
+// writeJSONWithETag отдаёт ответ GET с ETag и отвечает 304, если клиент уже имеет эту версию.
+// Если etag пустой, он вычисляется как хеш тела ответа (для списков).
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, etag string, v interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(v); err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "error.internal")
+		return
+	}
+
+	if etag == "" {
+		sum := sha256.Sum256(body.Bytes())
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("ETag", etag)
+	// Браузер хранит ответ, но перед использованием перепроверяет его через If-None-Match
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if header := r.Header.Get("If-None-Match"); header != "" && etagMatches(header, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body.Bytes())
+}
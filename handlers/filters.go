@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"CarDealership/validation"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// carFilter строит условие WHERE для списка автомобилей по query-параметрам:
+// firm, model, color, trim (без учёта регистра), vin, dealer_id, year_min/year_max,
+// power_min/power_max, price_min/price_max, mileage_min/mileage_max и перечислимые
+// condition, body_type, fuel_type, transmission, drivetrain, status — одно значение или несколько через запятую.
+// Без параметра status проданные автомобили в список не попадают.
+func carFilter(q url.Values) (string, []interface{}, validation.Errors) {
+	var conds []string
+	var args []interface{}
+	v := validation.New()
+
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	for _, field := range []string{"firm", "model", "color", "trim"} {
+		if value := strings.TrimSpace(q.Get(field)); value != "" {
+			add("lower("+field+") = lower($%d)", value)
+		}
+	}
+
+	if value := q.Get("vin"); value != "" {
+		add("vin = $%d", validation.NormalizeVIN(value))
+	}
+
+	enums := []struct {
+		param   string
+		allowed []string
+	}{
+		{"condition", validation.CarConditions},
+		{"body_type", validation.BodyTypes},
+		{"fuel_type", validation.FuelTypes},
+		{"transmission", validation.Transmissions},
+		{"drivetrain", validation.Drivetrains},
+		{"status", validation.CarStatuses},
+	}
+	for _, enum := range enums {
+		value := q.Get(enum.param)
+		if value == "" {
+			continue
+		}
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			item = strings.ToLower(strings.TrimSpace(item))
+			v.OneOf(enum.param, item, enum.allowed)
+			values = append(values, item)
+		}
+		add(enum.param+" = ANY($%d)", values)
+	}
+
+	if q.Get("status") == "" {
+		conds = append(conds, "status <> 'sold'")
+	}
+
+	if value := q.Get("dealer_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			v.Add("dealer_id", validation.CodeInvalidValue, nil)
+		} else {
+			add("dealer_id = $%d", id)
+		}
+	}
+
+	ranges := []struct{ param, cond string }{
+		{"year_min", "year >= $%d"},
+		{"year_max", "year <= $%d"},
+		{"power_min", "power >= $%d"},
+		{"power_max", "power <= $%d"},
+		{"price_min", "price >= $%d"},
+		{"price_max", "price <= $%d"},
+		{"mileage_min", "mileage >= $%d"},
+		{"mileage_max", "mileage <= $%d"},
+	}
+	for _, rng := range ranges {
+		value := q.Get(rng.param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			v.Add(rng.param, validation.CodeInvalidValue, nil)
+			continue
+		}
+		add(rng.cond, n)
+	}
+
+	if errs := v.Errors(); errs != nil {
+		return "", nil, errs
+	}
+	if len(conds) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args, nil
+}
@@ -0,0 +1,613 @@
+package handlers
+
+import (
+	"CarDealership/database/catalog"
+	"CarDealership/database/loader"
+	"CarDealership/database/models"
+	"CarDealership/i18n"
+	"CarDealership/messaging"
+	"CarDealership/problem"
+	"CarDealership/spreadsheet"
+	"CarDealership/validation"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxImportSize — максимальный размер загружаемого файла
+const maxImportSize = 20 << 20
+
+// carHeaderAliases сопоставляет заголовки колонок файла с полями автомобиля
+var carHeaderAliases = map[string]string{
+	"firm": "firm", "make": "firm", "brand": "firm", "марка": "firm", "производитель": "firm",
+	"model": "model", "модель": "model",
+	"year": "year", "год": "year", "год выпуска": "year",
+	"power": "power", "мощность": "power", "л.с.": "power",
+	"color": "color", "colour": "color", "цвет": "color",
+	"price": "price", "цена": "price", "стоимость": "price",
+	"dealer_id": "dealer_id", "dealer": "dealer_id", "дилер": "dealer_id", "id дилера": "dealer_id",
+	"vin": "vin", "вин": "vin",
+	"mileage": "mileage", "odometer": "mileage", "пробег": "mileage",
+	"condition": "condition", "состояние": "condition",
+	"body_type": "body_type", "body": "body_type", "кузов": "body_type", "тип кузова": "body_type",
+	"fuel_type": "fuel_type", "fuel": "fuel_type", "топливо": "fuel_type", "двигатель": "fuel_type",
+	"transmission": "transmission", "gearbox": "transmission", "коробка": "transmission", "кпп": "transmission",
+	"drivetrain": "drivetrain", "drive": "drivetrain", "привод": "drivetrain",
+	"trim": "trim", "комплектация": "trim",
+}
+
+// requiredCarColumns — колонки, без которых файл не импортируется
+var requiredCarColumns = []string{"firm", "model", "year", "power", "color", "price"}
+
+// exportCarColumns — колонки выгрузки автомобилей
+var exportCarColumns = append([]string{"id"}, carColumns...)
+
+// importRowError — ошибки одной строки файла (номер строки считается с заголовком, с единицы)
+type importRowError struct {
+	Row    int               `json:"row"`
+	Errors validation.Errors `json:"errors"`
+}
+
+type importReport struct {
+	DryRun   bool              `json:"dry_run"`
+	Applied  bool              `json:"applied"`
+	Total    int               `json:"total"`
+	Valid    int               `json:"valid"`
+	Invalid  int               `json:"invalid"`
+	Imported int               `json:"imported"`
+	Columns  map[string]string `json:"columns"`
+	Errors   []importRowError  `json:"errors"`
+}
+
+// importRow — разобранная строка файла
+type importRow struct {
+	line   int
+	car    models.Car
+	errors validation.Errors
+}
+
+// ImportCars загружает автомобили из CSV/XLSX (POST /api/import/cars, multipart-поле file).
+// Необязательные поля формы: mapping — JSON {"заголовок": "поле"}, dry_run — только проверить файл,
+// strict — не импортировать ничего, если есть ошибочные строки.
+// В ответе — отчёт с ошибками по каждой невалидной строке.
+func (h *CarsHandler) ImportCars(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "import.invalid_form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		problem.Validation(w, r, validation.Errors{{Field: "file", Code: validation.CodeRequired}})
+		return
+	}
+	defer file.Close()
+
+	format := strings.ToLower(r.FormValue("format"))
+	if format == "" {
+		format, err = spreadsheet.FormatFromName(header.Filename)
+	}
+	reader, err := openSpreadsheet(format, file, header.Size, err)
+	if err != nil {
+		problem.Error(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "import.unsupported_format")
+		return
+	}
+
+	var mapping map[string]string
+	if raw := r.FormValue("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			problem.Validation(w, r, validation.Errors{{Field: "mapping", Code: validation.CodeInvalidValue}})
+			return
+		}
+	}
+
+	dryRun, errDry := formBool(r, "dry_run")
+	strict, errStrict := formBool(r, "strict")
+	if errDry != nil || errStrict != nil {
+		v := validation.New()
+		if errDry != nil {
+			v.Add("dry_run", validation.CodeInvalidValue, nil)
+		}
+		if errStrict != nil {
+			v.Add("strict", validation.CodeInvalidValue, nil)
+		}
+		problem.Validation(w, r, v.Errors())
+		return
+	}
+
+	headerRow, err := reader.Read()
+	if err != nil {
+		problem.Error(w, r, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "import.empty_file")
+		return
+	}
+	columns, used, errs := mapCarColumns(headerRow, mapping)
+	if errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	rows, line, err := readCarRows(reader, columns)
+	if err != nil {
+		// Текст ошибки разборщика клиенту не отдаём: в ответе только номер строки
+		log.Printf("Ошибка чтения строки %d файла импорта %q: %v", line, header.Filename, err)
+		problem.Write(w, r, problem.New(http.StatusUnprocessableEntity, problem.CodeValidationFailed, "import.unreadable_file").
+			With("line", line))
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkImportDealers(ctx, tx, rows); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if err := checkImportCatalog(ctx, tx, rows); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if err := checkImportVINs(ctx, tx, rows); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	report := importReport{DryRun: dryRun, Total: len(rows), Columns: used, Errors: []importRowError{}}
+	lang := i18n.FromRequest(r)
+	var valid [][]interface{}
+	for _, row := range rows {
+		if row.errors != nil {
+			problem.LocalizeErrors(lang, row.errors)
+			report.Errors = append(report.Errors, importRowError{Row: row.line, Errors: row.errors})
+			continue
+		}
+		valid = append(valid, carValues(row.car))
+	}
+	report.Valid = len(valid)
+	report.Invalid = len(report.Errors)
+
+	if !dryRun && len(valid) > 0 && !(strict && report.Invalid > 0) {
+		n, err := tx.CopyFrom(ctx, pgx.Identifier{"cars"}, carColumns, pgx.CopyFromRows(valid))
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		report.Imported = int(n)
+		report.Applied = true
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+
+	if h.Rabbit != nil && report.Applied {
+		h.Rabbit.PublishEvent(messaging.BatchEvent{
+			EventType: "IMPORT",
+			Entity:    "car",
+			Count:     report.Imported,
+		})
+	}
+}
+
+// ExportCars выгружает автомобили в CSV, XLSX или JSON (GET /api/cars/export?format=csv|xlsx|json).
+// Поддерживает те же фильтры, что и список автомобилей; строки пишутся в ответ по мере чтения из базы.
+func (h *CarsHandler) ExportCars(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = spreadsheet.FormatCSV
+	}
+	if format != "json" && format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		problem.Validation(w, r, validation.Errors{{Field: "format", Code: validation.CodeNotAllowed}})
+		return
+	}
+
+	where, args, errs := carFilter(r.URL.Query())
+	if errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, "SELECT "+carFields+" FROM cars"+where+" ORDER BY id", args...)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("cars-%s.%s", time.Now().Format("20060102"), format)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		err = exportCarsJSON(w, rows)
+	} else {
+		if format == spreadsheet.FormatCSV {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		}
+		err = exportCarsTable(w, format, rows)
+	}
+	if err != nil {
+		// Заголовки уже отправлены — остаётся только залогировать и оборвать ответ
+		problem.ForRequest(r, err)
+	}
+}
+
+// exportCarsJSON пишет автомобили в формате файла начальных данных: {"version": 1, "cars": [...]},
+// поэтому выгрузку можно снова загрузить командой import
+func exportCarsJSON(w io.Writer, rows pgx.Rows) error {
+	if _, err := fmt.Fprintf(w, `{"version":%d,"cars":[`, loader.CurrentVersion); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for i := 0; rows.Next(); i++ {
+		var car models.Car
+		if err := scanCar(rows, &car); err != nil {
+			return err
+		}
+		// Статус не входит в формат начальных данных: загруженные машины всегда в наличии
+		car.Status, car.ReservedUntil = "", nil
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(car); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "]}\n")
+	return err
+}
+
+// exportCarsTable пишет автомобили в CSV или XLSX с заголовком exportCarColumns
+func exportCarsTable(w io.Writer, format string, rows pgx.Rows) error {
+	out, err := spreadsheet.NewWriter(format, w)
+	if err != nil {
+		return err
+	}
+	if err := out.Write(exportCarColumns); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var car models.Car
+		if err := scanCar(rows, &car); err != nil {
+			return err
+		}
+		dealer, vin := "", ""
+		if car.DealerID != nil {
+			dealer = strconv.Itoa(*car.DealerID)
+		}
+		if car.VIN != nil {
+			vin = *car.VIN
+		}
+		record := []string{
+			strconv.Itoa(car.ID), car.Firm, car.Model, strconv.Itoa(car.Year),
+			strconv.Itoa(car.Power), car.Color, strconv.Itoa(car.Price), dealer,
+			vin, strconv.Itoa(car.Mileage), car.Condition, car.BodyType, car.FuelType,
+			car.Transmission, car.Drivetrain, car.Trim,
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// openSpreadsheet открывает файл, если формат удалось определить
+func openSpreadsheet(format string, file io.ReaderAt, size int64, formatErr error) (spreadsheet.RowReader, error) {
+	if formatErr != nil {
+		return nil, formatErr
+	}
+	return spreadsheet.NewReader(format, file, size)
+}
+
+// mapCarColumns сопоставляет колонки файла с полями автомобиля.
+// Явное сопоставление из mapping имеет приоритет над известными заголовками.
+// Возвращает индексы колонок по полям и использованное сопоставление для отчёта.
+func mapCarColumns(header []string, mapping map[string]string) (map[string]int, map[string]string, validation.Errors) {
+	explicit := make(map[string]string, len(mapping))
+	for title, field := range mapping {
+		explicit[normalizeHeader(title)] = field
+	}
+
+	v := validation.New()
+	columns := make(map[string]int)
+	used := make(map[string]string)
+	for i, title := range header {
+		key := normalizeHeader(title)
+		field, ok := explicit[key]
+		if ok {
+			if !isCarColumn(field) {
+				v.Add("mapping."+title, validation.CodeNotAllowed, nil)
+				continue
+			}
+		} else if field, ok = carHeaderAliases[key]; !ok {
+			continue
+		}
+		if _, dup := columns[field]; dup {
+			continue
+		}
+		columns[field] = i
+		used[title] = field
+	}
+
+	for _, field := range requiredCarColumns {
+		if _, ok := columns[field]; !ok {
+			v.Add("columns."+field, validation.CodeRequired, nil)
+		}
+	}
+	return columns, used, v.Errors()
+}
+
+// readCarRows читает строки файла и проверяет каждую; пустые строки пропускаются.
+// Если файл не удалось дочитать, возвращается номер строки, на которой случилась ошибка.
+func readCarRows(reader spreadsheet.RowReader, columns map[string]int) ([]importRow, int, error) {
+	var rows []importRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, 0, nil
+		}
+		if err != nil {
+			return nil, line, err
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		rows = append(rows, parseCarRow(line, record, columns))
+	}
+}
+
+// parseCarRow превращает строку файла в автомобиль и собирает ошибки разбора и валидации
+func parseCarRow(line int, record []string, columns map[string]int) importRow {
+	cell := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := importRow{line: line}
+	parseErrs := validation.New()
+	number := func(field string) int {
+		value := cell(field)
+		if value == "" {
+			return 0
+		}
+		n, err := parseInt(value)
+		if err != nil {
+			parseErrs.Add(field, validation.CodeInvalidValue, nil)
+		}
+		return n
+	}
+
+	row.car = models.Car{
+		Firm:         cell("firm"),
+		Model:        cell("model"),
+		Color:        cell("color"),
+		Year:         number("year"),
+		Power:        number("power"),
+		Price:        number("price"),
+		Mileage:      number("mileage"),
+		Condition:    cell("condition"),
+		BodyType:     cell("body_type"),
+		FuelType:     cell("fuel_type"),
+		Transmission: cell("transmission"),
+		Drivetrain:   cell("drivetrain"),
+		Trim:         cell("trim"),
+	}
+	if cell("dealer_id") != "" {
+		id := number("dealer_id")
+		row.car.DealerID = &id
+	}
+	if vin := cell("vin"); vin != "" {
+		row.car.VIN = &vin
+	}
+	validation.NormalizeCar(&row.car)
+
+	errs := parseErrs.Errors()
+	for _, fe := range validation.ValidateCar(row.car) {
+		if !hasFieldError(errs, fe.Field) {
+			errs = append(errs, fe)
+		}
+	}
+	row.errors = errs
+	return row
+}
+
+// checkImportDealers проверяет существование дилеров одним запросом и блокирует их до конца транзакции
+func checkImportDealers(ctx context.Context, tx pgx.Tx, rows []importRow) error {
+	var ids []int
+	for _, row := range rows {
+		if row.errors == nil && row.car.DealerID != nil {
+			ids = append(ids, *row.car.DealerID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	dealerRows, err := tx.Query(ctx, "SELECT id FROM dealers WHERE id = ANY($1) FOR SHARE", ids)
+	if err != nil {
+		return err
+	}
+	found, err := pgx.CollectRows(dealerRows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+	existing := make(map[int]bool, len(found))
+	for _, id := range found {
+		existing[id] = true
+	}
+
+	for i := range rows {
+		if rows[i].errors == nil && rows[i].car.DealerID != nil && !existing[*rows[i].car.DealerID] {
+			rows[i].errors = validation.Errors{{Field: "dealer_id", Code: validation.CodeNotFound}}
+		}
+	}
+	return nil
+}
+
+// checkImportCatalog сверяет марки и модели строк с каталогом и подставляет их каноническое написание
+func checkImportCatalog(ctx context.Context, tx pgx.Tx, rows []importRow) error {
+	var firms []string
+	for _, row := range rows {
+		if row.errors == nil {
+			firms = append(firms, row.car.Firm)
+		}
+	}
+	if len(firms) == 0 {
+		return nil
+	}
+
+	idx, err := catalog.Load(ctx, tx, firms...)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		if rows[i].errors == nil {
+			rows[i].errors = idx.Resolve(&rows[i].car)
+		}
+	}
+	return nil
+}
+
+// checkImportVINs отмечает строки, VIN которых уже есть в базе или встречался в файле выше
+func checkImportVINs(ctx context.Context, tx pgx.Tx, rows []importRow) error {
+	var vins []string
+	for _, row := range rows {
+		if row.errors == nil && row.car.VIN != nil {
+			vins = append(vins, *row.car.VIN)
+		}
+	}
+	if len(vins) == 0 {
+		return nil
+	}
+
+	vinRows, err := tx.Query(ctx, "SELECT vin FROM cars WHERE vin = ANY($1)", vins)
+	if err != nil {
+		return err
+	}
+	found, err := pgx.CollectRows(vinRows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	taken := make(map[string]bool, len(found))
+	for _, vin := range found {
+		taken[vin] = true
+	}
+
+	for i := range rows {
+		if rows[i].errors != nil || rows[i].car.VIN == nil {
+			continue
+		}
+		vin := *rows[i].car.VIN
+		if taken[vin] {
+			rows[i].errors = validation.Errors{{Field: "vin", Code: validation.CodeDuplicate}}
+		}
+		taken[vin] = true
+	}
+	return nil
+}
+
+// parseInt разбирает целое число; значения вида «2020.0» из XLSX тоже допускаются
+func parseInt(value string) (int, error) {
+	value = strings.ReplaceAll(value, " ", "")
+	if n, err := strconv.Atoi(value); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return 0, strconv.ErrSyntax
+	}
+	return int(f), nil
+}
+
+// formBool читает логический флаг из формы или query-параметров
+func formBool(r *http.Request, name string) (bool, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func normalizeHeader(title string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(title, "\ufeff")))
+}
+
+func isCarColumn(field string) bool {
+	for _, c := range carColumns {
+		if c == field {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func hasFieldError(errs validation.Errors, field string) bool {
+	for _, fe := range errs {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,514 @@
+package handlers
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/media"
+	"CarDealership/problem"
+	"CarDealership/storage"
+	"CarDealership/validation"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MediaHandler — фотографии и документы автомобилей: /api/cars/{id}/media
+type MediaHandler struct {
+	DB      *pgxpool.Pool
+	Storage storage.Storage
+	Cleaner *media.Cleaner
+}
+
+func NewMediaHandler(db *pgxpool.Pool, st storage.Storage, cleaner *media.Cleaner) *MediaHandler {
+	return &MediaHandler{DB: db, Storage: st, Cleaner: cleaner}
+}
+
+// Ограничения загрузки
+const (
+	maxMediaFiles = 10
+	// maxMediaRequest — весь запрос: несколько файлов и поля формы
+	maxMediaRequest = 100 << 20
+	// mediaFormMemory — сколько данных формы держится в памяти, остальное пишется во временные файлы
+	mediaFormMemory = 32 << 20
+)
+
+// mediaColumns — колонки car_media в порядке, который ожидает scanMedia
+const mediaColumns = "id, car_id, kind, file_name, content_type, size, width, height, created_at, storage_key, thumbnail_key"
+
+// mediaUpload — проверенный файл, готовый к сохранению
+type mediaUpload struct {
+	item      models.CarMedia
+	data      []byte
+	thumbnail []byte
+}
+
+// IsMediaPath сообщает, что путь относится к вложениям автомобиля: /api/cars/{id}/media...
+func IsMediaPath(path string) bool {
+	parts := strings.Split(path, "/")
+	return len(parts) >= 5 && parts[4] == "media"
+}
+
+// mediaPath разбирает путь /api/cars/{id}/media[/{mediaId}[/thumbnail]].
+// При ошибке отвечает клиенту 400 или 404 и возвращает false.
+func mediaPath(w http.ResponseWriter, r *http.Request) (carID, mediaID int, thumbnail, ok bool) {
+	carID, ok = pathID(w, r)
+	if !ok {
+		return 0, 0, false, false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+	if len(parts) > 5 {
+		mediaID, _ = strconv.Atoi(parts[5])
+		if mediaID <= 0 {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidID, "error.invalid_id")
+			return 0, 0, false, false
+		}
+	}
+	switch {
+	case len(parts) == 7 && parts[6] == "thumbnail":
+		thumbnail = true
+	case len(parts) > 6:
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "media.not_found")
+		return 0, 0, false, false
+	}
+	return carID, mediaID, thumbnail, true
+}
+
+// ServeHTTP распределяет запросы к вложениям по методам и виду пути
+func (h *MediaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	carID, mediaID, thumbnail, ok := mediaPath(w, r)
+	if !ok {
+		return
+	}
+
+	switch {
+	case mediaID == 0 && r.Method == http.MethodGet:
+		h.ListMedia(w, r, carID)
+	case mediaID == 0 && r.Method == http.MethodPost:
+		h.UploadMedia(w, r, carID)
+	case mediaID != 0 && r.Method == http.MethodGet:
+		h.DownloadMedia(w, r, carID, mediaID, thumbnail)
+	case mediaID != 0 && !thumbnail && r.Method == http.MethodDelete:
+		h.DeleteMedia(w, r, carID, mediaID)
+	default:
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+	}
+}
+
+// ListMedia возвращает вложения автомобиля в порядке загрузки
+func (h *MediaHandler) ListMedia(w http.ResponseWriter, r *http.Request, carID int) {
+	ctx := r.Context()
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM cars WHERE id = $1)", carID).Scan(&exists); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !exists {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "car.not_found")
+		return
+	}
+
+	rows, err := conn.Query(ctx, "SELECT "+mediaColumns+" FROM car_media WHERE car_id = $1 ORDER BY id", carID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	items := []models.CarMedia{}
+	for rows.Next() {
+		var item models.CarMedia
+		if err := scanMedia(rows, &item); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", items)
+}
+
+// UploadMedia загружает файлы (POST, multipart-поле file, можно несколько).
+// Необязательное поле kind — photo или document — применяется ко всем файлам; по умолчанию
+// изображения считаются фотографиями, PDF — документами. Тип файла определяется по содержимому.
+// У изображений удаляются метаданные (EXIF, в том числе координаты съёмки) и строится превью.
+// Если хотя бы один файл не прошёл проверку, ничего не сохраняется.
+func (h *MediaHandler) UploadMedia(w http.ResponseWriter, r *http.Request, carID int) {
+	ctx := r.Context()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxMediaRequest)
+	if err := r.ParseMultipartForm(mediaFormMemory); err != nil {
+		h.invalidForm(w, r)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		problem.Validation(w, r, validation.Errors{{Field: "file", Code: validation.CodeRequired}})
+		return
+	}
+	if len(files) > maxMediaFiles {
+		h.invalidForm(w, r)
+		return
+	}
+
+	kind := r.FormValue("kind")
+	if kind != "" && kind != media.KindPhoto && kind != media.KindDocument {
+		problem.Validation(w, r, validation.Errors{{Field: "kind", Code: validation.CodeNotAllowed}})
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	// Машину проверяем до обработки изображений, чтобы не тратить на них время зря
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM cars WHERE id = $1)", carID).Scan(&exists); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !exists {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "car.not_found")
+		return
+	}
+
+	uploads := make([]mediaUpload, 0, len(files))
+	v := validation.New()
+	for i, header := range files {
+		upload, fieldErr, err := prepareUpload(carID, kind, header)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		if fieldErr != nil {
+			fieldErr.Field = fmt.Sprintf("file[%d]", i)
+			v.Add(fieldErr.Field, fieldErr.Code, fieldErr.Params)
+			continue
+		}
+		uploads = append(uploads, upload)
+	}
+	if errs := v.Errors(); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Сначала файлы, потом записи: запись без файла была бы битой ссылкой
+	var stored []string
+	for _, upload := range uploads {
+		keys, err := h.store(ctx, upload)
+		stored = append(stored, keys...)
+		if err != nil {
+			log.Printf("Ошибка сохранения файла машины %d: %v", carID, err)
+			h.discard(stored)
+			problem.Error(w, r, http.StatusBadGateway, problem.CodeStorageUnavailable, "media.storage_failed")
+			return
+		}
+	}
+
+	items, err := insertMedia(ctx, conn, carID, uploads)
+	if err != nil {
+		h.discard(stored)
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "car.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(items)
+}
+
+func (h *MediaHandler) invalidForm(w http.ResponseWriter, r *http.Request) {
+	problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadRequest, "media.invalid_form").
+		With("max_files", maxMediaFiles))
+}
+
+// prepareUpload читает файл формы и проверяет его; ошибка в данных возвращается как FieldError
+func prepareUpload(carID int, kind string, header *multipart.FileHeader) (mediaUpload, *validation.FieldError, error) {
+	tooLarge := func(limit int64) *validation.FieldError {
+		return &validation.FieldError{Code: validation.CodeTooLarge, Params: map[string]interface{}{"max_mb": limit >> 20}}
+	}
+	if header.Size > media.MaxDocumentSize {
+		return mediaUpload{}, tooLarge(media.MaxDocumentSize), nil
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		return mediaUpload{}, nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, media.MaxDocumentSize+1))
+	if err != nil {
+		return mediaUpload{}, nil, err
+	}
+
+	contentType, err := media.Detect(data)
+	if err != nil {
+		return mediaUpload{}, &validation.FieldError{Code: validation.CodeUnsupportedType}, nil
+	}
+	resolved, err := media.ResolveKind(kind, contentType)
+	if err != nil {
+		return mediaUpload{}, &validation.FieldError{Code: validation.CodeNotAllowed}, nil
+	}
+	if limit := media.MaxSize(resolved); int64(len(data)) > limit {
+		return mediaUpload{}, tooLarge(limit), nil
+	}
+
+	upload := mediaUpload{
+		item: models.CarMedia{
+			CarID:       carID,
+			Kind:        resolved,
+			FileName:    media.CleanFileName(header.Filename),
+			ContentType: contentType,
+		},
+		data: data,
+	}
+
+	if media.IsImage(contentType) {
+		img, err := media.ProcessImage(data, contentType)
+		if errors.Is(err, media.ErrInvalidImage) {
+			return mediaUpload{}, &validation.FieldError{Code: validation.CodeInvalidValue}, nil
+		}
+		if err != nil {
+			return mediaUpload{}, nil, err
+		}
+		upload.data = img.Data
+		upload.thumbnail = img.Thumbnail
+		upload.item.Width = &img.Width
+		upload.item.Height = &img.Height
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return mediaUpload{}, nil, err
+	}
+	upload.item.Size = int64(len(upload.data))
+	upload.item.StorageKey = fmt.Sprintf("cars/%d/%s%s", carID, name, media.Extension(contentType))
+	if upload.thumbnail != nil {
+		key := fmt.Sprintf("cars/%d/%s_thumb.jpg", carID, name)
+		upload.item.ThumbnailKey = &key
+	}
+	return upload, nil, nil
+}
+
+// store сохраняет файл и превью; возвращает ключи уже сохранённых объектов, даже при ошибке
+func (h *MediaHandler) store(ctx context.Context, upload mediaUpload) ([]string, error) {
+	var keys []string
+	err := h.Storage.Put(ctx, upload.item.StorageKey, bytes.NewReader(upload.data),
+		int64(len(upload.data)), upload.item.ContentType)
+	if err != nil {
+		return keys, err
+	}
+	keys = append(keys, upload.item.StorageKey)
+
+	if upload.item.ThumbnailKey != nil {
+		err := h.Storage.Put(ctx, *upload.item.ThumbnailKey, bytes.NewReader(upload.thumbnail),
+			int64(len(upload.thumbnail)), media.TypeJPEG)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, *upload.item.ThumbnailKey)
+	}
+	return keys, nil
+}
+
+// discard удаляет файлы неудавшейся загрузки. Запрос клиента мог быть уже отменён,
+// поэтому используется собственный контекст.
+func (h *MediaHandler) discard(keys []string) {
+	for _, key := range keys {
+		if err := h.Storage.Delete(context.Background(), key); err != nil {
+			log.Printf("Ошибка удаления файла %s: %v", key, err)
+		}
+	}
+}
+
+// insertMedia сохраняет записи о файлах в одной транзакции; pgx.ErrNoRows — машину успели удалить
+func insertMedia(ctx context.Context, conn *pgxpool.Conn, carID int, uploads []mediaUpload) ([]models.CarMedia, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем машину, чтобы её не удалили, пока добавляются вложения
+	if err := tx.QueryRow(ctx, "SELECT id FROM cars WHERE id = $1 FOR SHARE", carID).Scan(&carID); err != nil {
+		return nil, err
+	}
+
+	items := make([]models.CarMedia, 0, len(uploads))
+	for _, upload := range uploads {
+		item := upload.item
+		err := scanMedia(tx.QueryRow(ctx,
+			`INSERT INTO car_media (car_id, kind, file_name, content_type, size, width, height, storage_key, thumbnail_key)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			 RETURNING `+mediaColumns,
+			item.CarID, item.Kind, item.FileName, item.ContentType, item.Size,
+			item.Width, item.Height, item.StorageKey, item.ThumbnailKey), &item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, tx.Commit(ctx)
+}
+
+// DownloadMedia отдаёт файл вложения или его превью
+func (h *MediaHandler) DownloadMedia(w http.ResponseWriter, r *http.Request, carID, mediaID int, thumbnail bool) {
+	ctx := r.Context()
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	var item models.CarMedia
+	err = scanMedia(conn.QueryRow(ctx,
+		"SELECT "+mediaColumns+" FROM car_media WHERE id = $1 AND car_id = $2", mediaID, carID), &item)
+	// Соединение не нужно на время передачи файла
+	conn.Release()
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "media.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	key, contentType, size := item.StorageKey, item.ContentType, strconv.FormatInt(item.Size, 10)
+	etag := fmt.Sprintf(`"media-%d"`, item.ID)
+	if thumbnail {
+		if item.ThumbnailKey == nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "media.not_found")
+			return
+		}
+		key, contentType, size = *item.ThumbnailKey, media.TypeJPEG, ""
+		etag = fmt.Sprintf(`"media-%d-thumb"`, item.ID)
+	}
+
+	// Файлы не меняются после загрузки, поэтому клиент может кешировать их сколько угодно
+	if etagMatches(r.Header.Get("If-None-Match"), etag, true) {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, err := h.Storage.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "media.not_found")
+			return
+		}
+		log.Printf("Ошибка чтения файла %s: %v", key, err)
+		problem.Error(w, r, http.StatusBadGateway, problem.CodeStorageUnavailable, "media.storage_failed")
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", contentType)
+	if size != "" {
+		w.Header().Set("Content-Length", size)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": item.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	w.Header().Set("ETag", etag)
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("Ошибка передачи файла %s: %v", key, err)
+	}
+}
+
+// DeleteMedia удаляет вложение; файлы удаляются из хранилища фоновой очисткой
+func (h *MediaHandler) DeleteMedia(w http.ResponseWriter, r *http.Request, carID, mediaID int) {
+	ctx := r.Context()
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, "DELETE FROM car_media WHERE id = $1 AND car_id = $2", mediaID, carID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "media.not_found")
+		return
+	}
+	if h.Cleaner != nil {
+		h.Cleaner.Notify()
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scanMedia читает колонки mediaColumns и заполняет адреса файлов
+func scanMedia(row pgx.Row, item *models.CarMedia) error {
+	err := row.Scan(&item.ID, &item.CarID, &item.Kind, &item.FileName, &item.ContentType, &item.Size,
+		&item.Width, &item.Height, &item.CreatedAt, &item.StorageKey, &item.ThumbnailKey)
+	if err != nil {
+		return err
+	}
+
+	item.URL = fmt.Sprintf("/api/cars/%d/media/%d", item.CarID, item.ID)
+	item.ThumbnailURL = nil
+	if item.ThumbnailKey != nil {
+		thumb := item.URL + "/thumbnail"
+		item.ThumbnailURL = &thumb
+	}
+	return nil
+}
+
+// randomName — случайное имя объекта; по нему нельзя угадать адреса чужих файлов
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/geocoding"
+	"CarDealership/problem"
+	"CarDealership/validation"
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Радиус поиска ближайших дилеров в километрах
+const (
+	defaultNearbyRadiusKM = 50
+	maxNearbyRadiusKM     = 1000
+)
+
+// earthRadiusKM — средний радиус Земли, тот же, что в функции haversine_km
+const earthRadiusKM = 6371
+
+// nearbyDealer — дилер и расстояние до него в километрах
+type nearbyDealer struct {
+	models.Dealer
+	DistanceKM float64 `json:"distance_km"`
+}
+
+// GetNearbyDealers возвращает дилеров в радиусе radius_km (по умолчанию 50 км) от точки lat, lon
+// (GET /api/dealers/nearby), от ближних к дальним. Расстояние считает SQL-функция haversine_km,
+// а индекс по координатам сужает поиск до ограничивающего прямоугольника. Дилеры без координат не попадают.
+func (h *DealersHandler) GetNearbyDealers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	q := r.URL.Query()
+	v := validation.New()
+	lat, hasLat := queryFloat(v, q, "lat")
+	lon, hasLon := queryFloat(v, q, "lon")
+	radius, hasRadius := queryFloat(v, q, "radius_km")
+	if !hasLat && q.Get("lat") == "" {
+		v.Add("lat", validation.CodeRequired, nil)
+	}
+	if !hasLon && q.Get("lon") == "" {
+		v.Add("lon", validation.CodeRequired, nil)
+	}
+	if !hasRadius {
+		radius = defaultNearbyRadiusKM
+	}
+	v.FloatRange("lat", lat, -90, 90)
+	v.FloatRange("lon", lon, -180, 180)
+	v.FloatRange("radius_km", radius, 0, maxNearbyRadiusKM)
+	if errs := v.Errors(); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Ограничивающий прямоугольник: по широте — всегда, по долготе — если он не заходит
+	// за полюс или линию перемены дат
+	args := []interface{}{lat, lon, radius}
+	latDelta := radius / earthRadiusKM * 180 / math.Pi
+	args = append(args, lat-latDelta, lat+latDelta)
+	box := "latitude BETWEEN $4 AND $5"
+	if math.Abs(lat)+latDelta < 90 {
+		lonDelta := math.Asin(math.Sin(radius/earthRadiusKM)/math.Cos(lat*math.Pi/180)) * 180 / math.Pi
+		if lon-lonDelta >= -180 && lon+lonDelta <= 180 {
+			args = append(args, lon-lonDelta, lon+lonDelta)
+			box += " AND longitude BETWEEN $6 AND $7"
+		}
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx,
+		`SELECT `+dealerFields+`, distance_km FROM (
+			SELECT `+dealerFields+`, haversine_km($1, $2, latitude, longitude) AS distance_km
+			FROM dealers WHERE latitude IS NOT NULL AND `+box+`
+		) AS d
+		WHERE distance_km <= $3
+		ORDER BY distance_km, id`, args...)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	dealers := []nearbyDealer{}
+	for rows.Next() {
+		var dealer nearbyDealer
+		if err := scanDealer(rows, &dealer.Dealer, &dealer.DistanceKM); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		dealer.DistanceKM = math.Round(dealer.DistanceKM*100) / 100
+		dealers = append(dealers, dealer)
+	}
+	if err := rows.Err(); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", dealers)
+}
+
+// queryFloat читает дробный query-параметр; ok = false, если параметра нет или он не число
+// (во втором случае добавляется ошибка валидации)
+func queryFloat(v *validation.Validator, q url.Values, name string) (float64, bool) {
+	value := q.Get(name)
+	if value == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		v.Add(name, validation.CodeInvalidValue, nil)
+		return 0, false
+	}
+	return f, true
+}
+
+// dropStaleCoordinates стирает координаты дилера, если город или адрес изменились по сравнению
+// с current, а координаты остались прежними: они относятся к старому адресу, и locateDealer
+// найдёт новые. Без геокодера координаты не трогаются.
+func (h *DealersHandler) dropStaleCoordinates(current models.Dealer, dealer *models.Dealer) {
+	if h.Geocoder == nil {
+		return
+	}
+	moved := changedColumns(current, *dealer, []string{"city", "address", "latitude", "longitude"})
+	_, cityChanged := moved["city"]
+	_, addressChanged := moved["address"]
+	_, latChanged := moved["latitude"]
+	_, lonChanged := moved["longitude"]
+	if (cityChanged || addressChanged) && !latChanged && !lonChanged {
+		dealer.Latitude, dealer.Longitude = nil, nil
+	}
+}
+
+// locateDealer заполняет координаты дилера по городу и адресу, если их не передали и геокодер настроен.
+// Ненайденный адрес или сбой геокодера не мешают сохранению: дилер остаётся без координат
+func (h *DealersHandler) locateDealer(ctx context.Context, dealer *models.Dealer) {
+	if h.Geocoder == nil || dealer.Latitude != nil || dealer.Longitude != nil {
+		return
+	}
+	point, err := h.Geocoder.Geocode(ctx, geocoding.Query{City: dealer.City, Address: dealer.Address})
+	if err != nil {
+		if !errors.Is(err, geocoding.ErrNotFound) {
+			log.Printf("Ошибка геокодирования адреса %q, %q: %v", dealer.City, dealer.Address, err)
+		}
+		return
+	}
+	dealer.Latitude, dealer.Longitude = &point.Latitude, &point.Longitude
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"CarDealership/jsonpatch"
+	"CarDealership/problem"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Медиатипы, которые принимают PATCH-эндпоинты
+const (
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// maxPatchBody ограничивает размер тела PATCH-запроса
+const maxPatchBody = 1 << 20
+
+// applyPatch применяет тело PATCH-запроса к текущему состоянию ресурса и
+// раскладывает результат в target. Формат патча определяется по Content-Type:
+// JSON Patch (RFC 6902) или JSON Merge Patch (RFC 7396, также для application/json).
+func applyPatch(r *http.Request, current, target interface{}) *problem.Problem {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	if mediaType != mediaTypeMergePatch && mediaType != mediaTypeJSONPatch && mediaType != "application/json" {
+		return problem.New(http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "error.unsupported_patch_type")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPatchBody))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, problem.CodeInvalidPatch, "error.invalid_patch")
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, problem.CodeInternal, "error.internal")
+	}
+
+	var patched []byte
+	if mediaType == mediaTypeJSONPatch {
+		patched, err = jsonpatch.Apply(doc, body)
+	} else {
+		patched, err = jsonpatch.MergePatch(doc, body)
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return problem.New(http.StatusConflict, problem.CodePatchTestFailed, "error.patch_test_failed")
+	}
+	if err != nil {
+		return problem.New(http.StatusBadRequest, problem.CodeInvalidPatch, "error.invalid_patch")
+	}
+
+	// Результат патча должен по-прежнему соответствовать модели
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(target); err != nil {
+		return problem.New(http.StatusUnprocessableEntity, problem.CodeInvalidPatch, "error.invalid_patch_result")
+	}
+
+	return nil
+}
+
+// columnValues возвращает значения полей структуры по их json-тегам.
+// Теги моделей совпадают с именами колонок в базе.
+func columnValues(v interface{}) map[string]interface{} {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+
+	values := make(map[string]interface{}, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		values[name] = rv.Field(i).Interface()
+	}
+	return values
+}
+
+// changedColumns возвращает колонки из allowed, значения которых различаются в before и after
+func changedColumns(before, after interface{}, allowed []string) map[string]interface{} {
+	old := columnValues(before)
+	updated := columnValues(after)
+
+	changes := make(map[string]interface{})
+	for _, column := range allowed {
+		if !reflect.DeepEqual(old[column], updated[column]) {
+			changes[column] = updated[column]
+		}
+	}
+	return changes
+}
+
+// updateColumns обновляет в строке таблицы только переданные колонки и возвращает новую версию строки.
+// Имена таблицы и колонок берутся из кода, а не из запроса.
+func updateColumns(ctx context.Context, tx pgx.Tx, table string, id int, changes map[string]interface{}) (int, error) {
+	columns := make([]string, 0, len(changes))
+	for column := range changes {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	set := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns)+1)
+	for i, column := range columns {
+		set = append(set, fmt.Sprintf("%s = $%d", column, i+1))
+		args = append(args, changes[column])
+	}
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d RETURNING version", table, strings.Join(set, ", "), len(args))
+
+	var version int
+	err := tx.QueryRow(ctx, query, args...).Scan(&version)
+	return version, err
+}
@@ -0,0 +1,592 @@
+package handlers
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/messaging"
+	"CarDealership/problem"
+	"CarDealership/validation"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReviewsHandler — отзывы покупателей о дилерах и их модерация
+type ReviewsHandler struct {
+	DB     *pgxpool.Pool
+	Rabbit *messaging.RabbitMQ
+}
+
+func NewReviewsHandler(db *pgxpool.Pool) *ReviewsHandler {
+	return &ReviewsHandler{DB: db}
+}
+
+// reviewFields — колонки для чтения отзыва в том порядке, в котором их ожидает scanReview
+const reviewFields = "id, dealer_id, customer_id, score, text, status, created_at, moderated_at"
+
+// Размер страницы списков отзывов
+const (
+	reviewsDefaultLimit = 20
+	reviewsMaxLimit     = 100
+)
+
+// reviewAction — смена статуса модерации; event — тип события в RabbitMQ
+type reviewAction struct {
+	to    string
+	event string
+}
+
+var reviewActions = map[string]reviewAction{
+	"approve": {models.ReviewApproved, "APPROVE"},
+	"reject":  {models.ReviewRejected, "REJECT"},
+}
+
+// reviewPage — страница одобренных отзывов дилера; Total — число всех одобренных отзывов
+type reviewPage struct {
+	DealerID int             `json:"dealer_id"`
+	Rating   float64         `json:"rating"`
+	Total    int             `json:"total"`
+	Limit    int             `json:"limit"`
+	Offset   int             `json:"offset"`
+	Reviews  []models.Review `json:"reviews"`
+}
+
+// scanReview читает колонки reviewFields, а за ними — extra
+func scanReview(row pgx.Row, review *models.Review, extra ...interface{}) error {
+	dest := []interface{}{&review.ID, &review.DealerID, &review.CustomerID, &review.Score, &review.Text,
+		&review.Status, &review.CreatedAt, &review.ModeratedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// ReviewAction возвращает действие из пути /api/reviews/{id}/{действие}
+// либо пустую строку, если путь — сам отзыв
+func ReviewAction(path string) string {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) != 5 {
+		return ""
+	}
+	if _, ok := reviewActions[parts[4]]; !ok {
+		return ""
+	}
+	return parts[4]
+}
+
+// pageParams читает limit (по умолчанию reviewsDefaultLimit, не больше reviewsMaxLimit) и offset
+func pageParams(v *validation.Validator, q url.Values) (int, int) {
+	limit, offset := reviewsDefaultLimit, 0
+	if value := q.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			v.Add("limit", validation.CodeInvalidValue, nil)
+		} else {
+			limit = n
+			v.IntRange("limit", n, 1, reviewsMaxLimit)
+		}
+	}
+	if value := q.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			v.Add("offset", validation.CodeInvalidValue, nil)
+		} else {
+			offset = n
+		}
+	}
+	return limit, offset
+}
+
+// reviewFilter строит условия отбора отзывов по query-параметрам: dealer_id, customer_id и status
+func reviewFilter(q url.Values, v *validation.Validator) ([]string, []interface{}) {
+	var conds []string
+	var args []interface{}
+
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	for _, param := range []string{"dealer_id", "customer_id"} {
+		value := q.Get(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			v.Add(param, validation.CodeInvalidValue, nil)
+			continue
+		}
+		add(param+" = $%d", id)
+	}
+
+	if status := q.Get("status"); status != "" {
+		v.OneOf("status", status, validation.ReviewStatuses)
+		add("status = $%d", status)
+	}
+
+	return conds, args
+}
+
+// queryReviews возвращает отзывы по условиям, от новых к старым
+func queryReviews(ctx context.Context, q queryer, conds []string, args []interface{}, limit, offset int) ([]models.Review, error) {
+	query := "SELECT " + reviewFields + " FROM reviews"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, limit, offset)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	for rows.Next() {
+		var review models.Review
+		if err := scanReview(rows, &review); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
+
+// updateDealerRating пересчитывает рейтинг дилера по одобренным отзывам (см. models.ReviewPriorWeight)
+// и возвращает его. Априорное среднее — rating_prior; при первом пересчёте в него сохраняется
+// текущий рейтинг, поэтому рейтинг, заданный до появления отзывов, не теряется.
+// Дилер должен быть заблокирован транзакцией, иначе параллельная модерация
+// другого отзыва может сохранить рейтинг, посчитанный без этого изменения.
+func updateDealerRating(ctx context.Context, tx pgx.Tx, dealerID int) (float64, error) {
+	var rating, prior float64
+	err := tx.QueryRow(ctx,
+		`SELECT ROUND((COALESCE(d.rating_prior, d.rating) * $2::int + COALESCE(SUM(r.score), 0))
+		              / ($2::int + COUNT(r.id)), 1)::float8,
+		        COALESCE(d.rating_prior, d.rating)::float8
+		 FROM dealers d LEFT JOIN reviews r ON r.dealer_id = d.id AND r.status = 'approved'
+		 WHERE d.id = $1
+		 GROUP BY d.id`,
+		dealerID, models.ReviewPriorWeight).Scan(&rating, &prior)
+	if err != nil {
+		return 0, err
+	}
+
+	// Версию дилера меняем, только если рейтинг действительно изменился или впервые сохраняется rating_prior
+	_, err = tx.Exec(ctx,
+		`UPDATE dealers SET rating = $1, rating_prior = $3
+		 WHERE id = $2 AND (rating <> $1 OR rating_prior IS NULL)`,
+		rating, dealerID, prior)
+	return rating, err
+}
+
+// lockReview блокирует дилера отзыва, а затем сам отзыв. Порядок тот же, что при удалении дилера
+// (дилер, потом его отзывы), поэтому взаимных блокировок не возникает.
+func lockReview(ctx context.Context, tx pgx.Tx, id int) (models.Review, int, error) {
+	var review models.Review
+	var version int
+
+	var dealerID int
+	if err := tx.QueryRow(ctx, "SELECT dealer_id FROM reviews WHERE id = $1", id).Scan(&dealerID); err != nil {
+		return review, 0, err
+	}
+	if _, err := tx.Exec(ctx, "SELECT 1 FROM dealers WHERE id = $1 FOR UPDATE", dealerID); err != nil {
+		return review, 0, err
+	}
+
+	err := scanReview(tx.QueryRow(ctx, "SELECT "+reviewFields+", version FROM reviews WHERE id = $1 FOR UPDATE", id),
+		&review, &version)
+	return review, version, err
+}
+
+// GetAllReviews возвращает отзывы для модерации, от новых к старым: фильтры dealer_id, customer_id
+// и status (например, status=pending — очередь модерации), страница — limit и offset
+func (h *ReviewsHandler) GetAllReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	q := r.URL.Query()
+	v := validation.New()
+	conds, args := reviewFilter(q, v)
+	limit, offset := pageParams(v, q)
+	if errs := v.Errors(); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	reviews, err := queryReviews(ctx, conn, conds, args, limit, offset)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", reviews)
+}
+
+// GetDealerReviews возвращает одобренные отзывы дилера (GET /api/dealers/{id}/reviews), от новых к старым,
+// вместе с рейтингом и общим числом одобренных отзывов. Страница — limit и offset.
+func (h *ReviewsHandler) GetDealerReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	v := validation.New()
+	limit, offset := pageParams(v, r.URL.Query())
+	if errs := v.Errors(); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	// Рейтинг, число отзывов и страница читаются из одного снимка базы
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	page := reviewPage{DealerID: id, Limit: limit, Offset: offset}
+	err = tx.QueryRow(ctx,
+		`SELECT rating, (SELECT COUNT(*) FROM reviews WHERE dealer_id = dealers.id AND status = 'approved')
+		 FROM dealers WHERE id = $1`, id).Scan(&page.Rating, &page.Total)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "dealer.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	page.Reviews, err = queryReviews(ctx, tx, []string{"dealer_id = $1", "status = $2"},
+		[]interface{}{id, models.ReviewApproved}, limit, offset)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", page)
+}
+
+// GetReviewByID возвращает отзыв по ID в любом статусе
+func (h *ReviewsHandler) GetReviewByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var review models.Review
+	var version int
+	err = scanReview(conn.QueryRow(ctx, "SELECT "+reviewFields+", version FROM reviews WHERE id = $1", id),
+		&review, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "review.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, versionETag(version), review)
+}
+
+// CreateDealerReview добавляет отзыв покупателя о дилере (POST /api/dealers/{id}/reviews).
+// Отзыв ждёт модерации и в рейтинг пока не входит; второй отзыв того же покупателя отклоняется с 409.
+func (h *ReviewsHandler) CreateDealerReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	dealerID, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Парсим JSON из тела запроса
+	var review models.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	// Валидация полей
+	review.Text = strings.TrimSpace(review.Text)
+	if errs := validation.ValidateReview(review); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var rating float64
+	err = tx.QueryRow(ctx, "SELECT rating FROM dealers WHERE id = $1 FOR SHARE", dealerID).Scan(&rating)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "dealer.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	ok, err = customerExists(ctx, tx, review.CustomerID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !ok {
+		problem.Validation(w, r, validation.Errors{{Field: "customer_id", Code: validation.CodeNotFound}})
+		return
+	}
+
+	// Индекс reviews_dealer_customer_key страхует от гонки, а здесь клиент получает номер прежнего отзыва
+	var existingID int
+	err = tx.QueryRow(ctx, "SELECT id FROM reviews WHERE dealer_id = $1 AND customer_id = $2",
+		dealerID, review.CustomerID).Scan(&existingID)
+	if err == nil {
+		problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeConflict, "review.already_exists").
+			With("review_id", existingID))
+		return
+	}
+	if err != pgx.ErrNoRows {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	var version int
+	err = scanReview(tx.QueryRow(ctx,
+		`INSERT INTO reviews (dealer_id, customer_id, score, text)
+		 VALUES ($1, $2, $3, $4) RETURNING `+reviewFields+", version",
+		dealerID, review.CustomerID, review.Score, review.Text), &review, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.ReviewEvent{
+			EventType: "CREATE",
+			Review:    review,
+			Rating:    rating,
+		})
+	}
+}
+
+// ModerateReview одобряет или отклоняет отзыв (POST /api/reviews/{id}/approve, /reject)
+// и в той же транзакции пересчитывает рейтинг дилера
+func (h *ReviewsHandler) ModerateReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	action := reviewActions[ReviewAction(r.URL.Path)]
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	review, version, err := lockReview(ctx, tx, id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "review.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Проверяем, что модератор видел актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	if !models.CanReviewTransition(review.Status, action.to) {
+		problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeInvalidTransition, "review.invalid_status_transition").
+			With("from", review.Status).With("to", action.to))
+		return
+	}
+
+	err = scanReview(tx.QueryRow(ctx,
+		"UPDATE reviews SET status = $1, moderated_at = now() WHERE id = $2 RETURNING "+reviewFields+", version",
+		action.to, id), &review, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	rating, err := updateDealerRating(ctx, tx, review.DealerID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	json.NewEncoder(w).Encode(review)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.ReviewEvent{
+			EventType: action.event,
+			Review:    review,
+			Rating:    rating,
+		})
+	}
+}
+
+// DeleteReview удаляет отзыв (DELETE) и в той же транзакции пересчитывает рейтинг дилера
+func (h *ReviewsHandler) DeleteReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodDelete {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	review, version, err := lockReview(ctx, tx, id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "review.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Проверяем, что клиент удаляет актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM reviews WHERE id = $1", id); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	rating, err := updateDealerRating(ctx, tx, review.DealerID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Возвращаем успешный ответ без тела
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.ReviewEvent{
+			EventType: "DELETE",
+			Review:    review,
+			Rating:    rating,
+		})
+	}
+}
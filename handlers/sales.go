@@ -0,0 +1,643 @@
+package handlers
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/messaging"
+	"CarDealership/problem"
+	"CarDealership/validation"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SalesHandler — продажи автомобилей и журнал продаж дилера
+type SalesHandler struct {
+	DB     *pgxpool.Pool
+	Rabbit *messaging.RabbitMQ
+}
+
+func NewSalesHandler(db *pgxpool.Pool) *SalesHandler {
+	return &SalesHandler{DB: db}
+}
+
+// saleFields — колонки для чтения продажи в том порядке, в котором их ожидает scanSale.
+// Имена с таблицей, чтобы их можно было использовать в запросах с JOIN.
+const saleFields = "sales.id, sales.car_id, sales.dealer_id, sales.customer_id, sales.price, sales.discount, " +
+	"sales.payment_method, sales.sold_at"
+
+// returnEvent — тип события машины, возвращённой в наличие отменой продажи
+const returnEvent = "RETURN"
+
+// scanSale читает колонки saleFields, а за ними — extra
+func scanSale(row pgx.Row, sale *models.Sale, extra ...interface{}) error {
+	dest := []interface{}{&sale.ID, &sale.CarID, &sale.DealerID, &sale.CustomerID, &sale.Price, &sale.Discount,
+		&sale.PaymentMethod, &sale.SoldAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// ledgerEntry — строка журнала продаж дилера: продажа, машина и покупатель
+type ledgerEntry struct {
+	models.Sale
+	Firm         string  `json:"firm"`
+	Model        string  `json:"model"`
+	VIN          *string `json:"vin"`
+	CustomerName string  `json:"customer_name"`
+}
+
+// dealerLedger — журнал продаж дилера за период с итогами
+type dealerLedger struct {
+	DealerID  int           `json:"dealer_id"`
+	From      string        `json:"from,omitempty"`
+	To        string        `json:"to,omitempty"`
+	Count     int           `json:"count"`
+	Revenue   int64         `json:"revenue"`
+	Discounts int64         `json:"discounts"`
+	Sales     []ledgerEntry `json:"sales"`
+}
+
+// dateLayout — формат дат в параметрах from и to
+const dateLayout = "2006-01-02"
+
+// saleFilter строит условия отбора продаж по query-параметрам: dealer_id, customer_id
+// и период from/to — даты в формате ГГГГ-ММ-ДД, обе включительно
+func saleFilter(q url.Values) ([]string, []interface{}, validation.Errors) {
+	var conds []string
+	var args []interface{}
+	v := validation.New()
+
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	for _, param := range []string{"dealer_id", "customer_id"} {
+		value := q.Get(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			v.Add(param, validation.CodeInvalidValue, nil)
+			continue
+		}
+		add("sales."+param+" = $%d", id)
+	}
+
+	if value := q.Get("from"); value != "" {
+		from, err := time.Parse(dateLayout, value)
+		if err != nil {
+			v.Add("from", validation.CodeInvalidValue, nil)
+		} else {
+			add("sales.sold_at >= $%d", from)
+		}
+	}
+	if value := q.Get("to"); value != "" {
+		to, err := time.Parse(dateLayout, value)
+		if err != nil {
+			v.Add("to", validation.CodeInvalidValue, nil)
+		} else {
+			add("sales.sold_at < $%d", to.AddDate(0, 0, 1))
+		}
+	}
+
+	if errs := v.Errors(); errs != nil {
+		return nil, nil, errs
+	}
+	return conds, args, nil
+}
+
+// GetAllSales возвращает продажи, от новых к старым, отфильтрованные по query-параметрам (см. saleFilter)
+func (h *SalesHandler) GetAllSales(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	conds, args, errs := saleFilter(r.URL.Query())
+	if errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+	query := "SELECT " + saleFields + " FROM sales"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY sales.sold_at DESC, sales.id DESC"
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, query, args...)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	sales := []models.Sale{}
+	for rows.Next() {
+		var sale models.Sale
+		if err := scanSale(rows, &sale); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		sales = append(sales, sale)
+	}
+	if err := rows.Err(); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", sales)
+}
+
+// GetSaleByID возвращает продажу по ID
+func (h *SalesHandler) GetSaleByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var sale models.Sale
+	var version int
+	err = scanSale(conn.QueryRow(ctx, "SELECT "+saleFields+", version FROM sales WHERE id = $1", id), &sale, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "sale.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, versionETag(version), sale)
+}
+
+// CreateSale записывает продажу (POST) и в той же транзакции переводит машину в статус sold.
+// Продать можно машину из наличия или забронированную; машину, проданную через /sell без записи о продаже,
+// можно дооформить. Если dealer_id не указан, продажа записывается на дилера машины.
+func (h *SalesHandler) CreateSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	// Парсим JSON из тела запроса
+	var sale models.Sale
+	if err := json.NewDecoder(r.Body).Decode(&sale); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	// Валидация полей
+	validation.NormalizeSale(&sale)
+	if errs := validation.ValidateSale(sale); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем машину: её статус меняется в этой же транзакции
+	var car models.Car
+	var expired bool
+	err = scanCar(tx.QueryRow(ctx,
+		"SELECT "+carFields+", COALESCE(reserved_until <= now(), false) FROM cars WHERE id = $1 FOR UPDATE", sale.CarID),
+		&car, &expired)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Validation(w, r, validation.Errors{{Field: "car_id", Code: validation.CodeNotFound}})
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Продажа записывается на дилера машины
+	switch {
+	case sale.DealerID == 0 && car.DealerID == nil:
+		problem.Validation(w, r, validation.Errors{{Field: "dealer_id", Code: validation.CodeRequired}})
+		return
+	case sale.DealerID == 0:
+		sale.DealerID = *car.DealerID
+	case car.DealerID != nil && *car.DealerID != sale.DealerID:
+		problem.Validation(w, r, validation.Errors{{Field: "dealer_id", Code: validation.CodeInvalidValue}})
+		return
+	}
+
+	// Проверяем, что дилер и покупатель существуют
+	ok, err := dealerExists(ctx, tx, &sale.DealerID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !ok {
+		problem.Validation(w, r, validation.Errors{{Field: "dealer_id", Code: validation.CodeNotFound}})
+		return
+	}
+	ok, err = customerExists(ctx, tx, sale.CustomerID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !ok {
+		problem.Validation(w, r, validation.Errors{{Field: "customer_id", Code: validation.CodeNotFound}})
+		return
+	}
+
+	// Статус меняется по тем же правилам, что и через /sell, но машину из наличия можно продать
+	// сразу: запись о продаже появляется в той же транзакции. Истёкшая бронь считается снятой
+	from := car.Status
+	if expired {
+		from = models.StatusAvailable
+	}
+	sold := from != models.StatusSold
+	if sold && from != models.StatusAvailable && !models.CanTransition(from, models.StatusSold) {
+		problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeInvalidTransition, "car.invalid_status_transition").
+			With("from", from).With("to", models.StatusSold))
+		return
+	}
+
+	// У машины может быть только одна продажа — повторную запись отклонит уникальный индекс.
+	// Продажа записывается до смены статуса: без неё триггер не пропустит переход available → sold
+	var version int
+	err = scanSale(tx.QueryRow(ctx,
+		`INSERT INTO sales (car_id, dealer_id, customer_id, price, discount, payment_method, sold_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, now()))
+		 RETURNING `+saleFields+", version",
+		sale.CarID, sale.DealerID, sale.CustomerID, sale.Price, sale.Discount, sale.PaymentMethod, optionalTime(sale.SoldAt)),
+		&sale, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if sold {
+		err = scanCar(tx.QueryRow(ctx,
+			"UPDATE cars SET status = 'sold', reserved_until = NULL WHERE id = $1 RETURNING "+carFields, car.ID), &car)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sale)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.SaleEvent{
+			EventType: "CREATE",
+			Sale:      sale,
+		})
+		if sold {
+			h.Rabbit.PublishEvent(messaging.CarEvent{
+				EventType: statusActions["sell"].event,
+				Car:       car,
+			})
+		}
+	}
+}
+
+// UpdateSale исправляет продажу (PUT): покупателя, цену, скидку, способ оплаты и дату.
+// Машину и дилера изменить нельзя — для этого продажу отменяют и записывают заново.
+func (h *SalesHandler) UpdateSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPut {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Парсим JSON из тела запроса
+	var sale models.Sale
+	if err := json.NewDecoder(r.Body).Decode(&sale); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Проверяем существует ли продажа и блокируем её до конца транзакции
+	var current models.Sale
+	var version int
+	err = scanSale(tx.QueryRow(ctx, "SELECT "+saleFields+", version FROM sales WHERE id = $1 FOR UPDATE", id),
+		&current, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "sale.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Проверяем, что клиент редактировал актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	// Машина и дилер — только для чтения; пустое значение означает «не менять»
+	v := validation.New()
+	if sale.CarID != 0 && sale.CarID != current.CarID {
+		v.Add("car_id", validation.CodeReadOnly, nil)
+	}
+	if sale.DealerID != 0 && sale.DealerID != current.DealerID {
+		v.Add("dealer_id", validation.CodeReadOnly, nil)
+	}
+	if errs := v.Errors(); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+	sale.ID, sale.CarID, sale.DealerID = id, current.CarID, current.DealerID
+	if sale.SoldAt.IsZero() {
+		sale.SoldAt = current.SoldAt
+	}
+
+	// Валидация полей
+	validation.NormalizeSale(&sale)
+	if errs := validation.ValidateSale(sale); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	if sale.CustomerID != current.CustomerID {
+		found, err := customerExists(ctx, tx, sale.CustomerID)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		if !found {
+			problem.Validation(w, r, validation.Errors{{Field: "customer_id", Code: validation.CodeNotFound}})
+			return
+		}
+	}
+
+	// Обновляем запись; триггер увеличивает версию
+	err = scanSale(tx.QueryRow(ctx,
+		`UPDATE sales SET customer_id = $1, price = $2, discount = $3, payment_method = $4, sold_at = $5
+		 WHERE id = $6 RETURNING `+saleFields+", version",
+		sale.CustomerID, sale.Price, sale.Discount, sale.PaymentMethod, sale.SoldAt, id),
+		&sale, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	json.NewEncoder(w).Encode(sale)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.SaleEvent{
+			EventType: "UPDATE",
+			Sale:      sale,
+		})
+	}
+}
+
+// DeleteSale отменяет продажу (DELETE): запись удаляется, машина возвращается в наличие
+func (h *SalesHandler) DeleteSale(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodDelete {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Сначала получаем данные продажи для RabbitMQ и блокируем строку
+	var sale models.Sale
+	var version int
+	err = scanSale(tx.QueryRow(ctx, "SELECT "+saleFields+", version FROM sales WHERE id = $1 FOR UPDATE", id),
+		&sale, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "sale.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Проверяем, что клиент удаляет актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM sales WHERE id = $1", id); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Триггер разрешает переход sold → available только машине без записи о продаже
+	cars, err := collectCars(tx.Query(ctx,
+		"UPDATE cars SET status = 'available' WHERE id = $1 AND status = 'sold' RETURNING "+carFields, sale.CarID))
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Возвращаем успешный ответ без тела
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusNoContent)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.SaleEvent{
+			EventType: "DELETE",
+			Sale:      sale,
+		})
+		for _, car := range cars {
+			h.Rabbit.PublishEvent(messaging.CarEvent{
+				EventType: returnEvent,
+				Car:       car,
+			})
+		}
+	}
+}
+
+// GetDealerSales возвращает журнал продаж дилера (GET /api/dealers/{id}/sales) за период from/to
+// с числом продаж, выручкой и суммой скидок
+func (h *SalesHandler) GetDealerSales(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Дилер берётся из пути, поэтому dealer_id в параметрах не учитывается
+	q := r.URL.Query()
+	q.Del("dealer_id")
+	conds, args, errs := saleFilter(q)
+	if errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+	args = append(args, id)
+	conds = append(conds, fmt.Sprintf("sales.dealer_id = $%d", len(args)))
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM dealers WHERE id = $1)", id).Scan(&exists); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !exists {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "dealer.not_found")
+		return
+	}
+
+	rows, err := conn.Query(ctx,
+		`SELECT `+saleFields+`, cars.firm, cars.model, cars.vin, customers.name
+		 FROM sales
+		 JOIN cars ON cars.id = sales.car_id
+		 JOIN customers ON customers.id = sales.customer_id
+		 WHERE `+strings.Join(conds, " AND ")+`
+		 ORDER BY sales.sold_at, sales.id`, args...)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	ledger := dealerLedger{DealerID: id, From: q.Get("from"), To: q.Get("to"), Sales: []ledgerEntry{}}
+	for rows.Next() {
+		var entry ledgerEntry
+		if err := scanSale(rows, &entry.Sale, &entry.Firm, &entry.Model, &entry.VIN, &entry.CustomerName); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		ledger.Count++
+		ledger.Revenue += int64(entry.Price)
+		ledger.Discounts += int64(entry.Discount)
+		ledger.Sales = append(ledger.Sales, entry)
+	}
+	if err := rows.Err(); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", ledger)
+}
+
+// customerExists проверяет, что покупатель существует, и блокирует его до конца транзакции,
+// чтобы его нельзя было удалить параллельно
+func customerExists(ctx context.Context, tx pgx.Tx, customerID int) (bool, error) {
+	var id int
+	err := tx.QueryRow(ctx, "SELECT id FROM customers WHERE id = $1 FOR SHARE", customerID).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// optionalTime возвращает nil для нулевого времени, чтобы база подставила значение по умолчанию
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/problem"
+	"CarDealership/search"
+	"CarDealership/validation"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SearchHandler struct {
+	DB *pgxpool.Pool
+}
+
+func NewSearchHandler(db *pgxpool.Pool) *SearchHandler {
+	return &SearchHandler{DB: db}
+}
+
+// Ограничения поиска
+const (
+	searchMinLength    = 2
+	searchMaxLength    = 200
+	searchDefaultLimit = 20
+	searchMaxLimit     = 100
+)
+
+// searchResult — найденная запись. Highlights содержит экранированные для HTML значения полей,
+// в которых совпавшие слова обёрнуты в <mark>; поля без совпадений не включаются.
+type searchResult struct {
+	Type       string            `json:"type"`
+	ID         int               `json:"id"`
+	Title      string            `json:"title"`
+	Subtitle   string            `json:"subtitle"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+	Car        *models.Car       `json:"car,omitempty"`
+	Dealer     *models.Dealer    `json:"dealer,omitempty"`
+}
+
+type searchResponse struct {
+	Query   string         `json:"query"`
+	Results []searchResult `json:"results"`
+}
+
+// Search ищет автомобили и дилеров: GET /api/search?q=...&type=car|dealer&limit=N.
+// Запрос сопоставляется полнотекстово (по префиксам слов) и по триграммам pg_trgm, поэтому
+// находит записи с опечатками («Toyta Camri»), в транслитерации («Тойота», «Minsk»)
+// и со смешанной кириллицей и латиницей. Результаты обоих типов упорядочены по убыванию score.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	query := strings.TrimSpace(q.Get("q"))
+	v := validation.New()
+	v.Required("q", query).MaxLength("q", query, searchMaxLength)
+	if n := len([]rune(query)); n > 0 && n < searchMinLength {
+		v.Add("q", validation.CodeOutOfRange, map[string]interface{}{"min": searchMinLength, "max": searchMaxLength})
+	}
+
+	kind := q.Get("type")
+	if kind != "" && kind != "car" && kind != "dealer" {
+		v.Add("type", validation.CodeNotAllowed, nil)
+	}
+
+	limit := searchDefaultLimit
+	if value := q.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			v.Add("limit", validation.CodeInvalidValue, nil)
+		} else {
+			limit = n
+			v.IntRange("limit", n, 1, searchMaxLimit)
+		}
+	}
+
+	variants := search.Variants(query)
+	if len(variants) == 0 && query != "" {
+		v.Add("q", validation.CodeInvalidValue, nil)
+	}
+
+	if errs := v.Errors(); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	// Порог сходства задаётся только на время транзакции
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)",
+		strconv.FormatFloat(search.MatchThreshold, 'f', -1, 64)); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	results := []searchResult{}
+	if kind == "" || kind == "car" {
+		cars, err := searchCars(ctx, tx, variants, limit)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		results = append(results, cars...)
+	}
+	if kind == "" || kind == "dealer" {
+		dealers, err := searchDealers(ctx, tx, variants, limit)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		results = append(results, dealers...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	writeJSONWithETag(w, r, "", searchResponse{Query: query, Results: results})
+}
+
+// searchCondition возвращает условие отбора и выражение для score по колонкам search_vector и search_text.
+// $1 — tsquery, следующие параметры — варианты запроса для сравнения по триграммам.
+func searchCondition(variants [][]string) (string, string, []interface{}) {
+	args := []interface{}{search.TSQuery(variants)}
+	conds := []string{"search_vector @@ to_tsquery('simple', $1)"}
+	sims := make([]string, 0, len(variants))
+	for _, words := range variants {
+		args = append(args, strings.Join(words, " "))
+		n := len(args)
+		conds = append(conds, fmt.Sprintf("$%d <%% search_text", n))
+		sims = append(sims, fmt.Sprintf("word_similarity($%d, search_text)", n))
+	}
+
+	// Полнотекстовое совпадение весит больше нечёткого: точные слова должны быть выше опечаток
+	score := "(ts_rank(search_vector, to_tsquery('simple', $1)) * 2 + GREATEST(" + strings.Join(sims, ", ") + "))::float8"
+	return "(" + strings.Join(conds, " OR ") + ")", score, args
+}
+
+// searchCars ищет среди непроданных машин
+func searchCars(ctx context.Context, tx pgx.Tx, variants [][]string, limit int) ([]searchResult, error) {
+	where, score, args := searchCondition(variants)
+	args = append(args, limit)
+	rows, err := tx.Query(ctx, fmt.Sprintf(
+		`SELECT %s, %s AS score
+		 FROM cars WHERE status <> 'sold' AND %s ORDER BY score DESC, id LIMIT $%d`, carFields, score, where, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []searchResult
+	for rows.Next() {
+		var car models.Car
+		var score float64
+		if err := scanCar(rows, &car, &score); err != nil {
+			return nil, err
+		}
+		results = append(results, searchResult{
+			Type:       "car",
+			ID:         car.ID,
+			Title:      fmt.Sprintf("%s %s", car.Firm, car.Model),
+			Subtitle:   fmt.Sprintf("%d, %s, %d л.с., %d", car.Year, car.Color, car.Power, car.Price),
+			Score:      score,
+			Highlights: highlights(variants, map[string]string{"firm": car.Firm, "model": car.Model, "color": car.Color}),
+			Car:        &car,
+		})
+	}
+	return results, rows.Err()
+}
+
+func searchDealers(ctx context.Context, tx pgx.Tx, variants [][]string, limit int) ([]searchResult, error) {
+	where, score, args := searchCondition(variants)
+	args = append(args, limit)
+	rows, err := tx.Query(ctx, fmt.Sprintf(
+		`SELECT id, name, city, address, area, rating, %s AS score
+		 FROM dealers WHERE %s ORDER BY score DESC, id LIMIT $%d`, score, where, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []searchResult
+	for rows.Next() {
+		var dealer models.Dealer
+		var score float64
+		if err := rows.Scan(&dealer.ID, &dealer.Name, &dealer.City,
+			&dealer.Address, &dealer.Area, &dealer.Rating, &score); err != nil {
+			return nil, err
+		}
+		results = append(results, searchResult{
+			Type:       "dealer",
+			ID:         dealer.ID,
+			Title:      dealer.Name,
+			Subtitle:   fmt.Sprintf("%s, %s", dealer.City, dealer.Address),
+			Score:      score,
+			Highlights: highlights(variants, map[string]string{"name": dealer.Name, "city": dealer.City, "address": dealer.Address}),
+			Dealer:     &dealer,
+		})
+	}
+	return results, rows.Err()
+}
+
+// highlights подсвечивает совпадения в полях записи
+func highlights(variants [][]string, fields map[string]string) map[string]string {
+	result := map[string]string{}
+	for name, value := range fields {
+		if marked, ok := search.Highlight(value, variants); ok {
+			result[name] = marked
+		}
+	}
+	return result
+}
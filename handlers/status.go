@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/messaging"
+	"CarDealership/problem"
+	"CarDealership/validation"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Срок брони в минутах: по умолчанию сутки, не больше двух недель
+const (
+	defaultReservationMinutes = 24 * 60
+	maxReservationMinutes     = 14 * 24 * 60
+)
+
+// statusAction — переход, доступный через POST /api/cars/{id}/{действие}; event — тип события в RabbitMQ
+type statusAction struct {
+	to    string
+	event string
+}
+
+var statusActions = map[string]statusAction{
+	"reserve": {models.StatusReserved, "RESERVE"},
+	"release": {models.StatusAvailable, "RELEASE"},
+	"sell":    {models.StatusSold, "SELL"},
+}
+
+// expireEvent — тип события, когда бронь снята по истечении срока
+const expireEvent = "EXPIRE"
+
+type reserveRequest struct {
+	Minutes *int `json:"minutes"`
+}
+
+// IsStatusPath сообщает, что путь — смена статуса: /api/cars/{id}/reserve, /release или /sell
+func IsStatusPath(path string) bool {
+	parts := strings.Split(path, "/")
+	if len(parts) != 5 {
+		return false
+	}
+	_, ok := statusActions[parts[4]]
+	return ok
+}
+
+// ChangeStatus переводит автомобиль в другой статус (POST /api/cars/{id}/reserve, /release, /sell).
+// Для reserve в теле можно передать {"minutes": N} — срок брони, по умолчанию сутки.
+// Бронь с истёкшим сроком считается снятой, даже если фоновая задача ещё не успела её снять.
+func (h *CarsHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	action := statusActions[strings.Split(r.URL.Path, "/")[4]]
+
+	// Срок брони; для остальных переходов — NULL
+	var minutes *int
+	if action.to == models.StatusReserved {
+		req, ok := decodeReserveRequest(w, r)
+		if !ok {
+			return
+		}
+		minutes = req.Minutes
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем строку, чтобы два перехода не выполнялись одновременно
+	var car models.Car
+	var version int
+	var expired bool
+	err = scanCar(tx.QueryRow(ctx,
+		"SELECT "+carFields+", version, COALESCE(reserved_until <= now(), false) FROM cars WHERE id = $1 FOR UPDATE", id),
+		&car, &version, &expired)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "car.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Проверяем, что клиент видел актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	from := car.Status
+	if expired {
+		from = models.StatusAvailable
+	}
+	if !models.CanTransition(from, action.to) {
+		problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeInvalidTransition, "car.invalid_status_transition").
+			With("from", from).With("to", action.to))
+		return
+	}
+
+	// Срок брони считается по часам базы, как и в фоновом снятии просроченных броней
+	err = scanCar(tx.QueryRow(ctx,
+		`UPDATE cars SET status = $1, reserved_until = now() + $2::int * interval '1 minute'
+		 WHERE id = $3 RETURNING `+carFields+", version", action.to, minutes, id),
+		&car, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	json.NewEncoder(w).Encode(car)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.CarEvent{
+			EventType: action.event,
+			Car:       car,
+		})
+	}
+}
+
+// decodeReserveRequest читает необязательное тело запроса брони; пустое тело — срок по умолчанию
+func decodeReserveRequest(w http.ResponseWriter, r *http.Request) (reserveRequest, bool) {
+	var req reserveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return req, false
+	}
+	defer r.Body.Close()
+
+	if req.Minutes == nil {
+		minutes := defaultReservationMinutes
+		req.Minutes = &minutes
+	}
+	if errs := validation.New().IntRange("minutes", *req.Minutes, 1, maxReservationMinutes).Errors(); errs != nil {
+		problem.Validation(w, r, errs)
+		return req, false
+	}
+	return req, true
+}
+
+// ExpireReservations снимает просроченные брони и публикует событие EXPIRE для каждой машины.
+// Каждую строку обновляет ровно один UPDATE, поэтому несколько экземпляров приложения не мешают друг другу.
+func (h *CarsHandler) ExpireReservations(ctx context.Context) (int, error) {
+	cars, err := collectCars(h.DB.Query(ctx,
+		`UPDATE cars SET status = 'available', reserved_until = NULL
+		 WHERE status = 'reserved' AND reserved_until <= now()
+		 RETURNING `+carFields))
+	if err != nil {
+		return 0, err
+	}
+
+	if h.Rabbit != nil {
+		for _, car := range cars {
+			h.Rabbit.PublishEvent(messaging.CarEvent{
+				EventType: expireEvent,
+				Car:       car,
+			})
+		}
+	}
+	return len(cars), nil
+}
+
+// StartReservationExpiry раз в interval снимает просроченные брони, пока не отменён ctx
+func (h *CarsHandler) StartReservationExpiry(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := h.ExpireReservations(ctx); err != nil {
+					log.Println("Ошибка снятия просроченных броней:", err)
+				}
+			}
+		}
+	}()
+}
+
+// sameTime сравнивает необязательные моменты времени без учёта часового пояса
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,599 @@
+package handlers
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/messaging"
+	"CarDealership/problem"
+	"CarDealership/validation"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TransfersHandler — передача автомобилей между дилерами
+type TransfersHandler struct {
+	DB     *pgxpool.Pool
+	Rabbit *messaging.RabbitMQ
+}
+
+func NewTransfersHandler(db *pgxpool.Pool) *TransfersHandler {
+	return &TransfersHandler{DB: db}
+}
+
+// transferFields — колонки для чтения передачи в том порядке, в котором их ожидает scanTransfer
+const transferFields = "id, car_id, car_title, car_vin, from_dealer_id, from_dealer_name, to_dealer_id, to_dealer_name, " +
+	"status, note, requested_at, decided_at, completed_at"
+
+// requestEvent — тип события новой заявки на передачу; transferEvent — тип события машины,
+// перешедшей к другому дилеру
+const (
+	requestEvent  = "REQUEST"
+	transferEvent = "TRANSFER"
+)
+
+// transferAction — смена статуса передачи; event — тип события в RabbitMQ.
+// byReceiver — действие выполняет дилер-получатель (approve, reject), иначе — отправитель (cancel, complete).
+type transferAction struct {
+	to         string
+	event      string
+	byReceiver bool
+}
+
+var transferActions = map[string]transferAction{
+	"approve":  {models.TransferApproved, "APPROVE", true},
+	"reject":   {models.TransferRejected, "REJECT", true},
+	"cancel":   {models.TransferCancelled, "CANCEL", false},
+	"complete": {models.TransferCompleted, "COMPLETE", false},
+}
+
+// actor возвращает дилера, который вправе выполнить действие с передачей;
+// nil — дилер удалён, и заявка уже отменена
+func (a transferAction) actor(transfer models.Transfer) *int {
+	if a.byReceiver {
+		return transfer.ToDealerID
+	}
+	return transfer.FromDealerID
+}
+
+// scanTransfer читает колонки transferFields, а за ними — extra
+func scanTransfer(row pgx.Row, transfer *models.Transfer, extra ...interface{}) error {
+	dest := []interface{}{&transfer.ID, &transfer.CarID, &transfer.CarTitle, &transfer.CarVIN,
+		&transfer.FromDealerID, &transfer.FromDealerName,
+		&transfer.ToDealerID, &transfer.ToDealerName, &transfer.Status, &transfer.Note, &transfer.RequestedAt, &transfer.DecidedAt, &transfer.CompletedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// TransferAction возвращает действие из пути /api/transfers/{id}/{действие}
+// либо пустую строку, если путь — сама передача
+func TransferAction(path string) string {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) != 5 {
+		return ""
+	}
+	if _, ok := transferActions[parts[4]]; !ok {
+		return ""
+	}
+	return parts[4]
+}
+
+// IsCarTransfersPath сообщает, что путь — история передач автомобиля: /api/cars/{id}/transfers
+func IsCarTransfersPath(path string) bool {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	return len(parts) == 5 && parts[4] == "transfers"
+}
+
+// dealerMoved сообщает, что у машины с дилером меняется или снимается дилер. Иначе машину можно
+// было бы увести без заявки в два шага: снять дилера, а затем назначить другого.
+// Назначить дилера можно только машине, у которой его ещё не было.
+func dealerMoved(from, to *int) bool {
+	return from != nil && (to == nil || *from != *to)
+}
+
+// allowDealerMove разрешает до конца транзакции переводить машины от одного дилера к другому:
+// без этого такой UPDATE отклоняет триггер cars_dealer_move
+func allowDealerMove(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, "SELECT set_config('app.dealer_move', 'on', true)")
+	return err
+}
+
+// transferFilter строит условия отбора передач по query-параметрам: car_id, status и dealer_id.
+// direction уточняет роль дилера: incoming — получатель, outgoing — отправитель, по умолчанию любая.
+func transferFilter(q url.Values) ([]string, []interface{}, validation.Errors) {
+	var conds []string
+	var args []interface{}
+	v := validation.New()
+
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if value := q.Get("car_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			v.Add("car_id", validation.CodeInvalidValue, nil)
+		} else {
+			add("car_id = $%d", id)
+		}
+	}
+
+	if status := q.Get("status"); status != "" {
+		v.OneOf("status", status, validation.TransferStatuses)
+		add("status = $%d", status)
+	}
+
+	direction := q.Get("direction")
+	if direction != "" {
+		v.OneOf("direction", direction, []string{"incoming", "outgoing"})
+	}
+	if value := q.Get("dealer_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			v.Add("dealer_id", validation.CodeInvalidValue, nil)
+		} else {
+			switch direction {
+			case "incoming":
+				add("to_dealer_id = $%d", id)
+			case "outgoing":
+				add("from_dealer_id = $%d", id)
+			default:
+				add("$%d IN (from_dealer_id, to_dealer_id)", id)
+			}
+		}
+	} else if direction != "" {
+		v.Add("dealer_id", validation.CodeRequired, nil)
+	}
+
+	if errs := v.Errors(); errs != nil {
+		return nil, nil, errs
+	}
+	return conds, args, nil
+}
+
+// queryTransfers возвращает передачи по условиям, от новых к старым
+func queryTransfers(ctx context.Context, q queryer, conds []string, args []interface{}) ([]models.Transfer, error) {
+	query := "SELECT " + transferFields + " FROM car_transfers"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY requested_at DESC, id DESC"
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []models.Transfer{}
+	for rows.Next() {
+		var transfer models.Transfer
+		if err := scanTransfer(rows, &transfer); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, rows.Err()
+}
+
+// GetAllTransfers возвращает передачи, отфильтрованные по query-параметрам (см. transferFilter)
+func (h *TransfersHandler) GetAllTransfers(w http.ResponseWriter, r *http.Request) {
+	h.listTransfers(w, r, r.URL.Query())
+}
+
+// GetDealerTransfers возвращает передачи дилера (GET /api/dealers/{id}/transfers):
+// direction=incoming — заявки ему, outgoing — его заявки; status — фильтр по статусу
+func (h *TransfersHandler) GetDealerTransfers(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	q.Set("dealer_id", strconv.Itoa(id))
+	h.listTransfers(w, r, q)
+}
+
+func (h *TransfersHandler) listTransfers(w http.ResponseWriter, r *http.Request, q url.Values) {
+	ctx := r.Context()
+
+	conds, args, errs := transferFilter(q)
+	if errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	transfers, err := queryTransfers(ctx, conn, conds, args)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", transfers)
+}
+
+// GetCarTransfers возвращает историю передач автомобиля (GET /api/cars/{id}/transfers), от новых к старым
+func (h *TransfersHandler) GetCarTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM cars WHERE id = $1)", id).Scan(&exists); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !exists {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "car.not_found")
+		return
+	}
+
+	transfers, err := queryTransfers(ctx, conn, []string{"car_id = $1"}, []interface{}{id})
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, "", transfers)
+}
+
+// GetTransferByID возвращает передачу по ID
+func (h *TransfersHandler) GetTransferByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	var transfer models.Transfer
+	var version int
+	err = scanTransfer(conn.QueryRow(ctx, "SELECT "+transferFields+", version FROM car_transfers WHERE id = $1", id),
+		&transfer, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "transfer.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, versionETag(version), transfer)
+}
+
+// CreateTransfer создаёт заявку на передачу машины другому дилеру (POST).
+// Отправитель — текущий дилер машины; сама машина остаётся у него до завершения передачи.
+func (h *TransfersHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	// Парсим JSON из тела запроса
+	var transfer models.Transfer
+	if err := json.NewDecoder(r.Body).Decode(&transfer); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+
+	// Валидация полей
+	transfer.Note = strings.TrimSpace(transfer.Note)
+	if errs := validation.ValidateTransfer(transfer); errs != nil {
+		problem.Validation(w, r, errs)
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем машину, чтобы её дилер не сменился до конца транзакции
+	var car models.Car
+	err = scanCar(tx.QueryRow(ctx, "SELECT "+carFields+" FROM cars WHERE id = $1 FOR SHARE", transfer.CarID), &car)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Validation(w, r, validation.Errors{{Field: "car_id", Code: validation.CodeNotFound}})
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+	if car.DealerID == nil {
+		problem.Validation(w, r, validation.Errors{{Field: "car_id", Code: validation.CodeNotAllowed}})
+		return
+	}
+	if car.Status == models.StatusSold {
+		problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeConflict, "transfer.car_sold").
+			With("car_id", car.ID))
+		return
+	}
+	transfer.FromDealerID = car.DealerID
+	transfer.CarTitle = fmt.Sprintf("%s %s %d", car.Firm, car.Model, car.Year)
+	transfer.CarVIN = car.VIN
+
+	if *transfer.ToDealerID == *transfer.FromDealerID {
+		problem.Validation(w, r, validation.Errors{{Field: "to_dealer_id", Code: validation.CodeInvalidValue}})
+		return
+	}
+	ok, err := dealerExists(ctx, tx, transfer.ToDealerID)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	if !ok {
+		problem.Validation(w, r, validation.Errors{{Field: "to_dealer_id", Code: validation.CodeNotFound}})
+		return
+	}
+
+	// Незавершённая заявка у машины может быть только одна; индекс car_transfers_open_idx
+	// страхует от гонки, а здесь клиент получает номер мешающей заявки
+	var openID int
+	err = tx.QueryRow(ctx,
+		"SELECT id FROM car_transfers WHERE car_id = $1 AND status IN ('requested', 'approved')",
+		transfer.CarID).Scan(&openID)
+	if err == nil {
+		problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeConflict, "transfer.already_open").
+			With("transfer_id", openID))
+		return
+	}
+	if err != pgx.ErrNoRows {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Машина и названия дилеров сохраняются в заявке, чтобы история пережила их удаление
+	var version int
+	err = scanTransfer(tx.QueryRow(ctx,
+		`INSERT INTO car_transfers (car_id, car_title, car_vin, from_dealer_id, from_dealer_name, to_dealer_id, to_dealer_name, note)
+		 VALUES ($1, $2, $3, $4, (SELECT name FROM dealers WHERE id = $4), $5, (SELECT name FROM dealers WHERE id = $5), $6)
+		 RETURNING `+transferFields+", version",
+		transfer.CarID, transfer.CarTitle, transfer.CarVIN, transfer.FromDealerID, transfer.ToDealerID, transfer.Note),
+		&transfer, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.TransferEvent{
+			EventType: requestEvent,
+			Transfer:  transfer,
+		})
+	}
+}
+
+// ChangeTransferStatus меняет статус передачи (POST /api/transfers/{id}/approve, /reject, /cancel, /complete).
+// В теле указывается действующий дилер {"dealer_id": N}: approve и reject выполняет получатель,
+// cancel и complete — отправитель; чужой дилер получает 403.
+// complete в той же транзакции переводит машину к получателю и отменяет предстоящие тест-драйвы
+// у прежнего дилера; если машина успела сменить дилера или продана, передача не завершается.
+func (h *TransfersHandler) ChangeTransferStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Method != http.MethodPost {
+		problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		return
+	}
+
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	action := transferActions[TransferAction(r.URL.Path)]
+
+	// Парсим JSON из тела запроса: кто выполняет действие
+	var actor struct {
+		DealerID int `json:"dealer_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&actor); err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "error.invalid_json")
+		return
+	}
+	defer r.Body.Close()
+	if actor.DealerID <= 0 {
+		problem.Validation(w, r, validation.Errors{{Field: "dealer_id", Code: validation.CodeRequired}})
+		return
+	}
+
+	// Получаем соединение из пула
+	conn, err := h.DB.Acquire(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем строку, чтобы два перехода не выполнялись одновременно
+	var transfer models.Transfer
+	var version int
+	err = scanTransfer(tx.QueryRow(ctx, "SELECT "+transferFields+", version FROM car_transfers WHERE id = $1 FOR UPDATE", id),
+		&transfer, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "transfer.not_found")
+			return
+		}
+		problem.DBError(w, r, err)
+		return
+	}
+
+	// Проверяем, что клиент видел актуальную версию
+	if !checkIfMatch(w, r, version) {
+		return
+	}
+
+	// Согласие даёт получатель, а отзывает и завершает передачу отправитель
+	// Если дилер удалён, заявка уже отменена, и ответ даст проверка перехода
+	if expected := action.actor(transfer); expected != nil && actor.DealerID != *expected {
+		problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeForbidden, "transfer.wrong_dealer").
+			With("action", TransferAction(r.URL.Path)).With("dealer_id", *expected))
+		return
+	}
+
+	if !models.CanTransferTransition(transfer.Status, action.to) {
+		problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeInvalidTransition, "transfer.invalid_status_transition").
+			With("from", transfer.Status).With("to", action.to))
+		return
+	}
+
+	var car models.Car
+	var cancelled []models.Booking
+	stamp := "decided_at = now()"
+	if action.to == models.TransferCompleted {
+		stamp = "completed_at = now()"
+
+		err = scanCar(tx.QueryRow(ctx, "SELECT "+carFields+" FROM cars WHERE id = $1 FOR UPDATE", transfer.CarID), &car)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		if car.DealerID == nil || *car.DealerID != *transfer.FromDealerID {
+			problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeConflict, "transfer.car_moved").
+				With("car_id", car.ID))
+			return
+		}
+		if car.Status == models.StatusSold {
+			problem.Write(w, r, problem.New(http.StatusConflict, problem.CodeConflict, "transfer.car_sold").
+				With("car_id", car.ID))
+			return
+		}
+
+		if err := allowDealerMove(ctx, tx); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		err = scanCar(tx.QueryRow(ctx,
+			"UPDATE cars SET dealer_id = $1 WHERE id = $2 RETURNING "+carFields, transfer.ToDealerID, car.ID), &car)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+
+		// Тест-драйвы записаны у прежнего дилера — провести их он уже не сможет
+		rows, err := tx.Query(ctx,
+			`UPDATE bookings SET status = $1
+			 WHERE car_id = $2 AND status = 'booked' AND upper(slot) > now()
+			 RETURNING `+bookingFields, models.BookingCancelled, car.ID)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+		for rows.Next() {
+			var booking models.Booking
+			if err := scanBooking(rows, &booking); err != nil {
+				rows.Close()
+				problem.DBError(w, r, err)
+				return
+			}
+			cancelled = append(cancelled, booking)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+	}
+
+	err = scanTransfer(tx.QueryRow(ctx,
+		"UPDATE car_transfers SET status = $1, "+stamp+" WHERE id = $2 RETURNING "+transferFields+", version",
+		action.to, id), &transfer, &version)
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(version))
+	json.NewEncoder(w).Encode(transfer)
+
+	if h.Rabbit != nil {
+		h.Rabbit.PublishEvent(messaging.TransferEvent{
+			EventType: action.event,
+			Transfer:  transfer,
+		})
+		if action.to == models.TransferCompleted {
+			h.Rabbit.PublishEvent(messaging.CarEvent{
+				EventType: transferEvent,
+				Car:       car,
+			})
+		}
+		for _, booking := range cancelled {
+			h.Rabbit.PublishEvent(messaging.BookingEvent{
+				EventType: "CANCEL",
+				Booking:   booking,
+			})
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Lang — код языка ответа
+type Lang string
+
+const (
+	RU Lang = "ru"
+	EN Lang = "en"
+
+	// Default — язык по умолчанию, если клиент не указал поддерживаемый
+	Default = RU
+)
+
+// Params — именованные параметры, подставляемые в сообщение вместо {имя}
+type Params map[string]interface{}
+
+//go:embed locales/*.json
+var files embed.FS
+
+var (
+	supported = []Lang{RU, EN}
+	matcher   = language.NewMatcher([]language.Tag{language.Russian, language.English})
+	catalog   = mustLoad()
+)
+
+func mustLoad() map[Lang]map[string]string {
+	c := make(map[Lang]map[string]string, len(supported))
+	for _, lang := range supported {
+		body, err := files.ReadFile("locales/" + string(lang) + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: нет каталога %s: %v", lang, err))
+		}
+
+		messages := make(map[string]string)
+		if err := json.Unmarshal(body, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: ошибка разбора каталога %s: %v", lang, err))
+		}
+		c[lang] = messages
+	}
+	return c
+}
+
+// FromRequest выбирает язык ответа по заголовку Accept-Language
+func FromRequest(r *http.Request) Lang {
+	header := r.Header.Get("Accept-Language")
+	if header == "" {
+		return Default
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Default
+	}
+	return supported[index]
+}
+
+// T возвращает сообщение по ключу на нужном языке. Если перевода нет,
+// используется язык по умолчанию, а в крайнем случае — сам ключ.
+func T(lang Lang, key string, params Params) string {
+	msg, ok := catalog[lang][key]
+	if !ok {
+		msg, ok = catalog[Default][key]
+	}
+	if !ok {
+		msg = key
+	}
+
+	for name, value := range params {
+		msg = strings.ReplaceAll(msg, "{"+name+"}", fmt.Sprint(value))
+	}
+	return msg
+}
@@ -0,0 +1,254 @@
+package idempotency
+
+import (
+	"CarDealership/problem"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// HeaderKey — заголовок, в котором клиент передаёт ключ идемпотентности
+const HeaderKey = "Idempotency-Key"
+
+// DefaultTTL — сколько хранится сохранённый ответ
+const DefaultTTL = 24 * time.Hour
+
+// maxKeyLength соответствует размеру колонки idempotency_keys.key
+const maxKeyLength = 255
+
+// maxBody ограничивает размер тела запроса, которое читается для хеширования;
+// запрос с ключом и телом больше отклоняется с 413
+const maxBody = 1 << 20
+
+// Store хранит ключи идемпотентности и ответы на запросы в Postgres
+type Store struct {
+	DB  *pgxpool.Pool
+	TTL time.Duration
+}
+
+func NewStore(db *pgxpool.Pool) *Store {
+	return &Store{DB: db, TTL: DefaultTTL}
+}
+
+// Wrap делает обработчик идемпотентным: повторный запрос с тем же ключом и телом
+// получает сохранённый ответ, а с тем же ключом и другим телом — 422.
+// Запросы без заголовка Idempotency-Key обрабатываются как обычно.
+func (s *Store) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderKey)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "idempotency.invalid_key")
+			return
+		}
+
+		// Читаем на байт больше предела, чтобы отличить слишком большое тело от тела ровно в maxBody
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+		if err != nil {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeBadRequest, "error.invalid_json")
+			return
+		}
+		if len(body) > maxBody {
+			problem.Write(w, r, problem.New(http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge,
+				"idempotency.body_too_large").With("max_mb", maxBody>>20))
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		scope := r.Method + " " + r.URL.Path
+		hash := requestHash(r, body)
+
+		reserved, err := s.reserve(ctx, key, scope, hash)
+		if err != nil {
+			problem.DBError(w, r, err)
+			return
+		}
+
+		if !reserved {
+			s.replay(w, r, key, scope, hash)
+			return
+		}
+
+		// Если обработчик запаниковал, ключ освобождаем, а панику передаём дальше:
+		// иначе ключ остался бы занятым и повтор получал бы 409 до истечения TTL
+		defer func() {
+			if p := recover(); p != nil {
+				s.release(key, scope)
+				panic(p)
+			}
+		}()
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+
+		// Ответы 5xx не сохраняем: клиент должен иметь возможность повторить запрос
+		if rec.status >= http.StatusInternalServerError {
+			s.release(key, scope)
+			return
+		}
+		s.complete(key, scope, rec)
+	}
+}
+
+// reserve занимает ключ до завершения запроса. Возвращает false, если ключ уже занят
+func (s *Store) reserve(ctx context.Context, key, scope, hash string) (bool, error) {
+	// Просроченный ключ можно использовать заново
+	_, err := s.DB.Exec(ctx,
+		"DELETE FROM idempotency_keys WHERE key = $1 AND scope = $2 AND expires_at < now()", key, scope)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := s.DB.Exec(ctx,
+		`INSERT INTO idempotency_keys (key, scope, request_hash, expires_at)
+		 VALUES ($1, $2, $3, now() + $4::bigint * interval '1 second')
+		 ON CONFLICT (key, scope) DO NOTHING`,
+		key, scope, hash, int64(s.TTL.Seconds()))
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// replay отдаёт сохранённый ответ для повторного запроса
+func (s *Store) replay(w http.ResponseWriter, r *http.Request, key, scope, hash string) {
+	var (
+		storedHash  string
+		status      *int
+		contentType *string
+		etag        *string
+		body        []byte
+	)
+	err := s.DB.QueryRow(r.Context(),
+		`SELECT request_hash, status_code, content_type, etag, response_body
+		 FROM idempotency_keys WHERE key = $1 AND scope = $2`,
+		key, scope).Scan(&storedHash, &status, &contentType, &etag, &body)
+	if err == pgx.ErrNoRows {
+		// Первый запрос завершился ошибкой и освободил ключ — просим повторить
+		problem.Error(w, r, http.StatusConflict, problem.CodeIdempotencyInProgress, "idempotency.in_progress")
+		return
+	}
+	if err != nil {
+		problem.DBError(w, r, err)
+		return
+	}
+
+	if storedHash != hash {
+		problem.Error(w, r, http.StatusUnprocessableEntity, problem.CodeIdempotencyMismatch, "idempotency.mismatch")
+		return
+	}
+
+	if status == nil {
+		problem.Error(w, r, http.StatusConflict, problem.CodeIdempotencyInProgress, "idempotency.in_progress")
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if contentType != nil {
+		w.Header().Set("Content-Type", *contentType)
+	}
+	if etag != nil {
+		w.Header().Set("ETag", *etag)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(*status)
+	w.Write(body)
+}
+
+// complete сохраняет ответ на запрос. Запрос клиента к этому моменту уже
+// обработан, поэтому используется отдельный контекст.
+func (s *Store) complete(key, scope string, rec *recorder) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := s.DB.Exec(ctx,
+		`UPDATE idempotency_keys
+		 SET status_code = $3, content_type = $4, etag = $5, response_body = $6
+		 WHERE key = $1 AND scope = $2`,
+		key, scope, rec.status, nullable(rec.Header().Get("Content-Type")),
+		nullable(rec.Header().Get("ETag")), rec.body.Bytes())
+	if err != nil {
+		log.Println("Ошибка сохранения ответа для ключа идемпотентности:", err)
+	}
+}
+
+// release освобождает ключ после неудачного запроса
+func (s *Store) release(key, scope string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.DB.Exec(ctx,
+		"DELETE FROM idempotency_keys WHERE key = $1 AND scope = $2", key, scope); err != nil {
+		log.Println("Ошибка освобождения ключа идемпотентности:", err)
+	}
+}
+
+// StartCleanup периодически удаляет просроченные ключи, пока не отменён ctx
+func (s *Store) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.DB.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at < now()"); err != nil {
+					log.Println("Ошибка очистки ключей идемпотентности:", err)
+				}
+			}
+		}
+	}()
+}
+
+// requestHash однозначно описывает запрос: метод, путь и тело
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// recorder пропускает ответ клиенту и параллельно запоминает статус и тело
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,319 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrTrailingData — после JSON-значения в теле есть лишние данные
+	ErrTrailingData = errors.New("jsonpatch: лишние данные после JSON")
+
+	// ErrTestFailed — операция test обнаружила несовпадение значения
+	ErrTestFailed = errors.New("jsonpatch: проверка test не прошла")
+)
+
+// Operation — одна операция JSON Patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply применяет к документу JSON Patch (RFC 6902). Операции выполняются
+// по порядку; если любая из них не удалась, документ не изменяется.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("jsonpatch: неверный формат патча: %v", err)
+	}
+
+	root, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		root, err = applyOp(root, op)
+		if err != nil {
+			if errors.Is(err, ErrTestFailed) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("jsonpatch: операция %d (%s %s): %v", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func applyOp(root interface{}, op Operation) (interface{}, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New("не указано value")
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Op {
+		case "add":
+			return add(root, op.Path, value)
+		case "replace":
+			if _, err := get(root, op.Path); err != nil {
+				return nil, err
+			}
+			return replace(root, op.Path, value)
+		default:
+			current, err := get(root, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, ErrTestFailed
+			}
+			return root, nil
+		}
+
+	case "remove":
+		root, _, err := remove(root, op.Path)
+		return root, err
+
+	case "move":
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, errors.New("нельзя переместить значение внутрь самого себя")
+		}
+		root, value, err := remove(root, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, op.Path, value)
+
+	case "copy":
+		value, err := get(root, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, op.Path, deepCopy(value))
+	}
+
+	return nil, fmt.Errorf("неизвестная операция %q", op.Op)
+}
+
+// parsePointer разбирает JSON Pointer (RFC 6901) на токены
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("неверный указатель %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(root interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := root
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("путь %q не существует", pointer)
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("путь %q не существует", pointer)
+		}
+	}
+	return current, nil
+}
+
+// update находит родителя значения по указателю и заменяет его результатом fn
+func update(root interface{}, pointer string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return fn(nil, "")
+	}
+
+	parentPointer := ""
+	for _, t := range tokens[:len(tokens)-1] {
+		parentPointer += "/" + strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1")
+	}
+
+	parent, err := get(root, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+
+	newParent, err := fn(parent, tokens[len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	if parentPointer == "" {
+		return newParent, nil
+	}
+	return replace(root, parentPointer, newParent)
+}
+
+func add(root interface{}, pointer string, value interface{}) (interface{}, error) {
+	return update(root, pointer, func(parent interface{}, key string) (interface{}, error) {
+		switch node := parent.(type) {
+		case nil:
+			return value, nil
+		case map[string]interface{}:
+			node[key] = value
+			return node, nil
+		case []interface{}:
+			if key == "-" {
+				return append(node, value), nil
+			}
+			index, err := arrayIndex(key, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("путь %q не существует", pointer)
+	})
+}
+
+func replace(root interface{}, pointer string, value interface{}) (interface{}, error) {
+	return update(root, pointer, func(parent interface{}, key string) (interface{}, error) {
+		switch node := parent.(type) {
+		case nil:
+			return value, nil
+		case map[string]interface{}:
+			node[key] = value
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(key, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			node[index] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("путь %q не существует", pointer)
+	})
+}
+
+func remove(root interface{}, pointer string) (interface{}, interface{}, error) {
+	var removed interface{}
+	root, err := update(root, pointer, func(parent interface{}, key string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("путь %q не существует", pointer)
+			}
+			removed = value
+			delete(node, key)
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(key, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[index]
+			return append(node[:index], node[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("нельзя удалить %q", pointer)
+	})
+	return root, removed, err
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("неверный индекс массива %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max {
+		return 0, fmt.Errorf("индекс массива %q вне диапазона", token)
+	}
+	return index, nil
+}
+
+// equal сравнивает JSON-значения; числа сравниваются по значению, а не по записи
+func equal(a, b interface{}) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		af, aerr := an.Float64()
+		bf, berr := bn.Float64()
+		return aerr == nil && berr == nil && af == bf
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if !equal(v, bv[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(node))
+		for k, val := range node {
+			c[k] = deepCopy(val)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(node))
+		for i, val := range node {
+			c[i] = deepCopy(val)
+		}
+		return c
+	}
+	return v
+}
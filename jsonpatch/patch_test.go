@@ -0,0 +1,277 @@
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+// assertJSON сравнивает документы как JSON-значения, а не как текст
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	g, err := decode(got)
+	if err != nil {
+		t.Fatalf("результат не JSON: %v (%s)", err, got)
+	}
+	w, err := decode([]byte(want))
+	if err != nil {
+		t.Fatalf("ожидаемое значение не JSON: %v (%s)", err, want)
+	}
+	if !equal(g, w) {
+		t.Errorf("получено %s; ожидалось %s", got, want)
+	}
+}
+
+// Примеры из приложения A RFC 6902
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			"A.1 добавление поля объекта",
+			`{"foo": "bar"}`,
+			`[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			`{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			"A.2 добавление элемента массива",
+			`{"foo": ["bar", "baz"]}`,
+			`[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			`{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			"A.3 удаление поля объекта",
+			`{"baz": "qux", "foo": "bar"}`,
+			`[{"op": "remove", "path": "/baz"}]`,
+			`{"foo": "bar"}`,
+		},
+		{
+			"A.4 удаление элемента массива",
+			`{"foo": ["bar", "qux", "baz"]}`,
+			`[{"op": "remove", "path": "/foo/1"}]`,
+			`{"foo": ["bar", "baz"]}`,
+		},
+		{
+			"A.5 замена значения",
+			`{"baz": "qux", "foo": "bar"}`,
+			`[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			`{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			"A.6 перемещение значения",
+			`{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			`[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			`{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			"A.7 перемещение элемента массива",
+			`{"foo": ["all", "grass", "cows", "eat"]}`,
+			`[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			`{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			"A.8 успешная проверка test",
+			`{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			`[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			`{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			"A.10 добавление вложенного объекта",
+			`{"foo": "bar"}`,
+			`[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			`{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			"A.11 неизвестные поля операции игнорируются",
+			`{"foo": "bar"}`,
+			`[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			`{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			"A.14 экранирование ~0 и ~1",
+			`{"/": 9, "~1": 10}`,
+			`[{"op": "test", "path": "/~01", "value": 10}]`,
+			`{"/": 9, "~1": 10}`,
+		},
+		{
+			"A.16 добавление массива в конец массива",
+			`{"foo": ["bar"]}`,
+			`[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			`{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		{
+			"ключ с / через ~1",
+			`{}`,
+			`[{"op": "add", "path": "/a~1b", "value": 1}, {"op": "copy", "from": "/a~1b", "path": "/c~0d"}]`,
+			`{"a/b": 1, "c~d": 1}`,
+		},
+		{
+			"копия не связана с оригиналом",
+			`{"foo": {"bar": 1}}`,
+			`[{"op": "copy", "from": "/foo", "path": "/baz"}, {"op": "replace", "path": "/baz/bar", "value": 2}]`,
+			`{"foo": {"bar": 1}, "baz": {"bar": 2}}`,
+		},
+		{
+			"числа в test сравниваются по значению",
+			`{"price": 1.0}`,
+			`[{"op": "test", "path": "/price", "value": 1}]`,
+			`{"price": 1}`,
+		},
+		{
+			"замена всего документа",
+			`{"foo": "bar"}`,
+			`[{"op": "replace", "path": "", "value": [1, 2]}]`,
+			`[1, 2]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		patch      string
+		testFailed bool
+	}{
+		{
+			"A.9 проверка test не прошла",
+			`{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			`[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			true,
+		},
+		{
+			"A.12 добавление к несуществующему объекту",
+			`{"foo": "bar"}`,
+			`[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			false,
+		},
+		{
+			"A.15 строка не равна числу",
+			`{"/": 9, "~1": 10}`,
+			`[{"op": "test", "path": "/~01", "value": "10"}]`,
+			true,
+		},
+		{
+			"замена несуществующего поля",
+			`{"foo": "bar"}`,
+			`[{"op": "replace", "path": "/baz", "value": 1}]`,
+			false,
+		},
+		{
+			"удаление за пределами массива",
+			`{"foo": [1]}`,
+			`[{"op": "remove", "path": "/foo/1"}]`,
+			false,
+		},
+		{
+			"индекс с ведущим нулём",
+			`{"foo": [1, 2]}`,
+			`[{"op": "replace", "path": "/foo/01", "value": 3}]`,
+			false,
+		},
+		{
+			"- допустим только для add",
+			`{"foo": [1]}`,
+			`[{"op": "remove", "path": "/foo/-"}]`,
+			false,
+		},
+		{
+			"перемещение внутрь самого себя",
+			`{"foo": {"bar": 1}}`,
+			`[{"op": "move", "from": "/foo", "path": "/foo/bar/baz"}]`,
+			false,
+		},
+		{
+			"указатель без /",
+			`{"foo": 1}`,
+			`[{"op": "remove", "path": "foo"}]`,
+			false,
+		},
+		{
+			"нет value",
+			`{"foo": 1}`,
+			`[{"op": "add", "path": "/bar"}]`,
+			false,
+		},
+		{
+			"неизвестная операция",
+			`{"foo": 1}`,
+			`[{"op": "rename", "path": "/foo"}]`,
+			false,
+		},
+		{
+			"патч не массив",
+			`{"foo": 1}`,
+			`{"op": "remove", "path": "/foo"}`,
+			false,
+		},
+		{
+			"ошибка после успешных операций",
+			`{"foo": 1}`,
+			`[{"op": "remove", "path": "/foo"}, {"op": "test", "path": "/foo", "value": 1}]`,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if err == nil {
+				t.Fatalf("Apply = %s; ожидалась ошибка", got)
+			}
+			if errors.Is(err, ErrTestFailed) != tt.testFailed {
+				t.Errorf("Apply: ошибка %v; ErrTestFailed ожидалась: %v", err, tt.testFailed)
+			}
+		})
+	}
+}
+
+// Примеры из приложения A RFC 7396
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+		{`{"a": "b"}`, `{"a": null}`, `{}`},
+		{`{"a": "b", "b": "c"}`, `{"a": null}`, `{"b": "c"}`},
+		{`{"a": ["b"]}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "c"}`, `{"a": ["b"]}`, `{"a": ["b"]}`},
+		{`{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a": {"b": "d"}}`},
+		{`{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{`["a", "b"]`, `["c", "d"]`, `["c", "d"]`},
+		{`{"a": "b"}`, `["c"]`, `["c"]`},
+		{`{"a": "foo"}`, `null`, `null`},
+		{`{"a": "foo"}`, `"bar"`, `"bar"`},
+		{`{"e": null}`, `{"a": 1}`, `{"e": null, "a": 1}`},
+		{`[1, 2]`, `{"a": "b", "c": null}`, `{"a": "b"}`},
+		{`{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a": {"bb": {}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.doc+" + "+tt.patch, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("MergePatch: %v", err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestMergePatchTrailingData(t *testing.T) {
+	if _, err := MergePatch([]byte(`{"a": 1}`), []byte(`{"a": 2} {}`)); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("MergePatch: ошибка %v; ожидалась ErrTrailingData", err)
+	}
+}
@@ -0,0 +1,431 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema — схема JSON Schema (поднабор draft 2020-12): type, properties, required,
+// additionalProperties, items, enum, const, minimum/maximum, exclusiveMinimum/exclusiveMaximum,
+// multipleOf, minLength/maxLength, pattern, $defs и локальные ссылки $ref вида "#/$defs/имя".
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Defs                 map[string]*Schema `json:"$defs"`
+	Type                 typeList           `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                json.RawMessage    `json:"const"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum"`
+	MultipleOf           *float64           `json:"multipleOf"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+
+	root    *Schema
+	pattern *regexp.Regexp
+	konst   interface{}
+}
+
+// ValidationError — нарушение схемы. Path — путь к значению в виде сегментов JSON Pointer.
+type ValidationError struct {
+	Path    []string
+	Message string
+}
+
+// Pointer возвращает путь в виде JSON Pointer (RFC 6901)
+func (e ValidationError) Pointer() string {
+	if len(e.Path) == 0 {
+		return ""
+	}
+	parts := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		parts[i] = strings.NewReplacer("~", "~0", "/", "~1").Replace(p)
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+func (e ValidationError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	return e.Pointer() + ": " + e.Message
+}
+
+// typeList — значение "type": одна строка или массив строк
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = typeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("jsonschema: неверное значение type: %s", data)
+	}
+	*t = many
+	return nil
+}
+
+// Compile разбирает схему и проверяет ссылки и регулярные выражения
+func Compile(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("jsonschema: %v", err)
+	}
+	if err := s.prepare(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// MustCompile — как Compile, но паникует при ошибке; для встроенных схем
+func MustCompile(data []byte) *Schema {
+	s, err := Compile(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *Schema) prepare(root *Schema) error {
+	s.root = root
+	if s.Ref != "" {
+		if _, err := s.resolve(); err != nil {
+			return err
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("jsonschema: pattern %q: %v", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	if s.Const != nil {
+		v, err := decodeValue(s.Const)
+		if err != nil {
+			return fmt.Errorf("jsonschema: const: %v", err)
+		}
+		s.konst = v
+	}
+	for _, sub := range s.Defs {
+		if err := sub.prepare(root); err != nil {
+			return err
+		}
+	}
+	for _, sub := range s.Properties {
+		if err := sub.prepare(root); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.prepare(root)
+	}
+	return nil
+}
+
+func (s *Schema) resolve() (*Schema, error) {
+	name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+	if !ok {
+		return nil, fmt.Errorf("jsonschema: поддерживаются только ссылки #/$defs/..., получено %q", s.Ref)
+	}
+	def, ok := s.root.Defs[name]
+	if !ok {
+		return nil, fmt.Errorf("jsonschema: определение %q не найдено", name)
+	}
+	return def, nil
+}
+
+// Def возвращает схему из $defs по имени или nil
+func (s *Schema) Def(name string) *Schema {
+	return s.Defs[name]
+}
+
+// Property возвращает схему свойства с учётом $ref; nil, если свойство не описано.
+// Второе значение сообщает, разрешены ли неописанные свойства.
+func (s *Schema) Property(name string) (*Schema, bool) {
+	s = s.target()
+	if sub, ok := s.Properties[name]; ok {
+		return sub.target(), true
+	}
+	return nil, s.AdditionalProperties == nil || *s.AdditionalProperties
+}
+
+// ItemSchema возвращает схему элементов массива с учётом $ref
+func (s *Schema) ItemSchema() *Schema {
+	s = s.target()
+	if s.Items == nil {
+		return nil
+	}
+	return s.Items.target()
+}
+
+func (s *Schema) target() *Schema {
+	if s.Ref == "" {
+		return s
+	}
+	def, _ := s.resolve()
+	return def
+}
+
+// Validate проверяет значение, полученное из JSON (числа — json.Number или float64) или YAML (int, float64).
+// Возвращает все нарушения, упорядоченные по пути.
+func (s *Schema) Validate(v interface{}) []ValidationError {
+	var errs []ValidationError
+	s.validate(v, nil, &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Pointer() < errs[j].Pointer() })
+	return errs
+}
+
+func (s *Schema) validate(v interface{}, path []string, errs *[]ValidationError) {
+	s = s.target()
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: append([]string(nil), path...), Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(v) {
+		fail("ожидается %s, получено %s", strings.Join(typeNames(s.Type), " или "), typeName(typeOf(v)))
+		return
+	}
+	if s.konst != nil && !equal(v, s.konst) {
+		fail("ожидается значение %s", formatValue(s.konst))
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if equal(v, normalize(e)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			vals := make([]string, len(s.Enum))
+			for i, e := range s.Enum {
+				vals[i] = formatValue(e)
+			}
+			fail("допустимые значения: %s", strings.Join(vals, ", "))
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				fail("не хватает обязательного поля «%s»", name)
+			}
+		}
+		for name, item := range val {
+			sub, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*errs = append(*errs, ValidationError{
+						Path:    append(append([]string(nil), path...), name),
+						Message: fmt.Sprintf("неизвестное поле «%s»", name),
+					})
+				}
+				continue
+			}
+			sub.validate(item, append(path, name), errs)
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range val {
+				s.Items.validate(item, append(path, strconv.Itoa(i)), errs)
+			}
+		}
+	case string:
+		n := len([]rune(val))
+		if s.MinLength != nil && n < *s.MinLength {
+			if *s.MinLength == 1 {
+				fail("значение не может быть пустым")
+			} else {
+				fail("длина меньше %d символов", *s.MinLength)
+			}
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("длина больше %d символов", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			fail("значение не соответствует шаблону %s", s.Pattern)
+		}
+	default:
+		if f, ok := toFloat(v); ok {
+			s.validateNumber(f, fail)
+		}
+	}
+}
+
+func (s *Schema) validateNumber(f float64, fail func(string, ...interface{})) {
+	if s.Minimum != nil && f < *s.Minimum {
+		fail("значение меньше %s", formatFloat(*s.Minimum))
+	}
+	if s.Maximum != nil && f > *s.Maximum {
+		fail("значение больше %s", formatFloat(*s.Maximum))
+	}
+	if s.ExclusiveMinimum != nil && f <= *s.ExclusiveMinimum {
+		fail("значение должно быть больше %s", formatFloat(*s.ExclusiveMinimum))
+	}
+	if s.ExclusiveMaximum != nil && f >= *s.ExclusiveMaximum {
+		fail("значение должно быть меньше %s", formatFloat(*s.ExclusiveMaximum))
+	}
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		q := f / *s.MultipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("значение должно быть кратно %s", formatFloat(*s.MultipleOf))
+		}
+	}
+}
+
+func (s *Schema) matchesType(v interface{}) bool {
+	actual := typeOf(v)
+	for _, t := range s.Type {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf возвращает тип значения в терминах JSON Schema
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	if n, ok := v.(json.Number); ok {
+		// В JSON целым считаем только число без дробной части и экспоненты — иначе его не примет int
+		if strings.ContainsAny(string(n), ".eE") {
+			return "number"
+		}
+		return "integer"
+	}
+	if f, ok := toFloat(v); ok {
+		if f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+var typeTitles = map[string]string{
+	"null":    "null",
+	"boolean": "логическое значение",
+	"string":  "строка",
+	"object":  "объект",
+	"array":   "массив",
+	"integer": "целое число",
+	"number":  "число",
+}
+
+func typeName(t string) string {
+	if title, ok := typeTitles[t]; ok {
+		return title
+	}
+	return t
+}
+
+func typeNames(types []string) []string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = typeName(t)
+	}
+	return names
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// normalize приводит числа к float64, чтобы значения из JSON и YAML сравнивались одинаково
+func normalize(v interface{}) interface{} {
+	if f, ok := toFloat(v); ok {
+		return f
+	}
+	return v
+}
+
+func equal(a, b interface{}) bool {
+	a, b = normalize(a), normalize(b)
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, x := range av {
+			if y, ok := bv[k]; !ok || !equal(x, y) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func decodeValue(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -3,16 +3,23 @@ package main
 import (
 	"CarDealership/database/connection"
 	"CarDealership/database/importer"
+	"CarDealership/database/migrations"
 	"CarDealership/database/simple_sql"
+	"CarDealership/geocoding"
 	"CarDealership/handlers"
+	"CarDealership/idempotency"
+	"CarDealership/media"
 	"CarDealership/messaging"
+	"CarDealership/middleware"
 	"CarDealership/router"
+	"CarDealership/storage"
 	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -21,8 +28,9 @@ import (
 func enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, If-Match, If-None-Match, Idempotency-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, ETag, Idempotent-Replayed")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -36,6 +44,21 @@ func enableCORS(next http.Handler) http.Handler {
 func main() {
 	ctx := context.Background()
 
+	// Подкоманда импорта: go run . import [--dry-run] файлы...
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImportCommand(ctx, os.Args[2:]))
+	}
+
+	// Генерация синтетических данных: go run . generate --cars N (--out файл | --import)
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		os.Exit(runGenerateCommand(ctx, os.Args[2:]))
+	}
+
+	// Заполнение координат дилеров по адресам: go run . geocode [--dry-run] [--all]
+	if len(os.Args) > 1 && os.Args[1] == "geocode" {
+		os.Exit(runGeocodeCommand(ctx, os.Args[2:]))
+	}
+
 	// Используем пул соединений
 	pool, err := connection.CreateConnectionPool(ctx)
 	if err != nil {
@@ -45,17 +68,9 @@ func main() {
 
 	fmt.Println("✅ База данных успешно подключена!")
 
-	// Получаем одно соединение для создания таблиц
-	conn, err := pool.Acquire(ctx)
-	if err != nil {
-		log.Fatal("Ошибка получения соединения:", err)
-	}
-
-	if err := simple_sql.CreateTable(ctx, conn.Conn()); err != nil {
-		conn.Release()
+	if err := prepareSchema(ctx, pool); err != nil {
 		panic(err)
 	}
-	conn.Release()
 
 	fmt.Println("✅ Таблицы созданы/проверены!")
 
@@ -68,37 +83,142 @@ func main() {
 	}
 	defer rmq.Close()
 
+	// Хранилище фотографий и документов: локальная папка или S3 (MEDIA_STORAGE)
+	mediaStorage, err := storage.FromEnv(ctx)
+	if err != nil {
+		log.Fatal("Ошибка подключения хранилища файлов:", err)
+	}
+
+	// Геокодер для координат дилеров: встроенный справочник, свой файл или внешний сервис (GEOCODER)
+	geocoder, err := geocoding.FromEnv()
+	if err != nil {
+		log.Fatal("Ошибка настройки геокодера:", err)
+	}
+
+	// Файлы удалённых машин и вложений удаляются из хранилища в фоне
+	mediaCleaner := media.NewCleaner(pool, mediaStorage)
+	mediaCleaner.StartCleanup(ctx, time.Hour)
+
 	// Хендлеры для cars и для dealers
 	carsHandler := handlers.NewCarsHandler(pool)
 	carsHandler.Rabbit = rmq
+	carsHandler.Media = mediaCleaner
+	// Просроченные брони снимаются раз в минуту
+	carsHandler.StartReservationExpiry(ctx, time.Minute)
 
 	dealersHandler := handlers.NewDealersHandler(pool)
+	dealersHandler.Rabbit = rmq
+	dealersHandler.Media = mediaCleaner
+	dealersHandler.Geocoder = geocoder
+
+	searchHandler := handlers.NewSearchHandler(pool)
+
+	catalogHandler := handlers.NewCatalogHandler(pool)
+
+	mediaHandler := handlers.NewMediaHandler(pool, mediaStorage, mediaCleaner)
+
+	customersHandler := handlers.NewCustomersHandler(pool)
+	customersHandler.Rabbit = rmq
+
+	salesHandler := handlers.NewSalesHandler(pool)
+	salesHandler.Rabbit = rmq
+
+	bookingsHandler := handlers.NewBookingsHandler(pool)
+	bookingsHandler.Rabbit = rmq
+
+	transfersHandler := handlers.NewTransfersHandler(pool)
+	transfersHandler.Rabbit = rmq
+
+	reviewsHandler := handlers.NewReviewsHandler(pool)
+	reviewsHandler.Rabbit = rmq
+
+	// Ответы на POST с Idempotency-Key хранятся сутки
+	idempotencyStore := idempotency.NewStore(pool)
+	idempotencyStore.StartCleanup(ctx, time.Hour)
 
 	// Роутер
-	router.SetupRoutes(carsHandler, dealersHandler)
+	router.SetupRoutes(carsHandler, dealersHandler, searchHandler, catalogHandler, mediaHandler, customersHandler, salesHandler, bookingsHandler, transfersHandler, reviewsHandler, idempotencyStore)
 
-	// Оборачиваем все обработчики в CORS middleware
-	handler := enableCORS(http.DefaultServeMux)
+	// Оборачиваем все обработчики в CORS middleware и присваиваем запросам ID
+	handler := enableCORS(middleware.RequestID(http.DefaultServeMux))
 
 	// Запуск сервера
 	port := ":8080"
 	fmt.Printf("🚀 Сервер успешно запущен на http://localhost%s\n", port)
 	fmt.Println("🌐 CORS включен для всех доменов")
 	fmt.Println("📋 Доступные эндпоинты:")
-	fmt.Println("  GET    /api/cars          - Получить список всех машин")
+	fmt.Println("  GET    /api/cars          - Получить список машин (проданные — только с фильтром status)")
+	fmt.Println("  GET    /api/cars/unassigned - Получить автомобили без дилера")
+	fmt.Println("  POST   /api/cars/bulk     - Массовое создание/обновление/удаление (mode: atomic | best_effort)")
+	fmt.Println("  GET    /api/cars/export   - Выгрузить автомобили (format: csv | xlsx | json, фильтры как у списка)")
+	fmt.Println("  POST   /api/import/cars   - Импорт автомобилей из CSV/XLSX (multipart file, dry_run, strict, mapping)")
 	fmt.Println("  GET    /api/cars/{id}     - Получить автомобиль по его идентификатору")
-	fmt.Println("  POST   /api/cars          - Создать новый автомобиль")
+	fmt.Println("  GET    /api/cars/by-vin/{vin} - Найти автомобиль по VIN")
+	fmt.Println("  POST   /api/cars          - Создать новый автомобиль (поддерживает Idempotency-Key)")
 	fmt.Println("  PUT    /api/cars/{id}     - Обновить автомобиль по ID")
+	fmt.Println("  PATCH  /api/cars/{id}     - Частично обновить автомобиль (merge-patch или json-patch)")
 	fmt.Println("  DELETE /api/cars/{id}     - Удалить автомобиль по ID")
+	fmt.Println("  POST   /api/cars/{id}/reserve - Забронировать автомобиль (minutes — срок брони, по умолчанию сутки)")
+	fmt.Println("  POST   /api/cars/{id}/release - Снять бронь")
+	fmt.Println("  POST   /api/cars/{id}/sell  - Продать забронированный автомобиль")
+	fmt.Println("  GET    /api/cars/{id}/media - Фотографии и документы автомобиля")
+	fmt.Println("  POST   /api/cars/{id}/media - Загрузить файлы (multipart file, до 10 штук; kind: photo | document)")
+	fmt.Println("  GET    /api/cars/{id}/media/{mediaId} - Скачать файл (/thumbnail — превью фотографии)")
+	fmt.Println("  DELETE /api/cars/{id}/media/{mediaId} - Удалить файл")
 	fmt.Println("  GET    /api/dealers       - Получить всех дилеров")
 	fmt.Println("  GET    /api/dealers/{id}  - Получить дилера по его идентификатору")
-	fmt.Println("  POST   /api/dealers       - Создать нового дилера")
+	fmt.Println("  POST   /api/dealers       - Создать нового дилера (поддерживает Idempotency-Key)")
 	fmt.Println("  PUT    /api/dealers/{id}  - Обновить дилера по ID")
-	fmt.Println("  DELETE /api/dealers/{id}  - Удалить дилера по ID")
+	fmt.Println("  PATCH  /api/dealers/{id}  - Частично обновить дилера (merge-patch или json-patch)")
+	fmt.Println("  DELETE /api/dealers/{id}  - Удалить дилера по ID (?reassign_to={id} или ?cascade=true, если есть машины)")
+	fmt.Println("  GET    /api/dealers/{id}/sales - Журнал продаж дилера с итогами (from, to — даты ГГГГ-ММ-ДД)")
+	fmt.Println("  GET    /api/customers     - Покупатели (q — поиск по имени, email, телефону); POST — добавить")
+	fmt.Println("  PUT    /api/customers/{id} - Обновить покупателя (GET — получить, DELETE — удалить, если нет продаж)")
+	fmt.Println("  GET    /api/sales         - Продажи (dealer_id, customer_id, from, to)")
+	fmt.Println("  POST   /api/sales         - Записать продажу забронированной машины; машина становится проданной")
+	fmt.Println("  PUT    /api/sales/{id}    - Исправить продажу (GET — получить, DELETE — отменить и вернуть машину в наличие)")
+	fmt.Println("  GET    /api/dealers/nearby - Ближайшие дилеры по расстоянию (lat, lon, radius_km — по умолчанию 50)")
+	fmt.Println("  GET    /api/dealers/{id}/hours - Часы работы: weekly и exceptions; PUT — заменить целиком")
+	fmt.Println("  GET    /api/dealers/{id}/availability - Свободное время для тест-драйва (date, days, duration, car_id)")
+	fmt.Println("  GET    /api/bookings      - Записи на тест-драйв (dealer_id, car_id, customer_id, status, from, to)")
+	fmt.Println("  POST   /api/bookings      - Записаться на тест-драйв машины в наличии в часы работы дилера")
+	fmt.Println("  POST   /api/bookings/{id}/reschedule - Перенести запись (/cancel — отменить, GET /api/bookings/{id} — получить)")
+	fmt.Println("  GET    /api/transfers     - Передачи машин между дилерами (car_id, dealer_id, direction, status)")
+	fmt.Println("  POST   /api/transfers     - Заявка на передачу машины другому дилеру (car_id, to_dealer_id, note)")
+	fmt.Println("  POST   /api/transfers/{id}/approve - Принять заявку (/reject — отклонить); dealer_id — получатель")
+	fmt.Println("  POST   /api/transfers/{id}/complete - Передать машину получателю (/cancel — отозвать); dealer_id — отправитель")
+	fmt.Println("  GET    /api/dealers/{id}/transfers - Передачи дилера (direction: incoming | outgoing)")
+	fmt.Println("  GET    /api/cars/{id}/transfers - История передач автомобиля")
+	fmt.Println("  GET    /api/dealers/{id}/reviews - Одобренные отзывы и рейтинг дилера (limit, offset)")
+	fmt.Println("  POST   /api/dealers/{id}/reviews - Оставить отзыв (customer_id, score 1–5, text); ждёт модерации")
+	fmt.Println("  GET    /api/reviews       - Отзывы для модерации (status, dealer_id, customer_id, limit, offset)")
+	fmt.Println("  POST   /api/reviews/{id}/approve - Одобрить отзыв (/reject — отклонить, DELETE /api/reviews/{id} — удалить); рейтинг пересчитывается")
+	fmt.Println("  GET    /api/search        - Поиск машин и дилеров с опечатками и транслитерацией (q, type, limit)")
+	fmt.Println("  GET    /api/makes         - Марки каталога; POST — добавить марку")
+	fmt.Println("  PUT    /api/makes/{id}    - Переименовать марку (GET — получить, DELETE — удалить вместе с моделями)")
+	fmt.Println("  GET    /api/models        - Модели каталога (make_id); POST — добавить модель")
+	fmt.Println("  PUT    /api/models/{id}   - Переименовать или перенести модель (GET — получить, DELETE — удалить)")
+	fmt.Println("  GET    /api/catalog/conflicts - Отчёт о переносе марок и моделей в каталог")
 
 	log.Fatal(http.ListenAndServe(port, handler))
 }
 
+// prepareSchema создаёт таблицы и применяет миграции
+func prepareSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	// Получаем одно соединение для создания таблиц
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка получения соединения: %w", err)
+	}
+	defer conn.Release()
+
+	if err := simple_sql.CreateTable(ctx, conn.Conn()); err != nil {
+		return err
+	}
+
+	return migrations.Migrate(ctx, conn.Conn())
+}
+
 // importDataIfNeeded проверяет, есть ли данные в БД, и импортирует их если таблицы пустые
 func importDataIfNeeded(ctx context.Context, pool *pgxpool.Pool) {
 	// Получаем соединение из пула
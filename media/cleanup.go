@@ -0,0 +1,105 @@
+package media
+
+import (
+	"CarDealership/storage"
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// cleanupBatch — сколько файлов удаляется за одну транзакцию
+const cleanupBatch = 100
+
+// Cleaner удаляет из хранилища файлы, записи о которых удалены из базы (очередь media_deletions)
+type Cleaner struct {
+	DB      *pgxpool.Pool
+	Storage storage.Storage
+	wake    chan struct{}
+}
+
+func NewCleaner(db *pgxpool.Pool, st storage.Storage) *Cleaner {
+	return &Cleaner{DB: db, Storage: st, wake: make(chan struct{}, 1)}
+}
+
+// Notify просит фоновую очистку не ждать следующего тика; вызывается после удаления машин и вложений
+func (c *Cleaner) Notify() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run разбирает очередь и возвращает число удалённых файлов. Файлы, которые не удалось удалить,
+// остаются в очереди до следующего запуска.
+func (c *Cleaner) Run(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		deleted, full, err := c.runBatch(ctx)
+		total += deleted
+		if err != nil || deleted == 0 || !full {
+			return total, err
+		}
+	}
+}
+
+// runBatch удаляет одну пачку файлов; full — пачка была полной, и в очереди могут остаться ключи
+func (c *Cleaner) runBatch(ctx context.Context) (int, bool, error) {
+	tx, err := c.DB.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// SKIP LOCKED позволяет нескольким экземплярам приложения разбирать очередь одновременно
+	rows, err := tx.Query(ctx,
+		"SELECT storage_key FROM media_deletions ORDER BY queued_at LIMIT $1 FOR UPDATE SKIP LOCKED", cleanupBatch)
+	if err != nil {
+		return 0, false, err
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, false, err
+	}
+
+	var done []string
+	for _, key := range keys {
+		if err := c.Storage.Delete(ctx, key); err != nil {
+			log.Printf("Ошибка удаления файла %s из хранилища: %v", key, err)
+			continue
+		}
+		done = append(done, key)
+	}
+
+	if len(done) > 0 {
+		if _, err := tx.Exec(ctx, "DELETE FROM media_deletions WHERE storage_key = ANY($1)", done); err != nil {
+			return 0, false, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, err
+	}
+	return len(done), len(keys) == cleanupBatch, nil
+}
+
+// StartCleanup разбирает очередь сразу, затем раз в interval и по Notify, пока не отменён ctx
+func (c *Cleaner) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := c.Run(ctx); err != nil {
+				log.Println("Ошибка очистки файлов:", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-c.wake:
+			}
+		}
+	}()
+}
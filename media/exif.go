@@ -0,0 +1,91 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Маркеры JPEG, которые нужны для поиска EXIF
+const (
+	jpegSOI  = 0xD8
+	jpegAPP1 = 0xE1
+	jpegSOS  = 0xDA
+)
+
+// exifOrientationTag — тег ориентации в IFD0
+const exifOrientationTag = 0x0112
+
+// exifOrientation возвращает ориентацию снимка из EXIF (1–8); 1 — если EXIF нет или он повреждён.
+// Телефоны сохраняют кадр как сняла матрица и записывают поворот только в EXIF,
+// поэтому при удалении метаданных поворот нужно применить к пикселям.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != jpegSOI {
+		return 1
+	}
+
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// Заполняющие байты перед маркером
+			pos++
+			continue
+		}
+		if marker == jpegSOS {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == jpegAPP1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation читает тег ориентации из TIFF-структуры EXIF
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		// Тип SHORT, одно значение — лежит в первых двух байтах поля значения
+		value := int(order.Uint16(tiff[entry+8:]))
+		if value < 1 || value > 8 {
+			return 1
+		}
+		return value
+	}
+	return 1
+}
@@ -0,0 +1,155 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// Качество JPEG для очищенных фотографий и превью
+const (
+	photoQuality     = 90
+	thumbnailQuality = 80
+)
+
+// Image — изображение, подготовленное к сохранению
+type Image struct {
+	// Data — изображение, перекодированное без метаданных (EXIF, GPS, комментарии)
+	Data []byte
+	// Width и Height — размеры после поворота по EXIF
+	Width  int
+	Height int
+	// Thumbnail — превью в JPEG, вписанное в ThumbnailWidth×ThumbnailHeight
+	Thumbnail []byte
+}
+
+// ProcessImage проверяет изображение, поворачивает его по EXIF-ориентации и перекодирует.
+// Стандартные кодировщики не записывают метаданные, поэтому перекодирование удаляет EXIF целиком.
+func ProcessImage(data []byte, contentType string) (*Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrInvalidImage
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	var out bytes.Buffer
+	var flat *image.RGBA
+	switch contentType {
+	case TypeJPEG:
+		flat = orient(flatten(img), exifOrientation(data))
+		err = jpeg.Encode(&out, flat, &jpeg.Options{Quality: photoQuality})
+	case TypePNG:
+		// У PNG нет ориентации EXIF; сохраняем исходную палитру и прозрачность
+		flat = flatten(img)
+		err = png.Encode(&out, img)
+	default:
+		return nil, ErrUnsupportedType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, thumbnail(flat, ThumbnailWidth, ThumbnailHeight), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+
+	bounds := flat.Bounds()
+	return &Image{Data: out.Bytes(), Width: bounds.Dx(), Height: bounds.Dy(), Thumbnail: thumb.Bytes()}, nil
+}
+
+// flatten переводит изображение в RGBA с началом в (0, 0), заливая прозрачные области белым
+func flatten(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// orient применяет к пикселям поворот и отражение из EXIF-ориентации 2–8
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	// При ориентациях 5–8 ширина и высота меняются местами
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // отражение по горизонтали
+				sx, sy = w-1-x, y
+			case 3: // поворот на 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // отражение по вертикали
+				sx, sy = x, h-1-y
+			case 5: // отражение относительно главной диагонали
+				sx, sy = y, x
+			case 6: // поворот на 90° по часовой стрелке
+				sx, sy = y, h-1-x
+			case 7: // отражение относительно побочной диагонали
+				sx, sy = w-1-y, h-1-x
+			case 8: // поворот на 90° против часовой стрелки
+				sx, sy = w-1-y, x
+			}
+			si := src.PixOffset(sx, sy)
+			di := dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// thumbnail уменьшает изображение, чтобы оно вписалось в maxW×maxH, усредняя пиксели
+// каждого исходного блока; маленькие изображения не увеличиваются
+func thumbnail(src *image.RGBA, maxW, maxH int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if w > maxW || h > maxH {
+		if w*maxH > h*maxW {
+			dw, dh = maxW, max(1, h*maxW/w)
+		} else {
+			dw, dh = max(1, w*maxH/h), maxH
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			di := dst.PixOffset(x, y)
+			dst.Pix[di] = uint8(r / n)
+			dst.Pix[di+1] = uint8(g / n)
+			dst.Pix[di+2] = uint8(b / n)
+			dst.Pix[di+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
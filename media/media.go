@@ -0,0 +1,114 @@
+package media
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// Виды вложений автомобиля
+const (
+	KindPhoto    = "photo"
+	KindDocument = "document"
+)
+
+// Допустимые типы содержимого; тип определяется по самим данным, а не по заголовку клиента
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypePDF  = "application/pdf"
+)
+
+// Ограничения размеров
+const (
+	MaxPhotoSize    = 10 << 20
+	MaxDocumentSize = 20 << 20
+	// MaxPixels защищает от «бомб» — маленьких файлов с огромным разрешением
+	MaxPixels = 50_000_000
+	// ThumbnailWidth и ThumbnailHeight — рамка, в которую вписывается превью
+	ThumbnailWidth  = 320
+	ThumbnailHeight = 240
+)
+
+// Ошибки проверки файла
+var (
+	ErrUnsupportedType = errors.New("неподдерживаемый тип файла")
+	ErrTooLarge        = errors.New("файл слишком большой")
+	ErrKindMismatch    = errors.New("тип файла не подходит для этого вида вложения")
+	ErrInvalidImage    = errors.New("изображение повреждено или слишком велико")
+)
+
+// extensions — расширение сохраняемого файла по типу содержимого
+var extensions = map[string]string{
+	TypeJPEG: ".jpg",
+	TypePNG:  ".png",
+	TypePDF:  ".pdf",
+}
+
+// Detect определяет тип содержимого по первым байтам файла
+func Detect(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := extensions[contentType]; !ok {
+		return "", ErrUnsupportedType
+	}
+	return contentType, nil
+}
+
+// IsImage сообщает, что содержимое — изображение
+func IsImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+// Extension возвращает расширение файла для типа содержимого
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// ResolveKind проверяет вид вложения: фотографией может быть только изображение,
+// документом — PDF или скан. Пустой kind выбирается по типу содержимого.
+func ResolveKind(kind, contentType string) (string, error) {
+	switch kind {
+	case "":
+		if IsImage(contentType) {
+			return KindPhoto, nil
+		}
+		return KindDocument, nil
+	case KindPhoto:
+		if !IsImage(contentType) {
+			return "", ErrKindMismatch
+		}
+		return kind, nil
+	case KindDocument:
+		return kind, nil
+	}
+	return "", ErrKindMismatch
+}
+
+// MaxSize возвращает допустимый размер файла для вида вложения
+func MaxSize(kind string) int64 {
+	if kind == KindPhoto {
+		return MaxPhotoSize
+	}
+	return MaxDocumentSize
+}
+
+// CleanFileName оставляет от имени, присланного клиентом, только базовое имя без управляющих символов
+func CleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[len(runes)-255:])
+	}
+	return name
+}
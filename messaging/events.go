@@ -6,3 +6,50 @@ type CarEvent struct {
 	EventType string     `json:"eventType"`
 	Car       models.Car `json:"car"`
 }
+
+// ChangeEvent публикуется при частичном обновлении (PATCH) и содержит только изменённые поля
+type ChangeEvent struct {
+	EventType string                 `json:"eventType"`
+	Entity    string                 `json:"entity"`
+	ID        int                    `json:"id"`
+	Changes   map[string]interface{} `json:"changes"`
+}
+
+// BatchEvent публикуется одним сообщением для массовых операций (например, импорта)
+type BatchEvent struct {
+	EventType string `json:"eventType"`
+	Entity    string `json:"entity"`
+	Count     int    `json:"count"`
+}
+
+// CustomerEvent публикуется при создании, изменении и удалении покупателя
+type CustomerEvent struct {
+	EventType string          `json:"eventType"`
+	Customer  models.Customer `json:"customer"`
+}
+
+// SaleEvent публикуется при записи, изменении и отмене продажи
+type SaleEvent struct {
+	EventType string      `json:"eventType"`
+	Sale      models.Sale `json:"sale"`
+}
+
+// BookingEvent публикуется при записи на тест-драйв, её переносе и отмене
+type BookingEvent struct {
+	EventType string         `json:"eventType"`
+	Booking   models.Booking `json:"booking"`
+}
+
+// TransferEvent публикуется при каждой смене статуса передачи автомобиля между дилерами
+type TransferEvent struct {
+	EventType string          `json:"eventType"`
+	Transfer  models.Transfer `json:"transfer"`
+}
+
+// ReviewEvent публикуется при новом отзыве о дилере, его модерации и удалении.
+// Rating — рейтинг дилера после изменения.
+type ReviewEvent struct {
+	EventType string        `json:"eventType"`
+	Review    models.Review `json:"review"`
+	Rating    float64       `json:"rating"`
+}
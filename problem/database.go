@@ -0,0 +1,71 @@
+package problem
+
+import (
+	"CarDealership/middleware"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Коды ошибок PostgreSQL, которые имеют смысл для клиента
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgCheckViolation       = "23514"
+	pgExclusionViolation   = "23P01"
+	pgNotNullViolation     = "23502"
+	pgInvalidTextRepr      = "22P02"
+	pgNumericOutOfRange    = "22003"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// FromDB переводит ошибку pgx в ответ для клиента. Подробности ошибки
+// (SQL, имена таблиц) в ответ не попадают — только в лог сервера.
+func FromDB(err error) *Problem {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return New(http.StatusNotFound, CodeNotFound, "db.not_found")
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return New(http.StatusConflict, CodeUniqueViolation, "db.unique_violation")
+		case pgForeignKeyViolation:
+			// Удаление записи, на которую ещё ссылаются, — конфликт состояния;
+			// ссылка на несуществующую запись — ошибка в данных запроса
+			if strings.Contains(pgErr.Detail, "still referenced") {
+				return New(http.StatusConflict, CodeForeignKeyViolation, "db.still_referenced")
+			}
+			return New(http.StatusUnprocessableEntity, CodeForeignKeyViolation, "db.reference_missing")
+		case pgExclusionViolation:
+			return New(http.StatusConflict, CodeExclusionViolation, "db.exclusion_violation")
+		case pgCheckViolation, pgNotNullViolation, pgNumericOutOfRange, pgInvalidTextRepr:
+			return New(http.StatusUnprocessableEntity, CodeCheckViolation, "db.check_violation")
+		case pgSerializationFailure, pgDeadlockDetected:
+			return New(http.StatusConflict, CodeConcurrentUpdate, "db.concurrent_update")
+		}
+	}
+
+	return New(http.StatusInternalServerError, CodeInternal, "error.internal")
+}
+
+// ForRequest — как FromDB, но дополнительно логирует внутренние ошибки
+// с идентификатором запроса, чтобы их можно было найти по ответу клиенту
+func ForRequest(r *http.Request, err error) *Problem {
+	p := FromDB(err)
+	if p.Status >= http.StatusInternalServerError {
+		log.Printf("[%s] %s %s: %v", middleware.GetRequestID(r.Context()), r.Method, r.URL.Path, err)
+	}
+	return p
+}
+
+// DBError логирует ошибку базы данных и отвечает клиенту соответствующим статусом
+func DBError(w http.ResponseWriter, r *http.Request, err error) {
+	Write(w, r, ForRequest(r, err))
+}
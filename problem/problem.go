@@ -0,0 +1,159 @@
+package problem
+
+import (
+	"CarDealership/i18n"
+	"CarDealership/middleware"
+	"CarDealership/validation"
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType — медиатип ответов с ошибками (RFC 7807)
+const ContentType = "application/problem+json"
+
+// Машиночитаемые коды ошибок API
+const (
+	CodeBadRequest            = "bad_request"
+	CodeInvalidID             = "invalid_id"
+	CodeInvalidJSON           = "invalid_json"
+	CodeNotFound              = "not_found"
+	CodeMethodNotAllowed      = "method_not_allowed"
+	CodeValidationFailed      = "validation_failed"
+	CodeConflict              = "conflict"
+	CodeDealerHasCars         = "dealer_has_cars"
+	CodeUniqueViolation       = "unique_violation"
+	CodeForeignKeyViolation   = "foreign_key_violation"
+	CodeCheckViolation        = "check_violation"
+	CodeConcurrentUpdate      = "concurrent_update"
+	CodeUnsupportedMediaType  = "unsupported_media_type"
+	CodeInvalidPatch          = "invalid_patch"
+	CodePatchTestFailed       = "patch_test_failed"
+	CodePreconditionFailed    = "precondition_failed"
+	CodeIdempotencyMismatch   = "idempotency_key_mismatch"
+	CodeIdempotencyInProgress = "idempotency_key_in_progress"
+	CodeStorageUnavailable    = "storage_unavailable"
+	CodeInvalidTransition     = "invalid_status_transition"
+	CodeExclusionViolation    = "exclusion_violation"
+	CodeForbidden             = "forbidden"
+	CodePayloadTooLarge       = "payload_too_large"
+	CodeInternal              = "internal_error"
+)
+
+// Problem — тело ответа об ошибке в формате problem details.
+// Title и Detail заполняются при записи ответа на языке клиента.
+type Problem struct {
+	Type      string
+	Title     string
+	Status    int
+	Detail    string
+	Instance  string
+	Code      string
+	RequestID string
+	Errors    validation.Errors
+
+	// MessageKey — ключ сообщения для Detail в каталоге i18n
+	MessageKey string
+
+	// Extensions — дополнительные поля, специфичные для конкретной ошибки;
+	// они же подставляются в текст сообщения как параметры
+	Extensions map[string]interface{}
+}
+
+// New создаёт описание ошибки с типом, производным от кода, и ключом сообщения
+func New(status int, code, messageKey string) *Problem {
+	return &Problem{
+		Type:       "/problems/" + code,
+		Status:     status,
+		Code:       code,
+		MessageKey: messageKey,
+	}
+}
+
+// With добавляет в ответ дополнительное поле
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	body := make(map[string]interface{}, len(p.Extensions)+8)
+	for k, v := range p.Extensions {
+		body[k] = v
+	}
+
+	body["type"] = p.Type
+	body["title"] = p.Title
+	body["status"] = p.Status
+	body["code"] = p.Code
+	if p.Detail != "" {
+		body["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		body["instance"] = p.Instance
+	}
+	if p.RequestID != "" {
+		body["request_id"] = p.RequestID
+	}
+	if len(p.Errors) > 0 {
+		body["errors"] = p.Errors
+	}
+
+	return json.Marshal(body)
+}
+
+// Localize заполняет Title, Detail и сообщения ошибок полей на указанном языке.
+// Нужна, когда ошибка отдаётся не отдельным ответом, а внутри другого тела.
+func (p *Problem) Localize(lang i18n.Lang) {
+	if p.Title == "" {
+		p.Title = i18n.T(lang, "title."+p.Code, nil)
+	}
+	if p.Detail == "" && p.MessageKey != "" {
+		p.Detail = i18n.T(lang, p.MessageKey, p.Extensions)
+	}
+	LocalizeErrors(lang, p.Errors)
+}
+
+// LocalizeErrors заполняет сообщения ошибок полей на указанном языке
+func LocalizeErrors(lang i18n.Lang, errs validation.Errors) {
+	for i := range errs {
+		fe := &errs[i]
+		fe.Message = i18n.T(lang, "validation."+fe.Code, fe.Params)
+	}
+}
+
+// Write отправляет ошибку клиенту, дополняя её путём, идентификатором запроса
+// и текстами на языке из Accept-Language
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	lang := i18n.FromRequest(r)
+	p.Localize(lang)
+
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = middleware.GetRequestID(r.Context())
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Language", string(lang))
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error — аналог http.Error, отвечающий в формате problem details
+func Error(w http.ResponseWriter, r *http.Request, status int, code, messageKey string) {
+	Write(w, r, New(status, code, messageKey))
+}
+
+// Validation отвечает 422 со списком ошибок по полям
+func Validation(w http.ResponseWriter, r *http.Request, errs validation.Errors) {
+	p := New(http.StatusUnprocessableEntity, CodeValidationFailed, "error.validation_failed")
+	p.Errors = errs
+	Write(w, r, p)
+}
@@ -2,15 +2,30 @@ package router
 
 import (
 	"CarDealership/handlers"
+	"CarDealership/idempotency"
+	"CarDealership/problem"
 	"net/http"
 )
 
-func SetupRoutes(carsHandler *handlers.CarsHandler, dealersHandler *handlers.DealersHandler) {
+func SetupRoutes(carsHandler *handlers.CarsHandler, dealersHandler *handlers.DealersHandler, searchHandler *handlers.SearchHandler, catalogHandler *handlers.CatalogHandler, mediaHandler *handlers.MediaHandler, customersHandler *handlers.CustomersHandler, salesHandler *handlers.SalesHandler, bookingsHandler *handlers.BookingsHandler, transfersHandler *handlers.TransfersHandler, reviewsHandler *handlers.ReviewsHandler, idempotencyStore *idempotency.Store) {
+	// POST-запросы с Idempotency-Key можно безопасно повторять
+	createCar := idempotencyStore.Wrap(carsHandler.CreateCar)
+	createDealer := idempotencyStore.Wrap(dealersHandler.CreateDealer)
+	bulkCars := idempotencyStore.Wrap(carsHandler.BulkCars)
+	changeStatus := idempotencyStore.Wrap(carsHandler.ChangeStatus)
+	createCustomer := idempotencyStore.Wrap(customersHandler.CreateCustomer)
+	createSale := idempotencyStore.Wrap(salesHandler.CreateSale)
+	createBooking := idempotencyStore.Wrap(bookingsHandler.CreateBooking)
+	createTransfer := idempotencyStore.Wrap(transfersHandler.CreateTransfer)
+	changeTransferStatus := idempotencyStore.Wrap(transfersHandler.ChangeTransferStatus)
+	createReview := idempotencyStore.Wrap(reviewsHandler.CreateDealerReview)
+	moderateReview := idempotencyStore.Wrap(reviewsHandler.ModerateReview)
+
 	// Обработчики для автомобилей
 	http.HandleFunc("/api/cars", func(w http.ResponseWriter, r *http.Request) {
 		// CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == "OPTIONS" {
@@ -29,17 +44,118 @@ func SetupRoutes(carsHandler *handlers.CarsHandler, dealersHandler *handlers.Dea
 				carsHandler.GetAllCars(w, r)
 			}
 		case http.MethodPost:
-			carsHandler.CreateCar(w, r)
+			createCar(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Автомобили без дилера
+	http.HandleFunc("/api/cars/unassigned", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			carsHandler.GetUnassignedCars(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Массовые операции над автомобилями
+	http.HandleFunc("/api/cars/bulk", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			bulkCars(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Поиск автомобиля по VIN
+	http.HandleFunc("/api/cars/by-vin/", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			carsHandler.GetCarByVIN(w, r)
 		default:
-			http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
 		}
 	})
 
-	// Отдельный обработчик для PUT и DELETE автомобилей
+	// Выгрузка автомобилей в CSV/XLSX/JSON
+	http.HandleFunc("/api/cars/export", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			carsHandler.ExportCars(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Импорт автомобилей из CSV/XLSX
+	http.HandleFunc("/api/import/cars", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			// Импорт не идемпотентен по ключу: ради хеша пришлось бы держать в памяти файл до maxImportSize
+			carsHandler.ImportCars(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Отдельный обработчик для PUT, PATCH и DELETE автомобилей
 	http.HandleFunc("/api/cars/", func(w http.ResponseWriter, r *http.Request) {
 		// CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == "OPTIONS" {
@@ -47,15 +163,39 @@ func SetupRoutes(carsHandler *handlers.CarsHandler, dealersHandler *handlers.Dea
 			return
 		}
 
+		// Смена статуса: /api/cars/{id}/reserve, /release, /sell
+		if handlers.IsStatusPath(r.URL.Path) {
+			changeStatus(w, r)
+			return
+		}
+
+		// История передач между дилерами: /api/cars/{id}/transfers
+		if handlers.IsCarTransfersPath(r.URL.Path) {
+			if r.Method != http.MethodGet {
+				problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+				return
+			}
+			transfersHandler.GetCarTransfers(w, r)
+			return
+		}
+
+		// Фотографии и документы: /api/cars/{id}/media...
+		if handlers.IsMediaPath(r.URL.Path) {
+			mediaHandler.ServeHTTP(w, r)
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
 			carsHandler.GetCarByID(w, r)
 		case http.MethodPut:
 			carsHandler.UpdateCar(w, r)
+		case http.MethodPatch:
+			carsHandler.PatchCar(w, r)
 		case http.MethodDelete:
 			carsHandler.DeleteCar(w, r)
 		default:
-			http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
 		}
 	})
 
@@ -63,7 +203,7 @@ func SetupRoutes(carsHandler *handlers.CarsHandler, dealersHandler *handlers.Dea
 	http.HandleFunc("/api/dealers", func(w http.ResponseWriter, r *http.Request) {
 		// CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == "OPTIONS" {
@@ -80,17 +220,37 @@ func SetupRoutes(carsHandler *handlers.CarsHandler, dealersHandler *handlers.Dea
 				dealersHandler.GetAllDealers(w, r)
 			}
 		case http.MethodPost:
-			dealersHandler.CreateDealer(w, r)
+			createDealer(w, r)
 		default:
-			http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
 		}
 	})
 
-	// Отдельный обработчик для PUT и DELETE дилеров
+	// Ближайшие дилеры: /api/dealers/nearby?lat=&lon=&radius_km=
+	http.HandleFunc("/api/dealers/nearby", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			dealersHandler.GetNearbyDealers(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Отдельный обработчик для PUT, PATCH и DELETE дилеров
 	http.HandleFunc("/api/dealers/", func(w http.ResponseWriter, r *http.Request) {
 		// CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == "OPTIONS" {
@@ -98,15 +258,431 @@ func SetupRoutes(carsHandler *handlers.CarsHandler, dealersHandler *handlers.Dea
 			return
 		}
 
+		// Вложенные ресурсы дилера: /api/dealers/{id}/sales, /hours, /availability, /transfers, /reviews
+		switch handlers.DealerSubresource(r.URL.Path) {
+		case "reviews":
+			switch r.Method {
+			case http.MethodGet:
+				reviewsHandler.GetDealerReviews(w, r)
+			case http.MethodPost:
+				createReview(w, r)
+			default:
+				problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+			}
+			return
+		case "transfers":
+			if r.Method != http.MethodGet {
+				problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+				return
+			}
+			transfersHandler.GetDealerTransfers(w, r)
+			return
+		case "sales":
+			if r.Method != http.MethodGet {
+				problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+				return
+			}
+			salesHandler.GetDealerSales(w, r)
+			return
+		case "availability":
+			if r.Method != http.MethodGet {
+				problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+				return
+			}
+			bookingsHandler.GetAvailability(w, r)
+			return
+		case "hours":
+			switch r.Method {
+			case http.MethodGet:
+				dealersHandler.GetDealerHours(w, r)
+			case http.MethodPut:
+				dealersHandler.UpdateDealerHours(w, r)
+			default:
+				problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+			}
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
 			dealersHandler.GetDealerByID(w, r)
 		case http.MethodPut:
 			dealersHandler.UpdateDealer(w, r)
+		case http.MethodPatch:
+			dealersHandler.PatchDealer(w, r)
 		case http.MethodDelete:
 			dealersHandler.DeleteDealer(w, r)
 		default:
-			http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Обработчики для покупателей
+	http.HandleFunc("/api/customers", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			customersHandler.GetAllCustomers(w, r)
+		case http.MethodPost:
+			createCustomer(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Отдельный обработчик для GET, PUT и DELETE покупателей
+	http.HandleFunc("/api/customers/", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			customersHandler.GetCustomerByID(w, r)
+		case http.MethodPut:
+			customersHandler.UpdateCustomer(w, r)
+		case http.MethodDelete:
+			customersHandler.DeleteCustomer(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Обработчики для продаж
+	http.HandleFunc("/api/sales", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			salesHandler.GetAllSales(w, r)
+		case http.MethodPost:
+			createSale(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Отдельный обработчик для GET, PUT и DELETE продаж
+	http.HandleFunc("/api/sales/", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			salesHandler.GetSaleByID(w, r)
+		case http.MethodPut:
+			salesHandler.UpdateSale(w, r)
+		case http.MethodDelete:
+			salesHandler.DeleteSale(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Обработчики для записей на тест-драйв
+	http.HandleFunc("/api/bookings", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			bookingsHandler.GetAllBookings(w, r)
+		case http.MethodPost:
+			createBooking(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Запись по ID, отмена и перенос: /api/bookings/{id}/cancel, /reschedule
+	http.HandleFunc("/api/bookings/", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch handlers.BookingAction(r.URL.Path) {
+		case "cancel":
+			bookingsHandler.CancelBooking(w, r)
+			return
+		case "reschedule":
+			bookingsHandler.RescheduleBooking(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			bookingsHandler.GetBookingByID(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Обработчики для передач автомобилей между дилерами
+	http.HandleFunc("/api/transfers", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			transfersHandler.GetAllTransfers(w, r)
+		case http.MethodPost:
+			createTransfer(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Передача по ID и смена её статуса: /api/transfers/{id}/approve, /reject, /cancel, /complete
+	http.HandleFunc("/api/transfers/", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if handlers.TransferAction(r.URL.Path) != "" {
+			changeTransferStatus(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			transfersHandler.GetTransferByID(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Отзывы о дилерах для модерации: GET /api/reviews?status=pending
+	http.HandleFunc("/api/reviews", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			reviewsHandler.GetAllReviews(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Отзыв по ID, его удаление и модерация: /api/reviews/{id}/approve, /reject
+	http.HandleFunc("/api/reviews/", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if handlers.ReviewAction(r.URL.Path) != "" {
+			moderateReview(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			reviewsHandler.GetReviewByID(w, r)
+		case http.MethodDelete:
+			reviewsHandler.DeleteReview(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Поиск по автомобилям и дилерам
+	http.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			searchHandler.Search(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Каталог марок
+	http.HandleFunc("/api/makes", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			catalogHandler.GetAllMakes(w, r)
+		case http.MethodPost:
+			catalogHandler.CreateMake(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Марка каталога по ID
+	http.HandleFunc("/api/makes/", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			catalogHandler.GetMakeByID(w, r)
+		case http.MethodPut:
+			catalogHandler.UpdateMake(w, r)
+		case http.MethodDelete:
+			catalogHandler.DeleteMake(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Каталог моделей
+	http.HandleFunc("/api/models", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			catalogHandler.GetAllModels(w, r)
+		case http.MethodPost:
+			catalogHandler.CreateModel(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Модель каталога по ID
+	http.HandleFunc("/api/models/", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			catalogHandler.GetModelByID(w, r)
+		case http.MethodPut:
+			catalogHandler.UpdateModel(w, r)
+		case http.MethodDelete:
+			catalogHandler.DeleteModel(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
+		}
+	})
+
+	// Отчёт о переносе марок и моделей в каталог
+	http.HandleFunc("/api/catalog/conflicts", func(w http.ResponseWriter, r *http.Request) {
+		// CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			catalogHandler.GetConflicts(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "error.method_not_allowed")
 		}
 	})
 }
@@ -0,0 +1,186 @@
+package schedule
+
+import (
+	"CarDealership/database/models"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Ограничения записи на тест-драйв
+const (
+	MinDuration     = 15 * time.Minute
+	MaxDuration     = 3 * time.Hour
+	DefaultDuration = time.Hour
+	// Step — шаг, с которым предлагаются свободные слоты
+	Step = 30 * time.Minute
+	// MaxAdvance — насколько вперёд можно записаться
+	MaxAdvance = 90 * 24 * time.Hour
+)
+
+// Форматы времени в часах работы и дат исключений
+const (
+	ClockLayout = "15:04"
+	DateLayout  = "2006-01-02"
+)
+
+// Location — часовой пояс, в котором заданы часы работы дилеров; по умолчанию — пояс сервера (TZ)
+var Location = time.Local
+
+// Interval — полуинтервал времени [Start, End)
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Overlaps сообщает, что интервалы пересекаются; касание концами пересечением не считается
+func (i Interval) Overlaps(other Interval) bool {
+	return i.Start.Before(other.End) && other.Start.Before(i.End)
+}
+
+// day — часы работы в один день: минуты от полуночи
+type day struct {
+	opens, closes int
+}
+
+// Week — расписание дилера: часы по дням недели (1 — понедельник, 7 — воскресенье; дня нет — выходной)
+// и исключения по датам, которые заменяют обычные часы; исключение nil — дилер в этот день закрыт
+type Week struct {
+	days       map[int]day
+	exceptions map[string]*day
+}
+
+// ParseClock разбирает время ЧЧ:ММ и возвращает число минут от полуночи
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse(ClockLayout, s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// NewWeek строит расписание из часов работы, сохранённых в базе
+func NewWeek(hours models.OpeningHours) (Week, error) {
+	week := Week{days: make(map[int]day, len(hours.Weekly)), exceptions: make(map[string]*day, len(hours.Exceptions))}
+	for _, h := range hours.Weekly {
+		d, err := parseDay(h.Opens, h.Closes)
+		if err != nil {
+			return Week{}, err
+		}
+		week.days[h.Weekday] = d
+	}
+	for _, e := range hours.Exceptions {
+		if e.Opens == "" && e.Closes == "" {
+			week.exceptions[e.Date] = nil
+			continue
+		}
+		d, err := parseDay(e.Opens, e.Closes)
+		if err != nil {
+			return Week{}, fmt.Errorf("исключение %s: %w", e.Date, err)
+		}
+		week.exceptions[e.Date] = &d
+	}
+	return week, nil
+}
+
+func parseDay(opens, closes string) (day, error) {
+	o, err := ParseClock(opens)
+	if err != nil {
+		return day{}, fmt.Errorf("время открытия %q: %w", opens, err)
+	}
+	c, err := ParseClock(closes)
+	if err != nil {
+		return day{}, fmt.Errorf("время закрытия %q: %w", closes, err)
+	}
+	return day{opens: o, closes: c}, nil
+}
+
+// Weekday возвращает день недели в нумерации ISO: 1 — понедельник, 7 — воскресенье
+func Weekday(t time.Time) int {
+	if wd := int(t.Weekday()); wd != 0 {
+		return wd
+	}
+	return 7
+}
+
+// hoursOn возвращает время открытия и закрытия в день date; ok = false — дилер в этот день закрыт
+func (w Week) hoursOn(date time.Time) (opens, closes time.Time, ok bool) {
+	date = date.In(Location)
+	d, ok := w.days[Weekday(date)]
+	if e, exception := w.exceptions[date.Format(DateLayout)]; exception {
+		if e != nil {
+			d = *e
+		}
+		ok = e != nil
+	}
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	y, m, dd := date.Date()
+	opens = time.Date(y, m, dd, 0, d.opens, 0, 0, Location)
+	closes = time.Date(y, m, dd, 0, d.closes, 0, 0, Location)
+	return opens, closes, true
+}
+
+// Within сообщает, что интервал целиком приходится на часы работы одного дня
+func (w Week) Within(i Interval) bool {
+	opens, closes, ok := w.hoursOn(i.Start)
+	return ok && !i.Start.Before(opens) && !i.End.After(closes)
+}
+
+// Slots возвращает слоты длительностью duration с шагом Step в часы работы
+// за days дней, начиная с дня from. Слоты, начинающиеся раньше notBefore, пропускаются.
+func (w Week) Slots(from time.Time, days int, duration time.Duration, notBefore time.Time) []Interval {
+	var slots []Interval
+	y, m, d := from.In(Location).Date()
+	for n := 0; n < days; n++ {
+		opens, closes, ok := w.hoursOn(time.Date(y, m, d+n, 12, 0, 0, 0, Location))
+		if !ok {
+			continue
+		}
+		for start := opens; !start.Add(duration).After(closes); start = start.Add(Step) {
+			if start.Before(notBefore) {
+				continue
+			}
+			slots = append(slots, Interval{Start: start, End: start.Add(duration)})
+		}
+	}
+	return slots
+}
+
+// Busy — занятые интервалы по машинам
+type Busy map[int][]Interval
+
+// FreeSlot — свободный слот и машины, на которые в это время можно записаться
+type FreeSlot struct {
+	Interval
+	CarIDs []int `json:"car_ids"`
+}
+
+// Free возвращает для каждого слота машины из cars, у которых слот не пересекается
+// ни с одной записью; слоты без свободных машин пропускаются
+func Free(slots []Interval, cars []int, busy Busy) []FreeSlot {
+	sort.Ints(cars)
+	free := []FreeSlot{}
+	for _, slot := range slots {
+		var available []int
+		for _, car := range cars {
+			if !overlapsAny(slot, busy[car]) {
+				available = append(available, car)
+			}
+		}
+		if len(available) > 0 {
+			free = append(free, FreeSlot{Interval: slot, CarIDs: available})
+		}
+	}
+	return free
+}
+
+func overlapsAny(slot Interval, list []Interval) bool {
+	for _, i := range list {
+		if slot.Overlaps(i) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package schedule
+
+import (
+	"CarDealership/database/models"
+	"os"
+	"testing"
+	"time"
+)
+
+// Тесты не должны зависеть от TZ машины: часы работы считаются в фиксированном поясе UTC+3
+var msk = time.FixedZone("UTC+3", 3*60*60)
+
+func TestMain(m *testing.M) {
+	Location = msk
+	os.Exit(m.Run())
+}
+
+// testWeek — будни 09:00–18:00, суббота 10:00–16:00, воскресенье выходной.
+// 2026-03-09 (понедельник) — выходной, 2026-03-10 (вторник) — сокращённый день до 14:00,
+// 2026-03-08 (воскресенье) — рабочий 11:00–13:00.
+func testWeek(t *testing.T) Week {
+	t.Helper()
+	hours := models.OpeningHours{
+		Exceptions: []models.HoursException{
+			{Date: "2026-03-08", Opens: "11:00", Closes: "13:00", Note: "Ярмарка"},
+			{Date: "2026-03-09", Note: "Праздник"},
+			{Date: "2026-03-10", Opens: "09:00", Closes: "14:00", Note: "Сокращённый день"},
+		},
+	}
+	for wd := 1; wd <= 5; wd++ {
+		hours.Weekly = append(hours.Weekly, models.DealerHours{Weekday: wd, Opens: "09:00", Closes: "18:00"})
+	}
+	hours.Weekly = append(hours.Weekly, models.DealerHours{Weekday: 6, Opens: "10:00", Closes: "16:00"})
+
+	week, err := NewWeek(hours)
+	if err != nil {
+		t.Fatalf("NewWeek: %v", err)
+	}
+	return week
+}
+
+// at возвращает момент времени в поясе Location
+func at(date string, hour, min int) time.Time {
+	d, err := time.ParseInLocation(DateLayout, date, msk)
+	if err != nil {
+		panic(err)
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), hour, min, 0, 0, msk)
+}
+
+func TestHoursOn(t *testing.T) {
+	week := testWeek(t)
+	tests := []struct {
+		name          string
+		date          string
+		ok            bool
+		opens, closes time.Time
+	}{
+		{"обычный будний день", "2026-03-03", true, at("2026-03-03", 9, 0), at("2026-03-03", 18, 0)},
+		{"суббота", "2026-03-07", true, at("2026-03-07", 10, 0), at("2026-03-07", 16, 0)},
+		{"воскресенье — выходной", "2026-03-01", false, time.Time{}, time.Time{}},
+		{"рабочее воскресенье по исключению", "2026-03-08", true, at("2026-03-08", 11, 0), at("2026-03-08", 13, 0)},
+		{"закрыт по исключению", "2026-03-09", false, time.Time{}, time.Time{}},
+		{"сокращённый день", "2026-03-10", true, at("2026-03-10", 9, 0), at("2026-03-10", 14, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opens, closes, ok := week.hoursOn(at(tt.date, 12, 0))
+			if ok != tt.ok || !opens.Equal(tt.opens) || !closes.Equal(tt.closes) {
+				t.Errorf("hoursOn(%s) = %v, %v, %v; ожидалось %v, %v, %v",
+					tt.date, opens, closes, ok, tt.opens, tt.closes, tt.ok)
+			}
+		})
+	}
+}
+
+// День определяется в поясе Location, даже если время передано в UTC
+func TestHoursOnLocation(t *testing.T) {
+	week := testWeek(t)
+	// 2026-03-08 22:30 UTC — это уже понедельник 2026-03-09 01:30, а он закрыт
+	if _, _, ok := week.hoursOn(time.Date(2026, 3, 8, 22, 30, 0, 0, time.UTC)); ok {
+		t.Error("hoursOn: день определён по UTC, а не по Location")
+	}
+}
+
+func TestWithin(t *testing.T) {
+	week := testWeek(t)
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       bool
+	}{
+		{"в середине дня", at("2026-03-03", 12, 0), at("2026-03-03", 13, 0), true},
+		{"с открытия", at("2026-03-03", 9, 0), at("2026-03-03", 10, 0), true},
+		{"заканчивается ровно в закрытие", at("2026-03-03", 17, 0), at("2026-03-03", 18, 0), true},
+		{"заканчивается после закрытия", at("2026-03-03", 17, 30), at("2026-03-03", 18, 30), false},
+		{"начинается до открытия", at("2026-03-03", 8, 30), at("2026-03-03", 9, 30), false},
+		{"в выходной", at("2026-03-01", 12, 0), at("2026-03-01", 13, 0), false},
+		{"в день, закрытый по исключению", at("2026-03-09", 12, 0), at("2026-03-09", 13, 0), false},
+		{"до конца сокращённого дня", at("2026-03-10", 13, 0), at("2026-03-10", 14, 0), true},
+		{"после конца сокращённого дня", at("2026-03-10", 14, 0), at("2026-03-10", 15, 0), false},
+		{"время в UTC", time.Date(2026, 3, 3, 14, 0, 0, 0, time.UTC), time.Date(2026, 3, 3, 15, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := week.Within(Interval{Start: tt.start, End: tt.end}); got != tt.want {
+				t.Errorf("Within(%v–%v) = %v; ожидалось %v", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlots(t *testing.T) {
+	week := testWeek(t)
+
+	t.Run("сокращённый день после закрытого", func(t *testing.T) {
+		// Понедельник закрыт, во вторник слоты по часу с 09:00 до 13:00 с шагом 30 минут
+		slots := week.Slots(at("2026-03-09", 0, 0), 2, time.Hour, time.Time{})
+		if len(slots) != 9 {
+			t.Fatalf("Slots: %d слотов; ожидалось 9: %v", len(slots), slots)
+		}
+		if first := slots[0]; !first.Start.Equal(at("2026-03-10", 9, 0)) {
+			t.Errorf("первый слот начинается в %v; ожидалось 09:00 вторника", first.Start)
+		}
+		// Последний слот заканчивается ровно в закрытие
+		last := slots[len(slots)-1]
+		if !last.Start.Equal(at("2026-03-10", 13, 0)) || !last.End.Equal(at("2026-03-10", 14, 0)) {
+			t.Errorf("последний слот %v–%v; ожидалось 13:00–14:00", last.Start, last.End)
+		}
+		for _, slot := range slots {
+			if !week.Within(slot) {
+				t.Errorf("слот %v–%v вне часов работы", slot.Start, slot.End)
+			}
+		}
+	})
+
+	t.Run("notBefore", func(t *testing.T) {
+		slots := week.Slots(at("2026-03-10", 0, 0), 1, time.Hour, at("2026-03-10", 12, 10))
+		if len(slots) != 2 || !slots[0].Start.Equal(at("2026-03-10", 12, 30)) {
+			t.Errorf("Slots = %v; ожидались слоты с 12:30 и 13:00", slots)
+		}
+	})
+
+	t.Run("длительность не кратна шагу", func(t *testing.T) {
+		// Суббота 10:00–16:00, слоты по 45 минут: последний начинается в 15:00 и заканчивается в 15:45
+		slots := week.Slots(at("2026-03-07", 0, 0), 1, 45*time.Minute, time.Time{})
+		if len(slots) != 11 {
+			t.Fatalf("Slots: %d слотов; ожидалось 11", len(slots))
+		}
+		if last := slots[len(slots)-1]; !last.End.Equal(at("2026-03-07", 15, 45)) {
+			t.Errorf("последний слот заканчивается в %v; ожидалось 15:45", last.End)
+		}
+	})
+
+	t.Run("выходные дни пропускаются", func(t *testing.T) {
+		// Воскресенье 2026-03-01 закрыто, рабочее воскресенье 2026-03-08 — 11:00–13:00
+		if slots := week.Slots(at("2026-03-01", 0, 0), 1, time.Hour, time.Time{}); len(slots) != 0 {
+			t.Errorf("Slots в выходной = %v; ожидалось пусто", slots)
+		}
+		if slots := week.Slots(at("2026-03-08", 0, 0), 1, time.Hour, time.Time{}); len(slots) != 3 {
+			t.Errorf("Slots в рабочее воскресенье = %v; ожидалось 3 слота", slots)
+		}
+	})
+}
+
+func TestFree(t *testing.T) {
+	slots := []Interval{
+		{Start: at("2026-03-03", 9, 0), End: at("2026-03-03", 10, 0)},
+		{Start: at("2026-03-03", 10, 0), End: at("2026-03-03", 11, 0)},
+	}
+	busy := Busy{
+		1: {{Start: at("2026-03-03", 9, 30), End: at("2026-03-03", 10, 0)}},
+		2: {{Start: at("2026-03-03", 9, 0), End: at("2026-03-03", 11, 0)}},
+	}
+	free := Free(slots, []int{2, 1}, busy)
+	// Запись машины 1 касается второго слота концом и не мешает ему; машина 2 занята всё время
+	if len(free) != 1 || !free[0].Start.Equal(slots[1].Start) || len(free[0].CarIDs) != 1 || free[0].CarIDs[0] != 1 {
+		t.Errorf("Free = %+v; ожидался один слот 10:00 с машиной 1", free)
+	}
+}
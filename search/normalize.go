@@ -0,0 +1,153 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MaxWords — сколько слов запроса учитывается; остальные отбрасываются
+const MaxWords = 8
+
+// Words разбивает строку на слова в нижнем регистре: буквы и цифры, остальное — разделители.
+// Ё приравнивается к е, чтобы «Могилёв» и «Могилев» совпадали.
+func Words(s string) []string {
+	s = strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Variants возвращает варианты написания запроса, по которым ищутся совпадения:
+// исходные слова с исправленными смешанными раскладками, транслитерацию кириллицы
+// латиницей (в том числе с «c» вместо «k», как в Camry и Corolla) и латиницы кириллицей.
+// Так «Тойота Камри» находит Toyota Camry, а «Minsk» — Минск.
+// Каждый вариант — список слов; одинаковые варианты не повторяются.
+func Variants(query string) [][]string {
+	words := Words(query)
+	if len(words) > MaxWords {
+		words = words[:MaxWords]
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	folded := make([]string, len(words))
+	latin := make([]string, len(words))
+	latinC := make([]string, len(words))
+	cyrillic := make([]string, len(words))
+	for i, w := range words {
+		folded[i] = foldHomoglyphs(w)
+		latin[i] = toLatin(folded[i])
+		latinC[i] = hardC.ReplaceAllString(latin[i], "c$1")
+		cyrillic[i] = toCyrillic(folded[i])
+	}
+
+	var result [][]string
+	seen := map[string]bool{}
+	for _, v := range [][]string{folded, latin, latinC, cyrillic} {
+		key := strings.Join(v, " ")
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// homoglyphs — кириллические буквы, неотличимые на вид от латинских
+var homoglyphs = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i',
+}
+
+var homoglyphsReverse = func() map[rune]rune {
+	m := make(map[rune]rune, len(homoglyphs))
+	for cyr, lat := range homoglyphs {
+		m[lat] = cyr
+	}
+	return m
+}()
+
+// foldHomoglyphs приводит слово, набранное вперемешку кириллицей и латиницей
+// (например, «Tоyota» с русской «о»), к алфавиту, которого в слове больше
+func foldHomoglyphs(word string) string {
+	var cyr, lat int
+	for _, r := range word {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyr++
+		case unicode.Is(unicode.Latin, r):
+			lat++
+		}
+	}
+	if cyr == 0 || lat == 0 {
+		return word
+	}
+
+	table := homoglyphs
+	if cyr > lat {
+		table = homoglyphsReverse
+	}
+	return strings.Map(func(r rune) rune {
+		if to, ok := table[r]; ok {
+			return to
+		}
+		return r
+	}, word)
+}
+
+// cyrToLat — транслитерация кириллицы (русской и белорусской) латиницей
+var cyrToLat = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ў': "u",
+}
+
+// hardC — «к» перед гласными a, o, u и согласными l, r в латинских названиях обычно пишется как «c»
+var hardC = regexp.MustCompile(`k([aoulr])`)
+
+func toLatin(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		if s, ok := cyrToLat[r]; ok {
+			b.WriteString(s)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// latToCyr — обратная транслитерация; сочетания букв проверяются раньше одиночных
+var latToCyr = []struct{ lat, cyr string }{
+	{"shch", "щ"}, {"sch", "щ"}, {"zh", "ж"}, {"kh", "х"}, {"ts", "ц"}, {"ch", "ч"},
+	{"sh", "ш"}, {"yu", "ю"}, {"ya", "я"},
+	{"a", "а"}, {"b", "б"}, {"c", "к"}, {"d", "д"}, {"e", "е"}, {"f", "ф"}, {"g", "г"},
+	{"h", "х"}, {"i", "и"}, {"j", "й"}, {"k", "к"}, {"l", "л"}, {"m", "м"}, {"n", "н"},
+	{"o", "о"}, {"p", "п"}, {"q", "к"}, {"r", "р"}, {"s", "с"}, {"t", "т"}, {"u", "у"},
+	{"v", "в"}, {"w", "в"}, {"x", "кс"}, {"y", "й"}, {"z", "з"},
+}
+
+func toCyrillic(word string) string {
+	var b strings.Builder
+	for rest := word; rest != ""; {
+		matched := false
+		for _, p := range latToCyr {
+			if strings.HasPrefix(rest, p.lat) {
+				b.WriteString(p.cyr)
+				rest = rest[len(p.lat):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			r := []rune(rest)[0]
+			b.WriteRune(r)
+			rest = rest[len(string(r)):]
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TSQuery строит запрос для to_tsquery('simple', ...): внутри варианта слова
+// соединяются через & и ищутся по префиксу, варианты — через |.
+// Слова уже содержат только буквы и цифры (см. Words), поэтому экранирование не нужно.
+func TSQuery(variants [][]string) string {
+	parts := make([]string, 0, len(variants))
+	for _, words := range variants {
+		terms := make([]string, len(words))
+		for i, w := range words {
+			terms[i] = w + ":*"
+		}
+		parts = append(parts, "("+strings.Join(terms, " & ")+")")
+	}
+	return strings.Join(parts, " | ")
+}
+
+// MatchThreshold — минимальное сходство слова запроса со словом записи по триграммам.
+// То же значение задаётся в pg_trgm.word_similarity_threshold, чтобы подсветка совпадала с выдачей.
+const MatchThreshold = 0.45
+
+// Highlight возвращает значение поля, экранированное для HTML, в котором слова,
+// похожие на слова запроса (с опечатками и в любом из вариантов написания), обёрнуты в <mark>.
+// Если совпадений нет, второй результат равен false.
+func Highlight(value string, variants [][]string) (string, bool) {
+	var b strings.Builder
+	found := false
+	start := -1
+
+	flush := func(end int) {
+		token := value[start:end]
+		if matchesAny(token, variants) {
+			found = true
+			b.WriteString("<mark>" + html.EscapeString(token) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(token))
+		}
+		start = -1
+	}
+
+	for i, r := range value {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			flush(i)
+		}
+		b.WriteString(html.EscapeString(string(r)))
+	}
+	if start >= 0 {
+		flush(len(value))
+	}
+	return b.String(), found
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// matchesAny сообщает, похоже ли слово записи на какое-нибудь слово запроса
+func matchesAny(token string, variants [][]string) bool {
+	words := Words(token)
+	if len(words) != 1 {
+		return false
+	}
+	word := words[0]
+	for _, v := range variants {
+		for _, q := range v {
+			// Префикс из одной буквы совпал бы почти со всем
+			if utf8.RuneCountInString(q) > 1 && strings.HasPrefix(word, q) || WordSimilarity(q, word) >= MatchThreshold {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WordSimilarity — сходство слова запроса со словом записи, как word_similarity() в pg_trgm:
+// доля триграмм запроса, которые нашлись в слове записи
+func WordSimilarity(query, word string) float64 {
+	ta, tb := trigrams(query), trigrams(word)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta))
+}
+
+// trigrams возвращает триграммы слова; как в pg_trgm, слово дополняется
+// двумя пробелами в начале и одним в конце
+func trigrams(word string) map[string]bool {
+	if utf8.RuneCountInString(word) == 0 {
+		return nil
+	}
+	r := []rune("  " + word + " ")
+	set := make(map[string]bool, len(r)-2)
+	for i := 0; i+3 <= len(r); i++ {
+		set[string(r[i:i+3])] = true
+	}
+	return set
+}
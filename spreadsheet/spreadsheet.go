@@ -0,0 +1,97 @@
+package spreadsheet
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Форматы табличных файлов
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// ErrUnknownFormat — формат файла не поддерживается
+var ErrUnknownFormat = errors.New("spreadsheet: неподдерживаемый формат файла")
+
+// RowReader читает таблицу построчно; в конце возвращает io.EOF
+type RowReader interface {
+	Read() ([]string, error)
+}
+
+// RowWriter пишет таблицу построчно; Close дописывает служебные части файла
+type RowWriter interface {
+	Write(row []string) error
+	Close() error
+}
+
+// FormatFromName определяет формат по расширению файла
+func FormatFromName(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// NewReader открывает таблицу указанного формата. Для XLSX нужен произвольный доступ к файлу.
+func NewReader(format string, r io.ReaderAt, size int64) (RowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(io.NewSectionReader(r, 0, size)), nil
+	case FormatXLSX:
+		return NewXLSXReader(r, size)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// NewWriter создаёт потоковую запись таблицы указанного формата
+func NewWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		return NewXLSXWriter(w)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// newCSVReader читает CSV с разделителем «,» или «;» (его использует Excel в русской локали)
+// и пропускает BOM в начале файла
+func newCSVReader(r io.Reader) RowReader {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+
+	// Разделитель определяем по первой строке
+	head, _ := br.Peek(br.Size())
+	firstLine, _, _ := strings.Cut(string(head), "\n")
+
+	cr := csv.NewReader(br)
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	return cr
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(row []string) error {
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
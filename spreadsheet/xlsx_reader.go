@@ -0,0 +1,275 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// XLSXReader читает первый лист книги Excel (Office Open XML) построчно.
+// Поддерживаются общие и встроенные строки, числа и логические значения;
+// формулы отдаются последним вычисленным значением.
+type XLSXReader struct {
+	shared  []string
+	decoder *xml.Decoder
+	sheet   io.ReadCloser
+	nextRow int
+	pending []string
+}
+
+func NewXLSXReader(r io.ReaderAt, size int64) (*XLSXReader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("spreadsheet: файл не является XLSX: %v", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := readSharedStrings(files)
+	if err != nil {
+		return nil, err
+	}
+
+	sheetFile, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("spreadsheet: в книге нет листа %s", sheetPath)
+	}
+	sheet, err := sheetFile.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	return &XLSXReader{
+		shared:  shared,
+		decoder: xml.NewDecoder(sheet),
+		sheet:   sheet,
+		nextRow: 1,
+	}, nil
+}
+
+// Read возвращает следующую строку листа. Пропущенные в файле пустые строки
+// возвращаются как пустые, чтобы номера строк совпадали с Excel.
+func (x *XLSXReader) Read() ([]string, error) {
+	if x.pending != nil {
+		return x.take(), nil
+	}
+
+	for {
+		tok, err := x.decoder.Token()
+		if err == io.EOF {
+			x.sheet.Close()
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := x.decoder.DecodeElement(&row, &start); err != nil {
+			return nil, err
+		}
+
+		number := row.Number
+		if number == 0 {
+			number = x.nextRow
+		}
+		values := x.rowValues(row)
+
+		if number > x.nextRow {
+			// Между прочитанными строками были пустые
+			x.pending = values
+			x.nextRow++
+			return []string{}, nil
+		}
+
+		x.nextRow = number + 1
+		return values, nil
+	}
+}
+
+func (x *XLSXReader) take() []string {
+	row := x.pending
+	x.pending = nil
+	return row
+}
+
+func (x *XLSXReader) rowValues(row xlsxRow) []string {
+	var values []string
+	for i, c := range row.Cells {
+		col := i
+		if c.Ref != "" {
+			if parsed, ok := columnIndex(c.Ref); ok {
+				col = parsed
+			}
+		}
+		for len(values) <= col {
+			values = append(values, "")
+		}
+		values[col] = x.cellValue(c)
+	}
+	return values
+}
+
+func (x *XLSXReader) cellValue(c xlsxCell) string {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(c.Value))
+		if err != nil || i < 0 || i >= len(x.shared) {
+			return ""
+		}
+		return x.shared[i]
+	case "inlineStr":
+		return c.Inline.text()
+	case "b":
+		if c.Value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return c.Value
+}
+
+// columnIndex переводит адрес ячейки (например, "C12") в номер колонки с нуля
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+type xlsxRow struct {
+	Number int        `xml:"r,attr"`
+	Cells  []xlsxCell `xml:"c"`
+}
+
+type xlsxCell struct {
+	Ref    string     `xml:"r,attr"`
+	Type   string     `xml:"t,attr"`
+	Value  string     `xml:"v"`
+	Inline xlsxString `xml:"is"`
+}
+
+// xlsxString — строка, возможно разбитая на фрагменты с разным форматированием
+type xlsxString struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (s xlsxString) text() string {
+	if len(s.Runs) == 0 {
+		return s.Text
+	}
+	var b strings.Builder
+	for _, r := range s.Runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+func readSharedStrings(files map[string]*zip.File) ([]string, error) {
+	f, ok := files["xl/sharedStrings.xml"]
+	if !ok {
+		return nil, nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var sst struct {
+		Items []xlsxString `xml:"si"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&sst); err != nil {
+		return nil, fmt.Errorf("spreadsheet: ошибка чтения sharedStrings.xml: %v", err)
+	}
+
+	shared := make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		shared[i] = item.text()
+	}
+	return shared, nil
+}
+
+// firstSheetPath находит файл первого листа через workbook.xml и его связи
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("spreadsheet: в книге нет листов")
+	}
+
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels.Items {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", fmt.Errorf("spreadsheet: не найден файл первого листа")
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("spreadsheet: в книге нет %s", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("spreadsheet: ошибка чтения %s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Config — параметры S3-совместимого хранилища (AWS S3, MinIO и т. п.)
+type S3Config struct {
+	// Endpoint — адрес сервиса со схемой, например http://localhost:9000
+	Endpoint string
+	// Region — регион для подписи запросов; по умолчанию us-east-1
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// Client — HTTP-клиент; по умолчанию клиент с таймаутом в минуту
+	Client *http.Client
+}
+
+// S3 хранит файлы в бакете S3-совместимого хранилища. Объекты адресуются в стиле
+// path (endpoint/bucket/key), который поддерживают и AWS, и MinIO.
+type S3 struct {
+	endpoint *url.URL
+	bucket   string
+	creds    credentials
+	client   *http.Client
+	now      func() time.Time
+}
+
+// maxErrorBody — сколько байт ответа с ошибкой попадает в текст ошибки
+const maxErrorBody = 1024
+
+// NewS3 проверяет параметры и создаёт клиент хранилища
+func NewS3(cfg S3Config) (*S3, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT должен быть адресом вида http(s)://host[:port], получено %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("для S3 нужно задать S3_BUCKET, S3_ACCESS_KEY и S3_SECRET_KEY")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: time.Minute}
+	}
+
+	return &S3{
+		endpoint: endpoint,
+		bucket:   cfg.Bucket,
+		creds:    credentials{accessKey: cfg.AccessKey, secretKey: cfg.SecretKey, region: cfg.Region},
+		client:   cfg.Client,
+		now:      time.Now,
+	}, nil
+}
+
+// objectURL возвращает адрес объекта; пустой key — адрес бакета
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	segments := []string{s.bucket}
+	if key != "" {
+		segments = append(segments, strings.Split(key, "/")...)
+	}
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = uriEncode(segment)
+	}
+	base := strings.TrimSuffix(u.Path, "/")
+	u.Path = base + "/" + strings.Join(segments, "/")
+	u.RawPath = base + "/" + strings.Join(escaped, "/")
+	return &u
+}
+
+// do подписывает и отправляет запрос. body == nil — запрос без тела;
+// тело известной длины передаётся без подписи содержимого (UNSIGNED-PAYLOAD).
+func (s *S3) do(ctx context.Context, method string, u *url.URL, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		req.ContentLength = size
+		payloadHash = unsignedPayload
+		if b, ok := body.(*bytes.Reader); ok && b.Size() == size {
+			// Тело из памяти можно подписать целиком
+			data := make([]byte, size)
+			b.ReadAt(data, 0)
+			payloadHash = hashHex(data)
+		}
+	}
+	s.creds.sign(req, payloadHash, s.now())
+
+	return s.client.Do(req)
+}
+
+// responseError описывает неуспешный ответ S3, включая код ошибки из XML
+func responseError(op, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return fmt.Errorf("S3 %s %q: %s: %s", op, key, resp.Status, strings.TrimSpace(string(body)))
+}
+
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(key), body, size, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("PUT", key, resp)
+	}
+	return nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(key), nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, responseError("GET", key, resp)
+	}
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(key), nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// S3 отвечает 204 и на удаление несуществующего объекта; 404 возможен у других реализаций
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return responseError("DELETE", key, resp)
+	}
+	return nil
+}
+
+// EnsureBucket создаёт бакет, если его ещё нет
+func (s *S3) EnsureBucket(ctx context.Context) error {
+	resp, err := s.do(ctx, http.MethodHead, s.objectURL(""), nil, 0, nil)
+	if err != nil {
+		return fmt.Errorf("S3 недоступно: %w", err)
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+	default:
+		return fmt.Errorf("S3 HEAD бакета %q: %s", s.bucket, resp.Status)
+	}
+
+	// Вне us-east-1 регион бакета указывается явно
+	var body []byte
+	if s.creds.region != "us-east-1" {
+		body = []byte(`<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><LocationConstraint>` +
+			s.creds.region + `</LocationConstraint></CreateBucketConfiguration>`)
+	}
+	resp, err = s.do(ctx, http.MethodPut, s.objectURL(""), bytes.NewReader(body), int64(len(body)), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("PUT", s.bucket, resp)
+	}
+	return nil
+}
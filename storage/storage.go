@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrNotFound — объекта с таким ключом нет в хранилище
+var ErrNotFound = errors.New("объект не найден")
+
+// ErrInvalidKey — ключ пустой, абсолютный или выходит за пределы хранилища («..»)
+var ErrInvalidKey = errors.New("недопустимый ключ объекта")
+
+// Storage хранит файлы по ключам вида "cars/12/abc.jpg".
+// Delete несуществующего объекта не считается ошибкой.
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv создаёт хранилище по переменным окружения:
+//
+//	MEDIA_STORAGE  — local (по умолчанию) или s3
+//	MEDIA_DIR      — каталог локального хранилища, по умолчанию ./uploads
+//	S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY — параметры S3-совместимого хранилища
+//
+// Для S3 бакет создаётся, если его ещё нет.
+func FromEnv(ctx context.Context) (Storage, error) {
+	switch kind := os.Getenv("MEDIA_STORAGE"); kind {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		return NewLocal(dir)
+	case "s3":
+		s, err := NewS3(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.EnsureBucket(ctx); err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("неизвестный тип хранилища MEDIA_STORAGE=%q (ожидается local или s3)", kind)
+	}
+}
+
+// cleanKey проверяет ключ и приводит его к каноническому виду
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
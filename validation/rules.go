@@ -0,0 +1,371 @@
+package validation
+
+import (
+	"CarDealership/database/models"
+	"CarDealership/schedule"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MinCarYear — год выпуска первого автомобиля (Benz Patent-Motorwagen)
+const MinCarYear = 1886
+
+// Palette — допустимые цвета автомобиля (английские и русские названия)
+var Palette = []string{
+	"White", "Black", "Silver", "Gray", "Red", "Blue", "Green",
+	"Yellow", "Orange", "Brown", "Beige", "Purple", "Gold",
+	"Белый", "Черный", "Чёрный", "Серебристый", "Серый", "Красный", "Синий", "Зеленый",
+	"Зелёный", "Желтый", "Жёлтый", "Оранжевый", "Коричневый", "Бежевый", "Фиолетовый", "Золотой",
+}
+
+// Допустимые значения перечислимых полей автомобиля
+var (
+	CarConditions = []string{models.ConditionNew, models.ConditionUsed}
+	BodyTypes     = []string{"sedan", "hatchback", "liftback", "wagon", "coupe", "convertible", "suv", "crossover", "minivan", "pickup", "van"}
+	FuelTypes     = []string{"petrol", "diesel", "hybrid", "plug_in_hybrid", "electric", "lpg", "cng"}
+	Transmissions = []string{"manual", "automatic", "robot", "cvt"}
+	Drivetrains   = []string{"fwd", "rwd", "awd", "4wd"}
+	CarStatuses   = []string{models.StatusAvailable, models.StatusReserved, models.StatusSold}
+)
+
+// PaymentMethods — способы оплаты продажи
+var PaymentMethods = []string{models.PaymentCash, models.PaymentCard, models.PaymentBankTransfer,
+	models.PaymentCredit, models.PaymentLeasing}
+
+// BookingStatuses — статусы записи на тест-драйв
+var BookingStatuses = []string{models.BookingBooked, models.BookingCancelled}
+
+// TransferStatuses — статусы передачи автомобиля между дилерами
+var TransferStatuses = []string{models.TransferRequested, models.TransferApproved, models.TransferRejected,
+	models.TransferCancelled, models.TransferCompleted}
+
+// ReviewStatuses — статусы модерации отзыва о дилере
+var ReviewStatuses = []string{models.ReviewPending, models.ReviewApproved, models.ReviewRejected}
+
+// MaxMileage — верхняя граница пробега в километрах
+const MaxMileage = 3_000_000
+
+// NormalizeCar приводит поля автомобиля к виду, в котором они хранятся: VIN — в верхнем регистре
+// без разделителей (пустой — nil), перечислимые значения — в нижнем регистре.
+// Если состояние не указано, машина без пробега считается новой, с пробегом — подержанной.
+func NormalizeCar(car *models.Car) {
+	if car.VIN != nil {
+		if vin := NormalizeVIN(*car.VIN); vin != "" {
+			car.VIN = &vin
+		} else {
+			car.VIN = nil
+		}
+	}
+	for _, field := range []*string{&car.Condition, &car.BodyType, &car.FuelType, &car.Transmission, &car.Drivetrain} {
+		*field = strings.ToLower(strings.TrimSpace(*field))
+	}
+	car.Trim = strings.TrimSpace(car.Trim)
+
+	if car.Condition == "" {
+		car.Condition = models.ConditionNew
+		if car.Mileage > 0 {
+			car.Condition = models.ConditionUsed
+		}
+	}
+}
+
+// ValidateCar проверяет поля автомобиля; перед проверкой автомобиль нужно нормализовать (NormalizeCar)
+func ValidateCar(car models.Car) Errors {
+	v := New()
+
+	v.Required("firm", car.Firm).MaxLength("firm", car.Firm, 100)
+	v.Required("model", car.Model).MaxLength("model", car.Model, 100)
+	v.IntRange("year", car.Year, MinCarYear, time.Now().Year()+1)
+	v.Positive("power", car.Power)
+	v.Positive("price", car.Price)
+	v.Required("color", car.Color).OneOf("color", car.Color, Palette)
+
+	if car.DealerID != nil && *car.DealerID <= 0 {
+		v.Add("dealer_id", CodeNotPositive, nil)
+	}
+
+	if car.VIN != nil {
+		v.VIN("vin", *car.VIN)
+	}
+	v.IntRange("mileage", car.Mileage, 0, MaxMileage)
+	v.OneOf("condition", car.Condition, CarConditions)
+	v.optionalOneOf("body_type", car.BodyType, BodyTypes)
+	v.optionalOneOf("fuel_type", car.FuelType, FuelTypes)
+	v.optionalOneOf("transmission", car.Transmission, Transmissions)
+	v.optionalOneOf("drivetrain", car.Drivetrain, Drivetrains)
+	v.MaxLength("trim", car.Trim, 100)
+
+	return v.Errors()
+}
+
+// optionalOneOf — как OneOf, но пустое значение («не указано») допустимо
+func (v *Validator) optionalOneOf(field, value string, allowed []string) *Validator {
+	if value == "" {
+		return v
+	}
+	return v.OneOf(field, value, allowed)
+}
+
+// e164 — телефон в международном формате E.164: «+», код страны и номер, всего не больше 15 цифр
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// phoneSeparators — символы, которыми обычно разделяют цифры телефона; при нормализации они удаляются
+var phoneSeparators = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "")
+
+// NormalizeDealer убирает пробелы по краям контактов дилера, разделители из телефона
+// и приводит email к нижнему регистру
+func NormalizeDealer(dealer *models.Dealer) {
+	dealer.Phone = phoneSeparators.Replace(strings.TrimSpace(dealer.Phone))
+	dealer.Email = strings.ToLower(strings.TrimSpace(dealer.Email))
+	dealer.Website = strings.TrimSpace(dealer.Website)
+}
+
+// ValidateDealer проверяет поля дилера; перед проверкой дилера нужно нормализовать (NormalizeDealer).
+// Контакты и координаты необязательны. Рейтинг не проверяется: его считают отзывы, а не клиент.
+func ValidateDealer(dealer models.Dealer) Errors {
+	v := New()
+
+	v.Required("name", dealer.Name).MaxLength("name", dealer.Name, 100)
+	v.Required("city", dealer.City).MaxLength("city", dealer.City, 100)
+	v.Required("address", dealer.Address).MaxLength("address", dealer.Address, 100)
+	v.MaxLength("area", dealer.Area, 100)
+
+	if dealer.Phone != "" && !e164.MatchString(dealer.Phone) {
+		v.Add("phone", CodeInvalidValue, nil)
+	}
+	v.email("email", dealer.Email)
+	v.MaxLength("website", dealer.Website, 255)
+	if dealer.Website != "" && !v.failed("website") {
+		u, err := url.Parse(dealer.Website)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Add("website", CodeInvalidValue, nil)
+		}
+	}
+
+	// Координаты указываются парой
+	switch {
+	case dealer.Latitude == nil && dealer.Longitude != nil:
+		v.Add("latitude", CodeRequired, nil)
+	case dealer.Latitude != nil && dealer.Longitude == nil:
+		v.Add("longitude", CodeRequired, nil)
+	case dealer.Latitude != nil:
+		v.FloatRange("latitude", *dealer.Latitude, -90, 90)
+		v.FloatRange("longitude", *dealer.Longitude, -180, 180)
+	}
+
+	return v.Errors()
+}
+
+// email проверяет необязательный адрес электронной почты
+func (v *Validator) email(field, value string) *Validator {
+	v.MaxLength(field, value, 254)
+	if value != "" && !v.failed(field) {
+		// Адрес должен быть только адресом, без имени: «Иван <ivan@example.com>» не подходит
+		if addr, err := mail.ParseAddress(value); err != nil || addr.Address != value {
+			v.Add(field, CodeInvalidValue, nil)
+		}
+	}
+	return v
+}
+
+// ValidateMake проверяет марку каталога
+func ValidateMake(mk models.Make) Errors {
+	v := New()
+
+	v.Required("name", mk.Name).MaxLength("name", mk.Name, 100)
+
+	return v.Errors()
+}
+
+// ValidateCarModel проверяет модель каталога
+func ValidateCarModel(mo models.CarModel) Errors {
+	v := New()
+
+	v.Required("name", mo.Name).MaxLength("name", mo.Name, 100)
+	if mo.MakeID <= 0 {
+		v.Add("make_id", CodeRequired, nil)
+	}
+
+	return v.Errors()
+}
+
+// NormalizeCustomer убирает пробелы по краям полей покупателя и приводит email к нижнему регистру
+func NormalizeCustomer(customer *models.Customer) {
+	customer.Name = strings.TrimSpace(customer.Name)
+	customer.Email = strings.ToLower(strings.TrimSpace(customer.Email))
+	customer.Phone = strings.TrimSpace(customer.Phone)
+}
+
+// ValidateCustomer проверяет поля покупателя; перед проверкой покупателя нужно нормализовать (NormalizeCustomer)
+func ValidateCustomer(customer models.Customer) Errors {
+	v := New()
+
+	v.Required("name", customer.Name).MaxLength("name", customer.Name, 100)
+	v.email("email", customer.Email)
+	v.MaxLength("phone", customer.Phone, 30)
+
+	return v.Errors()
+}
+
+// NormalizeSale приводит способ оплаты к нижнему регистру
+func NormalizeSale(sale *models.Sale) {
+	sale.PaymentMethod = strings.ToLower(strings.TrimSpace(sale.PaymentMethod))
+}
+
+// ValidateSale проверяет поля продажи; перед проверкой продажу нужно нормализовать (NormalizeSale).
+// Существование машины, дилера и покупателя проверяет обработчик.
+func ValidateSale(sale models.Sale) Errors {
+	v := New()
+
+	if sale.CarID <= 0 {
+		v.Add("car_id", CodeRequired, nil)
+	}
+	if sale.DealerID < 0 {
+		v.Add("dealer_id", CodeNotPositive, nil)
+	}
+	if sale.CustomerID <= 0 {
+		v.Add("customer_id", CodeRequired, nil)
+	}
+	v.Positive("price", sale.Price)
+	if sale.Discount < 0 {
+		v.Add("discount", CodeInvalidValue, nil)
+	}
+	v.Required("payment_method", sale.PaymentMethod).OneOf("payment_method", sale.PaymentMethod, PaymentMethods)
+	// Продажу можно записать задним числом, но не будущим
+	if sale.SoldAt.After(time.Now().Add(time.Hour)) {
+		v.Add("sold_at", CodeInvalidValue, nil)
+	}
+
+	return v.Errors()
+}
+
+// ValidateBooking проверяет запись на тест-драйв: время в будущем, не дальше schedule.MaxAdvance,
+// длительность от schedule.MinDuration до schedule.MaxDuration. Часы работы дилера проверяет обработчик.
+func ValidateBooking(booking models.Booking) Errors {
+	v := New()
+
+	if booking.CarID <= 0 {
+		v.Add("car_id", CodeRequired, nil)
+	}
+	if booking.CustomerID <= 0 {
+		v.Add("customer_id", CodeRequired, nil)
+	}
+	v.validateSlot(booking.Start, booking.End)
+	v.MaxLength("note", booking.Note, 500)
+
+	return v.Errors()
+}
+
+// ValidateSlot проверяет новое время записи при переносе
+func ValidateSlot(start, end time.Time) Errors {
+	return New().validateSlot(start, end).Errors()
+}
+
+func (v *Validator) validateSlot(start, end time.Time) *Validator {
+	now := time.Now()
+	switch {
+	case start.IsZero():
+		v.Add("start", CodeRequired, nil)
+	case !start.After(now) || start.After(now.Add(schedule.MaxAdvance)):
+		v.Add("start", CodeInvalidValue, nil)
+	}
+	if end.IsZero() {
+		v.Add("end", CodeRequired, nil)
+		return v
+	}
+	// Границы длительности в сообщении — в минутах
+	if duration := end.Sub(start); !start.IsZero() && (duration < schedule.MinDuration || duration > schedule.MaxDuration) {
+		v.Add("end", CodeOutOfRange, map[string]interface{}{
+			"min": int(schedule.MinDuration / time.Minute), "max": int(schedule.MaxDuration / time.Minute),
+		})
+	}
+	return v
+}
+
+// ValidateDealerHours проверяет часы работы дилера. Недельное расписание: дни недели от 1 до 7
+// без повторов, время в формате ЧЧ:ММ, открытие раньше закрытия. Исключения: даты ГГГГ-ММ-ДД
+// без повторов; время либо не указано вовсе (закрыто), либо указано так же, как в расписании.
+func ValidateDealerHours(hours models.OpeningHours) Errors {
+	v := New()
+
+	seen := make(map[int]bool, len(hours.Weekly))
+	for i, h := range hours.Weekly {
+		field := func(name string) string { return fmt.Sprintf("weekly[%d].%s", i, name) }
+
+		v.IntRange(field("weekday"), h.Weekday, 1, 7)
+		if seen[h.Weekday] {
+			v.Add(field("weekday"), CodeDuplicate, nil)
+		}
+		seen[h.Weekday] = true
+
+		v.clockRange(field("opens"), field("closes"), h.Opens, h.Closes)
+	}
+
+	dates := make(map[string]bool, len(hours.Exceptions))
+	for i, e := range hours.Exceptions {
+		field := func(name string) string { return fmt.Sprintf("exceptions[%d].%s", i, name) }
+
+		if _, err := time.Parse(schedule.DateLayout, e.Date); err != nil {
+			v.Add(field("date"), CodeInvalidValue, nil)
+		} else if dates[e.Date] {
+			v.Add(field("date"), CodeDuplicate, nil)
+		}
+		dates[e.Date] = true
+
+		if e.Opens != "" || e.Closes != "" {
+			v.clockRange(field("opens"), field("closes"), e.Opens, e.Closes)
+		}
+		v.MaxLength(field("note"), e.Note, 200)
+	}
+
+	return v.Errors()
+}
+
+// clockRange проверяет время открытия и закрытия в формате ЧЧ:ММ; открытие должно быть раньше закрытия
+func (v *Validator) clockRange(opensField, closesField, opens, closes string) *Validator {
+	o, errOpens := schedule.ParseClock(opens)
+	if errOpens != nil {
+		v.Add(opensField, CodeInvalidValue, nil)
+	}
+	c, errCloses := schedule.ParseClock(closes)
+	if errCloses != nil {
+		v.Add(closesField, CodeInvalidValue, nil)
+	}
+	if errOpens == nil && errCloses == nil && o >= c {
+		v.Add(closesField, CodeInvalidValue, nil)
+	}
+	return v
+}
+
+// ValidateTransfer проверяет заявку на передачу автомобиля. Дилера-отправителя, существование машины
+// и получателя проверяет обработчик.
+func ValidateTransfer(transfer models.Transfer) Errors {
+	v := New()
+
+	if transfer.CarID == nil || *transfer.CarID <= 0 {
+		v.Add("car_id", CodeRequired, nil)
+	}
+	if transfer.ToDealerID == nil || *transfer.ToDealerID <= 0 {
+		v.Add("to_dealer_id", CodeRequired, nil)
+	}
+	v.MaxLength("note", transfer.Note, 500)
+
+	return v.Errors()
+}
+
+// ValidateReview проверяет отзыв о дилере. Существование дилера и покупателя проверяет обработчик.
+func ValidateReview(review models.Review) Errors {
+	v := New()
+
+	if review.CustomerID <= 0 {
+		v.Add("customer_id", CodeRequired, nil)
+	}
+	v.IntRange("score", review.Score, 1, 5)
+	v.Required("text", review.Text).MaxLength("text", review.Text, 2000)
+
+	return v.Errors()
+}
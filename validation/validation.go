@@ -0,0 +1,141 @@
+package validation
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Коды ошибок валидации, на которые может опираться клиент
+const (
+	CodeRequired        = "required"
+	CodeTooLong         = "too_long"
+	CodeOutOfRange      = "out_of_range"
+	CodeNotPositive     = "not_positive"
+	CodeNotAllowed      = "not_allowed"
+	CodePrecision       = "invalid_precision"
+	CodeNotFound        = "not_found"
+	CodeInvalidValue    = "invalid_value"
+	CodeReadOnly        = "read_only"
+	CodeInvalidChecksum = "invalid_checksum"
+	CodeDuplicate       = "duplicate"
+	CodeTooLarge        = "too_large"
+	CodeUnsupportedType = "unsupported_type"
+	CodeOutsideHours    = "outside_hours"
+)
+
+// FieldError описывает нарушение правила для одного поля.
+// Message заполняется при ответе клиенту из каталога сообщений по коду и параметрам.
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// Errors — список нарушений, накопленных при проверке модели
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fe := range e {
+		parts = append(parts, fe.Field+": "+fe.Code)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Validator накапливает ошибки; каждое правило проверяет одно поле
+type Validator struct {
+	errors Errors
+}
+
+func New() *Validator {
+	return &Validator{}
+}
+
+// Errors возвращает накопленные ошибки или nil, если их нет
+func (v *Validator) Errors() Errors {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}
+
+// Add добавляет произвольную ошибку поля
+func (v *Validator) Add(field, code string, params map[string]interface{}) {
+	v.errors = append(v.errors, FieldError{Field: field, Code: code, Params: params})
+}
+
+// failed сообщает, есть ли уже ошибка по полю — дальнейшие правила для него не проверяются
+func (v *Validator) failed(field string) bool {
+	for _, fe := range v.errors {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Required проверяет, что строка не пустая
+func (v *Validator) Required(field, value string) *Validator {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, CodeRequired, nil)
+	}
+	return v
+}
+
+// MaxLength проверяет длину строки в символах
+func (v *Validator) MaxLength(field, value string, max int) *Validator {
+	if !v.failed(field) && utf8.RuneCountInString(value) > max {
+		v.Add(field, CodeTooLong, map[string]interface{}{"max": max})
+	}
+	return v
+}
+
+// IntRange проверяет, что целое число лежит в [min, max]
+func (v *Validator) IntRange(field string, value, min, max int) *Validator {
+	if !v.failed(field) && (value < min || value > max) {
+		v.Add(field, CodeOutOfRange, map[string]interface{}{"min": min, "max": max})
+	}
+	return v
+}
+
+// Positive проверяет, что число больше нуля
+func (v *Validator) Positive(field string, value int) *Validator {
+	if !v.failed(field) && value <= 0 {
+		v.Add(field, CodeNotPositive, nil)
+	}
+	return v
+}
+
+// FloatRange проверяет, что дробное число лежит в [min, max]
+func (v *Validator) FloatRange(field string, value, min, max float64) *Validator {
+	if !v.failed(field) && (value < min || value > max) {
+		v.Add(field, CodeOutOfRange, map[string]interface{}{"min": min, "max": max})
+	}
+	return v
+}
+
+// Decimals проверяет, что у числа не больше places знаков после запятой
+func (v *Validator) Decimals(field string, value float64, places int) *Validator {
+	scale := math.Pow(10, float64(places))
+	scaled := value * scale
+	if !v.failed(field) && math.Abs(scaled-math.Round(scaled)) > 1e-9 {
+		v.Add(field, CodePrecision, map[string]interface{}{"places": places})
+	}
+	return v
+}
+
+// OneOf проверяет, что строка (без учёта регистра) входит в список допустимых значений
+func (v *Validator) OneOf(field, value string, allowed []string) *Validator {
+	if v.failed(field) {
+		return v
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimSpace(value), a) {
+			return v
+		}
+	}
+	v.Add(field, CodeNotAllowed, nil)
+	return v
+}
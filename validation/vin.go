@@ -0,0 +1,59 @@
+package validation
+
+import "strings"
+
+// VINLength — длина VIN по ISO 3779
+const VINLength = 17
+
+// vinValues — числовые значения символов VIN для расчёта контрольной цифры.
+// Буквы I, O и Q в VIN не используются, чтобы их не путали с 1 и 0.
+var vinValues = map[rune]int{
+	'0': 0, '1': 1, '2': 2, '3': 3, '4': 4, '5': 5, '6': 6, '7': 7, '8': 8, '9': 9,
+	'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+	'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+	'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+}
+
+// vinWeights — веса позиций VIN; девятая позиция — сама контрольная цифра
+var vinWeights = [VINLength]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// NormalizeVIN приводит VIN к виду, в котором он хранится: без пробелов и дефисов, в верхнем регистре
+func NormalizeVIN(vin string) string {
+	vin = strings.ToUpper(strings.TrimSpace(vin))
+	return strings.NewReplacer(" ", "", "-", "").Replace(vin)
+}
+
+// VINCheckDigit вычисляет контрольную цифру (девятый символ) VIN; второе значение равно false,
+// если VIN не из 17 допустимых символов
+func VINCheckDigit(vin string) (byte, bool) {
+	if len(vin) != VINLength {
+		return 0, false
+	}
+	sum := 0
+	for i, r := range vin {
+		value, ok := vinValues[r]
+		if !ok {
+			return 0, false
+		}
+		sum += value * vinWeights[i]
+	}
+	rem := sum % 11
+	if rem == 10 {
+		return 'X', true
+	}
+	return byte('0' + rem), true
+}
+
+// VIN проверяет формат и контрольную цифру VIN (ISO 3779); значение должно быть нормализовано
+func (v *Validator) VIN(field, value string) *Validator {
+	if v.failed(field) {
+		return v
+	}
+	digit, ok := VINCheckDigit(value)
+	if !ok {
+		v.Add(field, CodeInvalidValue, nil)
+	} else if value[8] != digit {
+		v.Add(field, CodeInvalidChecksum, nil)
+	}
+	return v
+}
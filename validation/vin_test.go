@@ -0,0 +1,103 @@
+package validation
+
+import "testing"
+
+func TestVINCheckDigit(t *testing.T) {
+	tests := []struct {
+		name  string
+		vin   string
+		digit byte
+		ok    bool
+	}{
+		{"контрольная цифра X", "1M8GDM9AXKP042788", 'X', true},
+		{"контрольная цифра 3", "1HGCM82633A004352", '3', true},
+		{"одни единицы", "11111111111111111", '1', true},
+		{"неверная цифра не влияет на расчёт", "1M8GDM9A1KP042788", 'X', true},
+		{"буква I", "1M8GDM9AXKP04278I", 0, false},
+		{"буква O", "1M8GDM9AXKP0427O8", 0, false},
+		{"буква Q", "QM8GDM9AXKP042788", 0, false},
+		{"строчные буквы", "1m8gdm9axkp042788", 0, false},
+		{"16 символов", "1M8GDM9AXKP04278", 0, false},
+		{"18 символов", "1M8GDM9AXKP0427881", 0, false},
+		{"пустой", "", 0, false},
+		{"кириллица", "1М8GDM9AXKP04278", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digit, ok := VINCheckDigit(tt.vin)
+			if digit != tt.digit || ok != tt.ok {
+				t.Errorf("VINCheckDigit(%q) = %q, %v; ожидалось %q, %v", tt.vin, digit, ok, tt.digit, tt.ok)
+			}
+		})
+	}
+}
+
+// Значения букв по ISO 3779: A–H → 1–8, J–N → 1–5, P → 7, R → 9, S–Z → 2–9
+func TestVINTransliteration(t *testing.T) {
+	want := map[rune]int{}
+	for i, r := range "ABCDEFGH" {
+		want[r] = i + 1
+	}
+	for i, r := range "JKLMN" {
+		want[r] = i + 1
+	}
+	want['P'] = 7
+	want['R'] = 9
+	for i, r := range "STUVWXYZ" {
+		want[r] = i + 2
+	}
+	for r := '0'; r <= '9'; r++ {
+		want[r] = int(r - '0')
+	}
+
+	if len(vinValues) != len(want) {
+		t.Errorf("в vinValues %d символов, ожидалось %d", len(vinValues), len(want))
+	}
+	for r, value := range want {
+		if got, ok := vinValues[r]; !ok || got != value {
+			t.Errorf("vinValues[%q] = %d, %v; ожидалось %d", r, got, ok, value)
+		}
+	}
+	for _, r := range "IOQ" {
+		if _, ok := vinValues[r]; ok {
+			t.Errorf("буква %q не должна входить в VIN", r)
+		}
+	}
+}
+
+func TestVINWeights(t *testing.T) {
+	want := [VINLength]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+	if vinWeights != want {
+		t.Errorf("vinWeights = %v; ожидалось %v", vinWeights, want)
+	}
+}
+
+func TestValidatorVIN(t *testing.T) {
+	tests := []struct {
+		name string
+		vin  string
+		code string
+	}{
+		{"верный VIN", "1M8GDM9AXKP042788", ""},
+		{"верный VIN после нормализации", NormalizeVIN(" 1m8-gdm9ax kp042788 "), ""},
+		{"неверная контрольная цифра", "1M8GDM9A1KP042788", CodeInvalidChecksum},
+		{"буква I", "1M8GDM9AXKP04278I", CodeInvalidValue},
+		{"буква O", "1M8GDM9AXKP0427O8", CodeInvalidValue},
+		{"буква Q", "QM8GDM9AXKP042788", CodeInvalidValue},
+		{"неверная длина", "1M8GDM9AXKP04278", CodeInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := New().VIN("vin", tt.vin).Errors()
+			if tt.code == "" {
+				if errs != nil {
+					t.Errorf("VIN(%q): неожиданные ошибки %v", tt.vin, errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Field != "vin" || errs[0].Code != tt.code {
+				t.Errorf("VIN(%q) = %v; ожидалась ошибка vin: %s", tt.vin, errs, tt.code)
+			}
+		})
+	}
+}